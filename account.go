@@ -226,6 +226,8 @@ type GetAccountChangesSchema struct {
 /* Errors */
 
 type PatchAccountConfigurationBadRequestError struct {
+	errorHeaders
+
 	ClientConfigureRejectTransaction *TransactionDefinition  `json:"clientConfigureRejectTransaction,omitempty"`
 	LastTransactionID                TransactionIDDefinition `json:"lastTransactionID,omitempty"`
 	ErrorCode                        string                  `json:"errorCode,omitempty"`
@@ -238,6 +240,8 @@ func (r *PatchAccountConfigurationBadRequestError) Error() string {
 }
 
 type PatchAccountConfigurationForbiddenError struct {
+	errorHeaders
+
 	ClientConfigureRejectTransaction *TransactionDefinition  `json:"clientConfigureRejectTransaction,omitempty"`
 	LastTransactionID                TransactionIDDefinition `json:"lastTransactionID,omitempty"`
 	ErrorCode                        string                  `json:"errorCode,omitempty"`
@@ -256,27 +260,18 @@ func (r *ReceiverAccounts) Get(ctx context.Context) (*GetAccountsSchema, error)
 	childCtx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	resp, err := r.Connection.request(
+	data, err := r.Connection.request(
 		childCtx,
 		&requestParams{
 			method:   "GET",
 			endPoint: "/v3/accounts",
+			results: map[int]interface{}{
+				200: new(GetAccountsSchema),
+			},
 		},
 	)
 	if err != nil {
-		return nil, errors.Errorf("Get accounts canceled: %v", err)
-	}
-	defer resp.Body.Close()
-
-	var data interface{}
-	switch resp.StatusCode {
-	case 200:
-		data = new(GetAccountsSchema)
-	}
-
-	data, err = parseResponse(resp, data, r.Connection.Strict)
-	if err != nil {
-		return nil, errors.Errorf("Get accounts failed: %v", err)
+		return nil, errors.Wrap(err, "Get accounts failed")
 	}
 	return data.(*GetAccountsSchema), nil
 }
@@ -286,7 +281,7 @@ func (r *ReceiverAccountID) Get(ctx context.Context) (*GetAccountIDSchema, error
 	childCtx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	resp, err := r.Connection.request(
+	data, err := r.Connection.request(
 		childCtx,
 		&requestParams{
 			method:   "GET",
@@ -294,22 +289,13 @@ func (r *ReceiverAccountID) Get(ctx context.Context) (*GetAccountIDSchema, error
 			headers: []header{
 				{key: "Accept-Datetime-Format", value: "RFC3339"},
 			},
+			results: map[int]interface{}{
+				200: new(GetAccountIDSchema),
+			},
 		},
 	)
 	if err != nil {
-		return nil, errors.Errorf("Get account ID canceled: %v", err)
-	}
-	defer resp.Body.Close()
-
-	var data interface{}
-	switch resp.StatusCode {
-	case 200:
-		data = new(GetAccountIDSchema)
-	}
-
-	data, err = parseResponse(resp, data, r.Connection.Strict)
-	if err != nil {
-		return nil, errors.Errorf("Get account ID failed: %v", err)
+		return nil, errors.Wrap(err, "Get account ID failed")
 	}
 	return data.(*GetAccountIDSchema), nil
 }
@@ -319,7 +305,7 @@ func (r *ReceiverAccountSummary) Get(ctx context.Context) (*GetAccountSummarySch
 	childCtx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	resp, err := r.Connection.request(
+	data, err := r.Connection.request(
 		childCtx,
 		&requestParams{
 			method:   "GET",
@@ -327,22 +313,13 @@ func (r *ReceiverAccountSummary) Get(ctx context.Context) (*GetAccountSummarySch
 			headers: []header{
 				{key: "Accept-Datetime-Format", value: "RFC3339"},
 			},
+			results: map[int]interface{}{
+				200: new(GetAccountSummarySchema),
+			},
 		},
 	)
 	if err != nil {
-		return nil, errors.Errorf("Get account summary canceled: %v", err)
-	}
-	defer resp.Body.Close()
-
-	var data interface{}
-	switch resp.StatusCode {
-	case 200:
-		data = new(GetAccountSummarySchema)
-	}
-
-	data, err = parseResponse(resp, data, r.Connection.Strict)
-	if err != nil {
-		return nil, errors.Errorf("Get account summary failed: %v", err)
+		return nil, errors.Wrap(err, "Get account summary failed")
 	}
 	return data.(*GetAccountSummarySchema), nil
 }
@@ -352,7 +329,7 @@ func (r *ReceiverAccountInstruments) Get(ctx context.Context, params *GetAccount
 	childCtx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	resp, err := r.Connection.request(
+	data, err := r.Connection.request(
 		childCtx,
 		&requestParams{
 			method:   "GET",
@@ -363,22 +340,13 @@ func (r *ReceiverAccountInstruments) Get(ctx context.Context, params *GetAccount
 			queries: []query{
 				{key: "instruments", value: strings.Join(params.Instruments, ",")},
 			},
+			results: map[int]interface{}{
+				200: new(GetAccountInstrumentsSchema),
+			},
 		},
 	)
 	if err != nil {
-		return nil, errors.Errorf("Get account instruments canceled: %v", err)
-	}
-	defer resp.Body.Close()
-
-	var data interface{}
-	switch resp.StatusCode {
-	case 200:
-		data = new(GetAccountInstrumentsSchema)
-	}
-
-	data, err = parseResponse(resp, data, r.Connection.Strict)
-	if err != nil {
-		return nil, errors.Errorf("Get account instruments failed: %v", err)
+		return nil, errors.Wrap(err, "Get account instruments failed")
 	}
 	return data.(*GetAccountInstrumentsSchema), nil
 }
@@ -388,7 +356,7 @@ func (r *ReceiverAccountConfiguration) Patch(ctx context.Context, params *PatchA
 	childCtx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	resp, err := r.Connection.request(
+	data, err := r.Connection.request(
 		childCtx,
 		&requestParams{
 			method:   "PATCH",
@@ -397,26 +365,23 @@ func (r *ReceiverAccountConfiguration) Patch(ctx context.Context, params *PatchA
 				{key: "Accept-Datetime-Format", value: "RFC3339"},
 			},
 			body: params.Body,
+			simulate: func() (int, interface{}) {
+				tx := dryRunTransaction(r.AccountID, ClientConfigureTransaction, "")
+				if params.Body != nil {
+					tx.Alias = params.Body.Alias
+					tx.MarginRate = params.Body.MarginRate
+				}
+				return 200, &PatchAccountConfigurationSchema{ClientConfigureTransaction: tx}
+			},
+			results: map[int]interface{}{
+				200: new(PatchAccountConfigurationSchema),
+				400: new(PatchAccountConfigurationBadRequestError),
+				403: new(PatchAccountConfigurationForbiddenError),
+			},
 		},
 	)
 	if err != nil {
-		return nil, errors.Errorf("Patch account configuration canceled: %v", err)
-	}
-	defer resp.Body.Close()
-
-	var data interface{}
-	switch resp.StatusCode {
-	case 200:
-		data = new(PatchAccountConfigurationSchema)
-	case 400:
-		data = new(PatchAccountConfigurationBadRequestError)
-	case 403:
-		data = new(PatchAccountConfigurationForbiddenError)
-	}
-
-	data, err = parseResponse(resp, data, r.Connection.Strict)
-	if err != nil {
-		return nil, errors.Errorf("Patch account configuration failed: %v", err)
+		return nil, errors.Wrap(err, "Patch account configuration failed")
 	}
 	return data.(*PatchAccountConfigurationSchema), nil
 }
@@ -426,7 +391,7 @@ func (r *ReceiverAccountChanges) Get(ctx context.Context, params *GetAccountChan
 	childCtx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	resp, err := r.Connection.request(
+	data, err := r.Connection.request(
 		childCtx,
 		&requestParams{
 			method:   "GET",
@@ -442,22 +407,13 @@ func (r *ReceiverAccountChanges) Get(ctx context.Context, params *GetAccountChan
 
 				return q
 			}(),
+			results: map[int]interface{}{
+				200: new(GetAccountChangesSchema),
+			},
 		},
 	)
 	if err != nil {
-		return nil, errors.Errorf("Get account changes canceled: %v", err)
-	}
-	defer resp.Body.Close()
-
-	var data interface{}
-	switch resp.StatusCode {
-	case 200:
-		data = new(GetAccountChangesSchema)
-	}
-
-	data, err = parseResponse(resp, data, r.Connection.Strict)
-	if err != nil {
-		return nil, errors.Errorf("Get account changes failed: %v", err)
+		return nil, errors.Wrap(err, "Get account changes failed")
 	}
 	return data.(*GetAccountChangesSchema), nil
 }
@@ -0,0 +1,324 @@
+package oanda
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+/* Params */
+
+type GetAccountMirrorParams struct {
+	// Interval of polling the account changes, defaults to five seconds.
+	Interval time.Duration
+}
+
+/* Mirror */
+
+// AccountMirror keeps a client-side copy of an account current by polling
+// GET /v3/accounts/{accountID}/changes, as recommended by OANDA.
+type AccountMirror struct {
+	mu                sync.RWMutex
+	account           *AccountDefinition
+	lastTransactionID TransactionIDDefinition
+	lastError         error
+
+	close     context.CancelFunc
+	closeWait *sync.WaitGroup
+}
+
+/* API */
+
+// Mirror bootstraps an AccountMirror from GET /v3/accounts/{accountID} and
+// starts polling the changes since its last transaction.
+func (r *ReceiverAccountID) Mirror(ctx context.Context, params *GetAccountMirrorParams) (*AccountMirror, error) {
+	data, err := r.Get(ctx)
+	if err != nil {
+		return nil, errors.Errorf("Get account mirror failed: %v", err)
+	}
+
+	interval := params.Interval
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+
+	childCtx, cancel := context.WithCancel(ctx)
+	m := &AccountMirror{
+		account:           data.Account,
+		lastTransactionID: data.LastTransactionID,
+		close:             cancel,
+		closeWait:         new(sync.WaitGroup),
+	}
+
+	m.closeWait.Add(1)
+	go func() {
+		defer m.closeWait.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-childCtx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			if err := m.Poll(childCtx, r.Changes()); err != nil {
+				select {
+				case <-childCtx.Done():
+					return
+				default:
+				}
+				m.mu.Lock()
+				m.lastError = err
+				m.mu.Unlock()
+			}
+		}
+	}()
+
+	return m, nil
+}
+
+// Poll fetches and applies the changes since the last applied transaction.
+func (m *AccountMirror) Poll(ctx context.Context, r *ReceiverAccountChanges) error {
+	m.mu.RLock()
+	since := m.lastTransactionID
+	m.mu.RUnlock()
+
+	data, err := r.Get(ctx, &GetAccountChangesParams{SinceTransactionID: since})
+	if err != nil {
+		return errors.Errorf("Poll account changes failed: %v", err)
+	}
+
+	m.Apply(data.Changes, data.State, data.LastTransactionID)
+	return nil
+}
+
+// Apply applies account changes and the recalculated state to the mirror.
+func (m *AccountMirror) Apply(changes *AccountChangesDefinition, state *AccountChangesStateDefinition, lastTransactionID TransactionIDDefinition) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if changes != nil {
+		applyAccountChanges(m.account, changes)
+	}
+	if state != nil {
+		applyAccountChangesState(m.account, state)
+	}
+	if lastTransactionID != "" {
+		m.lastTransactionID = lastTransactionID
+		m.account.LastTransactionID = lastTransactionID
+	}
+}
+
+// Snapshot returns a deep copy of the mirrored account.
+func (m *AccountMirror) Snapshot() (*AccountDefinition, error) {
+	m.mu.RLock()
+	body, err := json.Marshal(m.account)
+	m.mu.RUnlock()
+	if err != nil {
+		return nil, errors.Errorf("Copy account failed: %v", err)
+	}
+
+	account := new(AccountDefinition)
+	if err := json.Unmarshal(body, account); err != nil {
+		return nil, errors.Errorf("Copy account failed: %v", err)
+	}
+	return account, nil
+}
+
+// LastTransactionID returns the ID of the last transaction applied.
+func (m *AccountMirror) LastTransactionID() TransactionIDDefinition {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.lastTransactionID
+}
+
+/* Utils */
+
+func (m *AccountMirror) Close() {
+	m.close()
+	m.closeWait.Wait()
+}
+
+// Err returns the last error of polling the account changes.
+func (m *AccountMirror) Err() error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.lastError
+}
+
+func applyAccountChanges(account *AccountDefinition, changes *AccountChangesDefinition) {
+	applyAccountTransactions(account, changes.Transactions)
+
+	for _, order := range changes.OrdersCreated {
+		account.Orders = removeOrder(account.Orders, order.ID)
+		account.Orders = append(account.Orders, order)
+	}
+	for _, orders := range [][]*OrderDefinition{changes.OrdersCancelled, changes.OrdersFilled, changes.OrdersTriggered} {
+		for _, order := range orders {
+			account.Orders = removeOrder(account.Orders, order.ID)
+		}
+	}
+
+	for _, trade := range changes.TradesOpened {
+		account.Trades = removeTrade(account.Trades, trade.ID)
+		account.Trades = append(account.Trades, trade)
+	}
+	for _, trade := range changes.TradesReduced {
+		for n, t := range account.Trades {
+			if t.ID == trade.ID {
+				account.Trades[n] = trade
+			}
+		}
+	}
+	for _, trade := range changes.TradesClosed {
+		account.Trades = removeTrade(account.Trades, trade.ID)
+	}
+
+	for _, position := range changes.Positions {
+		replaced := false
+		for n, p := range account.Positions {
+			if p.Instrument == position.Instrument {
+				account.Positions[n] = position
+				replaced = true
+			}
+		}
+		if !replaced {
+			account.Positions = append(account.Positions, position)
+		}
+	}
+
+	account.OpenTradeCount = Int(len(account.Trades))
+	account.PendingOrderCount = Int(len(account.Orders))
+	openPositions := 0
+	for _, p := range account.Positions {
+		if isPositionOpen(p) {
+			openPositions++
+		}
+	}
+	account.OpenPositionCount = Int(openPositions)
+}
+
+// applyAccountTransactions takes the balance and the realized amounts of the
+// account from transactions, which the changes state doesn't carry.
+func applyAccountTransactions(account *AccountDefinition, transactions []*TransactionDefinition) {
+	for _, tx := range transactions {
+		if tx.AccountBalance != "" {
+			account.Balance = tx.AccountBalance
+		}
+		for _, v := range []struct {
+			total  *AccountUnitsDefinition
+			amount AccountUnitsDefinition
+		}{
+			{&account.PL, tx.PL},
+			{&account.ResettablePL, tx.PL},
+			{&account.Financing, tx.Financing},
+			{&account.Commission, tx.Commission},
+			{&account.GuaranteedExecutionFees, tx.GuaranteedExecutionFee},
+		} {
+			addAccountUnits(v.total, v.amount)
+		}
+	}
+}
+
+// addAccountUnits adds amount to total, keeping total when either doesn't
+// parse.
+func addAccountUnits(total *AccountUnitsDefinition, amount AccountUnitsDefinition) {
+	if amount == "" {
+		return
+	}
+	v, err := parseDecimal(amount)
+	if err != nil {
+		return
+	}
+	var t float64
+	if *total != "" {
+		if t, err = parseDecimal(*total); err != nil {
+			return
+		}
+	}
+	*total = formatAmount(t + v)
+}
+
+func applyAccountChangesState(account *AccountDefinition, state *AccountChangesStateDefinition) {
+	account.UnrealizedPL = state.UnrealizedPL
+	account.NAV = state.NAV
+	account.MarginUsed = state.MarginUsed
+	account.MarginAvailable = state.MarginAvailable
+	account.PositionValue = state.PositionValue
+	account.MarginCloseoutUnrealizedPL = state.MarginCloseoutUnrealizedPL
+	account.MarginCloseoutNAV = state.MarginCloseoutNAV
+	account.MarginCloseoutMarginUsed = state.MarginCloseoutMarginUsed
+	account.MarginCloseoutPercent = state.MarginCloseoutPercent
+	account.MarginCloseoutPositionValue = state.MarginCloseoutPositionValue
+	account.WithdrawalLimit = state.WithdrawalLimit
+	account.MarginCallMarginUsed = state.MarginCallMarginUsed
+	account.MarginCallPercent = state.MarginCallPercent
+
+	for _, s := range state.Orders {
+		for _, o := range account.Orders {
+			if o.ID == s.ID {
+				o.TrailingStopValue = s.TrailingStopValue
+			}
+		}
+	}
+
+	for _, s := range state.Trades {
+		for _, t := range account.Trades {
+			if t.ID == s.ID {
+				t.UnrealizedPL = s.UnrealizedPL
+				t.MarginUsed = s.MarginUsed
+			}
+		}
+	}
+
+	for _, s := range state.Positions {
+		for _, p := range account.Positions {
+			if p.Instrument != s.Instrument {
+				continue
+			}
+			p.UnrealizedPL = s.NetUnrealizedPL
+			p.MarginUsed = s.MarginUsed
+			if p.Long != nil {
+				p.Long.UnrealizedPL = s.LongUnrealizedPL
+			}
+			if p.Short != nil {
+				p.Short.UnrealizedPL = s.ShortUnrealizedPL
+			}
+		}
+	}
+}
+
+func removeOrder(orders []*OrderDefinition, id string) []*OrderDefinition {
+	kept := orders[:0]
+	for _, o := range orders {
+		if o.ID != id {
+			kept = append(kept, o)
+		}
+	}
+	return kept
+}
+
+func removeTrade(trades []*TradeSummaryDefinition, id TradeIDDefinition) []*TradeSummaryDefinition {
+	kept := trades[:0]
+	for _, t := range trades {
+		if t.ID != id {
+			kept = append(kept, t)
+		}
+	}
+	return kept
+}
+
+func isPositionOpen(p *PositionDefinition) bool {
+	for _, side := range []*PositionSideDefinition{p.Long, p.Short} {
+		if side != nil && side.Units != "" && side.Units != "0" {
+			return true
+		}
+	}
+	return false
+}
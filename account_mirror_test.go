@@ -0,0 +1,123 @@
+package oanda
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_AccountMirror(t *testing.T) {
+	m := &AccountMirror{
+		account: &AccountDefinition{
+			ID: "001",
+			Orders: []*OrderDefinition{
+				{ID: "10", Type: "LIMIT"},
+				{ID: "11", Type: "TAKE_PROFIT"},
+			},
+			Trades: []*TradeSummaryDefinition{
+				{ID: "20", Instrument: "EUR_USD", CurrentUnits: "100"},
+				{ID: "21", Instrument: "USD_JPY", CurrentUnits: "50"},
+			},
+			Positions: []*PositionDefinition{
+				{Instrument: "EUR_USD", Long: &PositionSideDefinition{Units: "100"}, Short: &PositionSideDefinition{Units: "0"}},
+				{Instrument: "USD_JPY", Long: &PositionSideDefinition{Units: "50"}, Short: &PositionSideDefinition{Units: "0"}},
+			},
+		},
+		lastTransactionID: "30",
+	}
+
+	m.Apply(
+		&AccountChangesDefinition{
+			OrdersCreated:   []*OrderDefinition{{ID: "12", Type: "STOP"}},
+			OrdersFilled:    []*OrderDefinition{{ID: "10"}},
+			OrdersCancelled: []*OrderDefinition{{ID: "11"}},
+			TradesOpened:    []*TradeSummaryDefinition{{ID: "22", Instrument: "EUR_USD", CurrentUnits: "10"}},
+			TradesReduced:   []*TradeSummaryDefinition{{ID: "20", Instrument: "EUR_USD", CurrentUnits: "60"}},
+			TradesClosed:    []*TradeSummaryDefinition{{ID: "21"}},
+			Positions: []*PositionDefinition{
+				{Instrument: "USD_JPY", Long: &PositionSideDefinition{Units: "0"}, Short: &PositionSideDefinition{Units: "0"}},
+			},
+		},
+		&AccountChangesStateDefinition{
+			NAV:             "1000.5",
+			MarginAvailable: "900",
+			Trades:          []*CalculatedTradeStateDefinition{{ID: "22", UnrealizedPL: "1.5"}},
+			Positions:       []*CalculatedPositionStateDefinition{{Instrument: "EUR_USD", NetUnrealizedPL: "2.5", LongUnrealizedPL: "2.5"}},
+		},
+		"35",
+	)
+
+	account, err := m.Snapshot()
+	if err != nil {
+		t.Fatalf("Error occurred.\n%+v", err)
+	}
+
+	if len(account.Orders) != 1 || account.Orders[0].ID != "12" {
+		t.Fatalf("Got unexpected orders.\n%#v", account.Orders)
+	}
+	if len(account.Trades) != 2 || account.Trades[0].CurrentUnits != "60" || account.Trades[1].UnrealizedPL != "1.5" {
+		t.Fatalf("Got unexpected trades.\n%#v", account.Trades)
+	}
+	if *account.OpenPositionCount != 1 || *account.OpenTradeCount != 2 || *account.PendingOrderCount != 1 {
+		t.Fatalf("Got unexpected counts.\nPositions: %d\nTrades: %d\nOrders: %d", *account.OpenPositionCount, *account.OpenTradeCount, *account.PendingOrderCount)
+	}
+	if account.Positions[0].UnrealizedPL != "2.5" || account.Positions[0].Long.UnrealizedPL != "2.5" {
+		t.Fatalf("Got unexpected position state.\n%#v", account.Positions[0])
+	}
+	if account.NAV != "1000.5" || account.MarginAvailable != "900" {
+		t.Fatalf("Got unexpected account state.\nNAV: %s\nMarginAvailable: %s", account.NAV, account.MarginAvailable)
+	}
+	if m.LastTransactionID() != "35" || account.LastTransactionID != "35" {
+		t.Fatalf("Got unexpected last transaction ID %s", m.LastTransactionID())
+	}
+
+	// The snapshot must not share memory with the mirror.
+	account.Trades[0].CurrentUnits = "0"
+	if again, _ := m.Snapshot(); again.Trades[0].CurrentUnits != "60" {
+		t.Fatal("Snapshot shares memory with the mirror.")
+	}
+
+	t.Run("Poll", func(t *testing.T) {
+		// A poll with a fill and a financing moves the balance and the
+		// realized amounts.
+		connection := &Connection{
+			Environemnt: OandaPractice,
+			Timeout:     time.Second,
+			Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				if since := req.URL.Query().Get("sinceTransactionID"); since != "35" {
+					t.Errorf("Got unexpected sinceTransactionID %s", since)
+				}
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Header:     http.Header{"Requestid": {"1"}},
+					Body: ioutil.NopCloser(strings.NewReader(`{"changes":{"transactions":[
+						{"id":"36","type":"ORDER_FILL","accountBalance":"1012.4000","pl":"12.5000","financing":"-0.1000"},
+						{"id":"37","type":"DAILY_FINANCING","accountBalance":"1012.1500","financing":"-0.2500"}
+					]},"lastTransactionID":"37"}`)),
+					Request: req,
+				}, nil
+			}),
+		}
+		m := &AccountMirror{
+			account:           &AccountDefinition{ID: "001", Balance: "1000.0000", PL: "10.0000", ResettablePL: "10.0000", Financing: "-1.0000"},
+			lastTransactionID: "35",
+		}
+		if err := m.Poll(context.Background(), connection.Accounts().AccountID("001").Changes()); err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+
+		account, err := m.Snapshot()
+		if err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+		if account.Balance != "1012.1500" || account.PL != "22.5000" || account.ResettablePL != "22.5000" || account.Financing != "-1.3500" {
+			t.Fatalf("Got unexpected account.\nBalance: %s\nPL: %s\nResettablePL: %s\nFinancing: %s", account.Balance, account.PL, account.ResettablePL, account.Financing)
+		}
+		if account.LastTransactionID != "37" {
+			t.Fatalf("Got unexpected last transaction ID %s", account.LastTransactionID)
+		}
+	})
+}
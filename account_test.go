@@ -2,9 +2,14 @@ package oanda
 
 import (
 	"context"
+	"io/ioutil"
+	"net/http"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/davecgh/go-spew/spew"
+	"github.com/pkg/errors"
 )
 
 func Test_Accounts(t *testing.T) {
@@ -105,6 +110,39 @@ func Test_AccountConfiguration(t *testing.T) {
 
 		t.Logf("Response:\n%s", spew.Sdump(data))
 	})
+
+	t.Run("Errors", func(t *testing.T) {
+		for _, c := range []struct {
+			status int
+			expect interface{}
+		}{
+			{http.StatusBadRequest, new(*PatchAccountConfigurationBadRequestError)},
+			{http.StatusForbidden, new(*PatchAccountConfigurationForbiddenError)},
+		} {
+			connection := &Connection{
+				Environemnt: OandaPractice,
+				Timeout:     time.Second,
+				Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+					return &http.Response{
+						StatusCode: c.status,
+						Header:     http.Header{"Requestid": {"42"}},
+						Body:       ioutil.NopCloser(strings.NewReader(`{"errorCode":"INVALID_MARGIN_RATE","errorMessage":"invalid"}`)),
+						Request:    req,
+					}, nil
+				}),
+			}
+			params := &PatchAccountConfigurationParams{&PatchAccountConfigurationBodyParams{MarginRate: "2"}}
+			_, err := connection.Accounts().AccountID(testReplayAccountID).Configuration().Patch(context.Background(), params)
+
+			if !errors.As(err, c.expect) {
+				t.Fatalf("Got unexpected error.\n%+v", err)
+			}
+			var respErr ResponseError
+			if !errors.As(err, &respErr) || respErr.ResponseHeaders().RequestID != "42" {
+				t.Fatalf("Got unexpected error.\n%+v", err)
+			}
+		}
+	})
 }
 
 func Test_AccountChanges(t *testing.T) {
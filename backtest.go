@@ -0,0 +1,299 @@
+package oanda
+
+import (
+	"context"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+/* Params */
+
+type BacktestParams struct {
+	// Granularity of the candles.
+	Granularity CandlestickGranularityDefinition
+	// Candles by instrument in chronological order, e.g. as returned by
+	// ReceiverInstrumentCandles.Get. Incomplete candles are skipped. Include
+	// the candles of the instruments converting amounts to the home currency,
+	// e.g. of USD_JPY for trading EUR_JPY on a USD account.
+	Candles map[InstrumentNameDefinition][]*CandlestickDefinition
+	// Spread by instrument applied around the mid prices of candles without
+	// bid and ask prices.
+	Spread map[InstrumentNameDefinition]float64
+	// Slippage by instrument, see SimulatorParams.
+	Slippage map[InstrumentNameDefinition]float64
+	// Financing by instrument, e.g. of InstrumentDefinition.Financing.
+	Financing map[InstrumentNameDefinition]*InstrumentFinancingDefinition
+
+	// The simulated account, see SimulatorParams.
+	Currency   CurrencyDefinition
+	Balance    float64
+	MarginRate float64
+}
+
+/* Strategy */
+
+// Strategy is run by Backtest.
+type Strategy interface {
+	// OnCandle is called with every candle once the market moved through it,
+	// the account is at the close of the candle. Trade through account like
+	// through a live account.
+	OnCandle(ctx context.Context, account *ReceiverAccountID, instrument InstrumentNameDefinition, candle *CandlestickDefinition) error
+}
+
+// StrategyFunc adapts a function to a Strategy.
+type StrategyFunc func(ctx context.Context, account *ReceiverAccountID, instrument InstrumentNameDefinition, candle *CandlestickDefinition) error
+
+func (f StrategyFunc) OnCandle(ctx context.Context, account *ReceiverAccountID, instrument InstrumentNameDefinition, candle *CandlestickDefinition) error {
+	return f(ctx, account, instrument, candle)
+}
+
+/* Report */
+
+type BacktestReport struct {
+	// Trades opened during the backtest. Trades still open are valued at the
+	// last prices.
+	Trades []*TradeDefinition
+	// Equity at the close of every candle.
+	Equity []*EquityPoint
+
+	Balance   float64
+	NAV       float64
+	PL        float64
+	Financing float64
+	// MaxDrawdown is the largest decline of the NAV from a peak as a fraction
+	// of the peak.
+	MaxDrawdown float64
+	// SharpeRatio is annualized from the daily returns of the NAV with a
+	// risk-free rate of zero and 252 trading days a year.
+	SharpeRatio float64
+}
+
+type EquityPoint struct {
+	Time    time.Time
+	Balance float64
+	NAV     float64
+	// Drawdown of the NAV from its peak as a fraction of the peak.
+	Drawdown float64
+}
+
+/* Backtest */
+
+type backtestCandle struct {
+	instrument InstrumentNameDefinition
+	time       time.Time
+	def        *CandlestickDefinition
+}
+
+// Backtest runs strategy on the candles of params against a Simulator.
+//
+// Within a candle the market is assumed to move from the open to the high, the
+// low and the close when it closed below the open, and from the open to the
+// low, the high and the close otherwise. Orders crossed on the way are
+// filled at their price, orders gapped over between candles at the open.
+// Candles of the same time are moved through together.
+func Backtest(ctx context.Context, strategy Strategy, params *BacktestParams) (*BacktestReport, error) {
+	if !IsGranularityValid(params.Granularity) {
+		return nil, errors.Errorf("Invalid granularity %s", params.Granularity)
+	}
+	duration := Granularity2Duration(params.Granularity)
+
+	financing := make(map[InstrumentNameDefinition]*SimulatorFinancing)
+	for instrument, def := range params.Financing {
+		f, err := NewSimulatorFinancing(def)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Financing of %s is invalid", instrument)
+		}
+		financing[instrument] = f
+	}
+	sim := NewSimulator(&SimulatorParams{
+		Currency:   params.Currency,
+		Balance:    params.Balance,
+		MarginRate: params.MarginRate,
+		Financing:  financing,
+		Slippage:   params.Slippage,
+	})
+	connection := &Connection{Environemnt: OandaPractice, Transport: sim}
+	account := connection.Accounts().AccountID(sim.accountID)
+
+	var candles []*backtestCandle
+	for instrument, defs := range params.Candles {
+		for _, def := range defs {
+			if def.Complete != nil && !*def.Complete {
+				continue
+			}
+			t, err := time.Parse(time.RFC3339Nano, def.Time)
+			if err != nil {
+				return nil, errors.Errorf("Parse candle time of %s failed: %v", instrument, err)
+			}
+			candles = append(candles, &backtestCandle{instrument: instrument, time: t, def: def})
+		}
+	}
+	sort.SliceStable(candles, func(i, j int) bool {
+		if !candles[i].time.Equal(candles[j].time) {
+			return candles[i].time.Before(candles[j].time)
+		}
+		return candles[i].instrument < candles[j].instrument
+	})
+
+	report := new(BacktestReport)
+	peak := params.Balance
+	for len(candles) > 0 {
+		if err := ctx.Err(); err != nil {
+			return nil, errors.Wrap(err, "Backtest canceled")
+		}
+		n := 1
+		for n < len(candles) && candles[n].time.Equal(candles[0].time) {
+			n++
+		}
+		group := candles[:n]
+		candles = candles[n:]
+
+		paths := make([][]*PriceDefinition, len(group))
+		for i, c := range group {
+			path, err := candlePath(c, duration, params.Spread[c.instrument])
+			if err != nil {
+				return nil, err
+			}
+			paths[i] = path
+		}
+		for step := 0; step < 4; step++ {
+			for _, path := range paths {
+				if err := sim.updatePrice(path[step], step > 0); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		for _, c := range group {
+			if err := strategy.OnCandle(ctx, account, c.instrument, c.def); err != nil {
+				return nil, errors.Wrapf(err, "Strategy failed on %s candle at %s", c.instrument, c.def.Time)
+			}
+		}
+
+		sim.mu.Lock()
+		point := &EquityPoint{Time: group[0].time.Add(duration), Balance: sim.balance, NAV: sim.state().nav}
+		sim.mu.Unlock()
+		peak = math.Max(peak, point.NAV)
+		if peak > 0 {
+			point.Drawdown = (peak - point.NAV) / peak
+		}
+		report.MaxDrawdown = math.Max(report.MaxDrawdown, point.Drawdown)
+		report.Equity = append(report.Equity, point)
+	}
+
+	sim.mu.Lock()
+	defer sim.mu.Unlock()
+	state := sim.state()
+	for _, t := range sim.trades {
+		report.Trades = append(report.Trades, sim.trade(t))
+	}
+	report.Balance = sim.balance
+	report.NAV = state.nav
+	report.PL = sim.pl
+	report.Financing = sim.financed
+	report.SharpeRatio = sharpeRatio(params.Balance, report.Equity)
+	return report, nil
+}
+
+/* Utils */
+
+// candlePath returns the prices at the open, the first and second extreme and
+// the close of a candle.
+func candlePath(c *backtestCandle, duration time.Duration, spread float64) ([]*PriceDefinition, error) {
+	var bid, ask [4]float64
+	switch {
+	case c.def.Bid != nil && c.def.Ask != nil:
+		var err error
+		if bid, err = parseCandleData(c.def.Bid); err != nil {
+			return nil, errors.Wrapf(err, "Parse bid of %s candle at %s failed", c.instrument, c.def.Time)
+		}
+		if ask, err = parseCandleData(c.def.Ask); err != nil {
+			return nil, errors.Wrapf(err, "Parse ask of %s candle at %s failed", c.instrument, c.def.Time)
+		}
+	case c.def.Mid != nil:
+		mid, err := parseCandleData(c.def.Mid)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Parse mid of %s candle at %s failed", c.instrument, c.def.Time)
+		}
+		for i, v := range mid {
+			bid[i], ask[i] = v-spread/2, v+spread/2
+		}
+	default:
+		return nil, errors.Errorf("Candle of %s at %s has no prices", c.instrument, c.def.Time)
+	}
+
+	// The path as indexes of the open, high, low and close.
+	order := []int{0, 2, 1, 3}
+	if bid[3]+ask[3] < bid[0]+ask[0] {
+		order = []int{0, 1, 2, 3}
+	}
+	path := make([]*PriceDefinition, len(order))
+	for step, i := range order {
+		path[step] = &PriceDefinition{
+			Type:       "PRICE",
+			Instrument: c.instrument,
+			Time:       c.time.Add(duration * time.Duration(step) / 3).Format(time.RFC3339Nano),
+			Tradeable:  Bool(true),
+			Bids:       []*PriceBucketDefinition{{Price: formatPrice(bid[i])}},
+			Asks:       []*PriceBucketDefinition{{Price: formatPrice(ask[i])}},
+		}
+	}
+	return path, nil
+}
+
+// parseCandleData returns the open, high, low and close of d.
+func parseCandleData(d *CandlestickDataDefinition) ([4]float64, error) {
+	var v [4]float64
+	for i, s := range []PriceValueDefinition{d.O, d.H, d.L, d.C} {
+		f, err := parseDecimal(s)
+		if err != nil {
+			return v, err
+		}
+		v[i] = f
+	}
+	return v, nil
+}
+
+// sharpeRatio returns the annualized Sharpe ratio of the daily returns of the
+// equity starting at balance.
+func sharpeRatio(balance float64, equity []*EquityPoint) float64 {
+	// NAVs at the end of UTC days.
+	navs := []float64{balance}
+	var day time.Time
+	for i, point := range equity {
+		d := point.Time.UTC().Truncate(24 * time.Hour)
+		if i > 0 && d.Equal(day) {
+			navs[len(navs)-1] = point.NAV
+		} else {
+			navs = append(navs, point.NAV)
+		}
+		day = d
+	}
+
+	var returns []float64
+	for i := 1; i < len(navs); i++ {
+		if navs[i-1] > 0 {
+			returns = append(returns, navs[i]/navs[i-1]-1)
+		}
+	}
+	if len(returns) < 2 {
+		return 0
+	}
+	var mean float64
+	for _, r := range returns {
+		mean += r
+	}
+	mean /= float64(len(returns))
+	var variance float64
+	for _, r := range returns {
+		variance += (r - mean) * (r - mean)
+	}
+	stddev := math.Sqrt(variance / float64(len(returns)-1))
+	if stddev == 0 {
+		return 0
+	}
+	return mean / stddev * math.Sqrt(252)
+}
@@ -0,0 +1,188 @@
+package oanda
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func newMidCandle(t, o, h, l, c string) *CandlestickDefinition {
+	return &CandlestickDefinition{
+		Time:     t,
+		Mid:      &CandlestickDataDefinition{O: o, H: h, L: l, C: c},
+		Complete: Bool(true),
+	}
+}
+
+func Test_Backtest(t *testing.T) {
+	t.Run("Fills", func(t *testing.T) {
+		orders := []*MarketOrderRequestDefinition{
+			// Filled at the close ask 1.1001 and the slippage, the take profit
+			// is crossed within the next candle.
+			{Type: "MARKET", Instrument: "EUR_USD", Units: "1000", TakeProfitOnFill: &TakeProfitDetailsDefinition{Price: "1.1050"}},
+			// Filled at the close bid 1.1039 and the slippage, the stop loss
+			// is gapped over by the next open.
+			{Type: "MARKET", Instrument: "EUR_USD", Units: "-1000", StopLossOnFill: &StopLossDetailsDefinition{Price: "1.1060"}},
+		}
+		var calls int
+		strategy := StrategyFunc(func(ctx context.Context, account *ReceiverAccountID, instrument InstrumentNameDefinition, candle *CandlestickDefinition) error {
+			defer func() { calls++ }()
+			if calls >= len(orders) {
+				return nil
+			}
+			_, err := account.Orders().Post(ctx, &PostOrdersParams{Body: PostOrdersBodyParams{Order: orders[calls]}})
+			return err
+		})
+
+		report, err := Backtest(context.Background(), strategy, &BacktestParams{
+			Granularity: H1,
+			Candles: map[InstrumentNameDefinition][]*CandlestickDefinition{
+				"EUR_USD": {
+					newMidCandle("2022-01-04T10:00:00Z", "1.1000", "1.1010", "1.0990", "1.1000"),
+					newMidCandle("2022-01-04T11:00:00Z", "1.1000", "1.1060", "1.0995", "1.1040"),
+					newMidCandle("2022-01-04T12:00:00Z", "1.1080", "1.1090", "1.1070", "1.1075"),
+					{Time: "2022-01-04T13:00:00Z", Mid: &CandlestickDataDefinition{O: "1.1075", H: "1.1075", L: "1.1075", C: "1.1075"}, Complete: Bool(false)},
+				},
+			},
+			Spread:   map[InstrumentNameDefinition]float64{"EUR_USD": 0.0002},
+			Slippage: map[InstrumentNameDefinition]float64{"EUR_USD": 0.0001},
+			Balance:  10000,
+		})
+		if err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+
+		if calls != 3 {
+			t.Fatalf("Got unexpected number of candles %d.", calls)
+		}
+		if len(report.Trades) != 2 {
+			t.Fatalf("Got unexpected trades.\n%+v", report.Trades)
+		}
+		for i, expect := range []struct{ price, closePrice, pl string }{
+			{"1.1002", "1.105", "4.8000"},
+			{"1.1038", "1.1082", "-4.4000"},
+		} {
+			trade := report.Trades[i]
+			if trade.State != "CLOSED" || trade.Price != expect.price || trade.AverageClosePrice != expect.closePrice || trade.RealizedPL != expect.pl {
+				t.Fatalf("Got unexpected trade.\n%+v", trade)
+			}
+		}
+		if math.Abs(report.Balance-10000.4) > 1e-9 || math.Abs(report.NAV-10000.4) > 1e-9 || math.Abs(report.PL-0.4) > 1e-9 {
+			t.Fatalf("Got unexpected report.\n%+v", report)
+		}
+
+		expect := []*EquityPoint{
+			{Time: time.Date(2022, 1, 4, 11, 0, 0, 0, time.UTC), Balance: 10000, NAV: 9999.7, Drawdown: 0.3 / 10000},
+			{Time: time.Date(2022, 1, 4, 12, 0, 0, 0, time.UTC), Balance: 10004.8, NAV: 10004.5},
+			{Time: time.Date(2022, 1, 4, 13, 0, 0, 0, time.UTC), Balance: 10000.4, NAV: 10000.4, Drawdown: 4.1 / 10004.5},
+		}
+		if len(report.Equity) != len(expect) {
+			t.Fatalf("Got unexpected equity.\n%+v", report.Equity)
+		}
+		for i, e := range expect {
+			a := report.Equity[i]
+			if !a.Time.Equal(e.Time) || math.Abs(a.Balance-e.Balance) > 1e-9 || math.Abs(a.NAV-e.NAV) > 1e-9 || math.Abs(a.Drawdown-e.Drawdown) > 1e-9 {
+				t.Fatalf("Got unexpected equity point %d.\nExpect: %+v\nActual: %+v", i, e, a)
+			}
+		}
+		if math.Abs(report.MaxDrawdown-4.1/10004.5) > 1e-9 {
+			t.Fatalf("Got unexpected max drawdown %v.", report.MaxDrawdown)
+		}
+	})
+
+	t.Run("Financing", func(t *testing.T) {
+		flat := &CandlestickDataDefinition{O: "1.1000", H: "1.1000", L: "1.1000", C: "1.1000"}
+		candle := func(t string) *CandlestickDefinition {
+			return &CandlestickDefinition{
+				Time: t,
+				Bid:  &CandlestickDataDefinition{O: "1.0999", H: "1.0999", L: "1.0999", C: "1.0999"},
+				Ask:  &CandlestickDataDefinition{O: "1.1001", H: "1.1001", L: "1.1001", C: "1.1001"},
+				Mid:  flat,
+			}
+		}
+		strategy := StrategyFunc(func(ctx context.Context, account *ReceiverAccountID, instrument InstrumentNameDefinition, candle *CandlestickDefinition) error {
+			if candle.Time != "2022-01-03T21:00:00Z" {
+				return nil
+			}
+			_, err := account.Orders().Post(ctx, &PostOrdersParams{Body: PostOrdersBodyParams{Order: &MarketOrderRequestDefinition{Type: "MARKET", Instrument: "EUR_USD", Units: "10000"}}})
+			return err
+		})
+
+		// The long position is financed once on Tuesday and three times on
+		// Wednesday.
+		report, err := Backtest(context.Background(), strategy, &BacktestParams{
+			Granularity: D,
+			Candles: map[InstrumentNameDefinition][]*CandlestickDefinition{
+				"EUR_USD": {candle("2022-01-03T21:00:00Z"), candle("2022-01-04T21:00:00Z"), candle("2022-01-05T21:00:00Z")},
+			},
+			Financing: map[InstrumentNameDefinition]*InstrumentFinancingDefinition{
+				"EUR_USD": {
+					LongRate:  "-0.0365",
+					ShortRate: "0.01",
+					FinancingDaysOfWeek: []FinancingDayOfWeekDefinition{
+						{DayOfWeek: "MONDAY", DaysCharged: Int(1)},
+						{DayOfWeek: "TUESDAY", DaysCharged: Int(1)},
+						{DayOfWeek: "WEDNESDAY", DaysCharged: Int(3)},
+						{DayOfWeek: "THURSDAY", DaysCharged: Int(1)},
+						{DayOfWeek: "FRIDAY", DaysCharged: Int(1)},
+						{DayOfWeek: "SATURDAY", DaysCharged: Int(0)},
+						{DayOfWeek: "SUNDAY", DaysCharged: Int(0)},
+					},
+				},
+			},
+			Balance: 10000,
+		})
+		if err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+		if math.Abs(report.Financing+4.4) > 1e-9 || report.Trades[0].Financing != "-4.4000" {
+			t.Fatalf("Got unexpected financing %v.\n%+v", report.Financing, report.Trades[0])
+		}
+	})
+
+	t.Run("SharpeRatio", func(t *testing.T) {
+		day := time.Date(2022, 1, 4, 21, 0, 0, 0, time.UTC)
+		equity := []*EquityPoint{
+			{Time: day.Add(-time.Hour), NAV: 99},
+			{Time: day, NAV: 101},
+			{Time: day.Add(24 * time.Hour), NAV: 100},
+			{Time: day.Add(48 * time.Hour), NAV: 102},
+		}
+		if actual := sharpeRatio(100, equity); math.Abs(actual-6.987203230485361) > 1e-9 {
+			t.Fatalf("Got unexpected Sharpe ratio %v.", actual)
+		}
+		if actual := sharpeRatio(100, equity[:2]); actual != 0 {
+			t.Fatalf("Got unexpected Sharpe ratio %v.", actual)
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		failure := errors.New("failure")
+		strategy := StrategyFunc(func(ctx context.Context, account *ReceiverAccountID, instrument InstrumentNameDefinition, candle *CandlestickDefinition) error {
+			return failure
+		})
+		params := &BacktestParams{
+			Granularity: M1,
+			Candles: map[InstrumentNameDefinition][]*CandlestickDefinition{
+				"EUR_USD": {newMidCandle("2022-01-04T10:00:00Z", "1.1000", "1.1010", "1.0990", "1.1000")},
+			},
+			Balance: 10000,
+		}
+		if _, err := Backtest(context.Background(), strategy, params); errors.Cause(err) != failure {
+			t.Fatalf("Got unexpected error.\n%+v", err)
+		}
+
+		params.Candles["EUR_USD"][0].Mid = nil
+		if _, err := Backtest(context.Background(), strategy, params); err == nil {
+			t.Fatalf("Error did not occur.")
+		}
+
+		params.Granularity = "M3"
+		if _, err := Backtest(context.Background(), strategy, params); err == nil {
+			t.Fatalf("Error did not occur.")
+		}
+	})
+}
@@ -0,0 +1,277 @@
+package oanda
+
+import (
+	"math"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+/* Calculator */
+
+// AccountCalculator calculates the state of an account from prices as
+// returned by GET /v3/accounts/{accountID}/changes, e.g. to update an
+// AccountMirror with every price between polls:
+//
+//	state, err := calculator.UpdatePrice(price)
+//	if err == nil {
+//		mirror.Apply(nil, state, "")
+//	}
+//
+// Unrealized P/L of long trades is valued at the bid and of short trades at
+// the ask, the position value at the mid price. Margin is the position value
+// at the greater margin rate of the account and the instrument, positions
+// take the margin of their greater side. Closeout values are calculated with
+// the closeout prices. Trades of instruments without a price keep their
+// values.
+type AccountCalculator struct {
+	// Converter converts amounts in the quote currencies of the instruments
+	// into the home currency.
+	Converter *HomeConverter
+
+	mu          sync.Mutex
+	account     *AccountDefinition
+	marginRates map[InstrumentNameDefinition]float64
+	prices      map[InstrumentNameDefinition]*calculatorPrice
+}
+
+type calculatorPrice struct {
+	bid, ask                 float64
+	closeoutBid, closeoutAsk float64
+}
+
+// calculatorValues are the values of a trade, a position side or an account
+// in the home currency.
+type calculatorValues struct {
+	unrealizedPL         float64
+	marginUsed           float64
+	positionValue        float64
+	closeoutUnrealizedPL float64
+	closeoutMarginUsed   float64
+	closeoutValue        float64
+}
+
+// NewAccountCalculator returns a calculator of account. converter may be nil
+// when the instruments are quoted in the home currency.
+func NewAccountCalculator(account *AccountDefinition, converter *HomeConverter) *AccountCalculator {
+	if converter == nil {
+		converter = NewHomeConverter(account.Currency)
+	}
+	return &AccountCalculator{
+		Converter:   converter,
+		account:     account,
+		marginRates: make(map[InstrumentNameDefinition]float64),
+		prices:      make(map[InstrumentNameDefinition]*calculatorPrice),
+	}
+}
+
+// SetAccount replaces the account, e.g. with a snapshot of an AccountMirror
+// after polling.
+func (c *AccountCalculator) SetAccount(account *AccountDefinition) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.account = account
+}
+
+// SetMarginRate sets the margin rate of an instrument, e.g. of
+// InstrumentDefinition.MarginRate.
+func (c *AccountCalculator) SetMarginRate(instrument InstrumentNameDefinition, rate float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.marginRates[instrument] = rate
+}
+
+// UpdatePrice takes price and returns the state of the account at the
+// current prices. Heartbeats only return the state.
+func (c *AccountCalculator) UpdatePrice(price *PriceDefinition) (*AccountChangesStateDefinition, error) {
+	if price.Type != "HEARTBEAT" {
+		p, err := parseCalculatorPrice(price)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Update price of %s failed", price.Instrument)
+		}
+		c.mu.Lock()
+		c.prices[price.Instrument] = p
+		c.mu.Unlock()
+	}
+	return c.State()
+}
+
+// State returns the state of the account at the current prices.
+func (c *AccountCalculator) State() (*AccountChangesStateDefinition, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	balance, err := parseDecimal(c.account.Balance)
+	if err != nil {
+		return nil, errors.Wrap(err, "Parse balance failed")
+	}
+	accountRate, err := parseDecimal(c.account.MarginRate)
+	if err != nil {
+		return nil, errors.Wrap(err, "Parse margin rate failed")
+	}
+
+	state := new(AccountChangesStateDefinition)
+	var instruments []InstrumentNameDefinition
+	longs := make(map[InstrumentNameDefinition]*calculatorValues)
+	shorts := make(map[InstrumentNameDefinition]*calculatorValues)
+	for _, t := range c.account.Trades {
+		units, err := parseDecimal(t.CurrentUnits)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Parse units of trade %s failed", t.ID)
+		}
+		v, err := c.trade(t, units, math.Max(accountRate, c.marginRates[t.Instrument]))
+		if err != nil {
+			return nil, errors.Wrapf(err, "Calculate trade %s failed", t.ID)
+		}
+		state.Trades = append(state.Trades, &CalculatedTradeStateDefinition{
+			ID:           t.ID,
+			UnrealizedPL: formatAmount(v.unrealizedPL),
+			MarginUsed:   formatAmount(v.marginUsed),
+		})
+
+		if longs[t.Instrument] == nil {
+			instruments = append(instruments, t.Instrument)
+			longs[t.Instrument], shorts[t.Instrument] = new(calculatorValues), new(calculatorValues)
+		}
+		side := longs[t.Instrument]
+		if units < 0 {
+			side = shorts[t.Instrument]
+		}
+		side.add(v)
+	}
+
+	account := new(calculatorValues)
+	for _, instrument := range instruments {
+		long, short := longs[instrument], shorts[instrument]
+		position := &calculatorValues{
+			unrealizedPL:         long.unrealizedPL + short.unrealizedPL,
+			marginUsed:           math.Max(long.marginUsed, short.marginUsed),
+			positionValue:        long.positionValue + short.positionValue,
+			closeoutUnrealizedPL: long.closeoutUnrealizedPL + short.closeoutUnrealizedPL,
+			closeoutMarginUsed:   math.Max(long.closeoutMarginUsed, short.closeoutMarginUsed),
+			closeoutValue:        long.closeoutValue + short.closeoutValue,
+		}
+		account.add(position)
+		state.Positions = append(state.Positions, &CalculatedPositionStateDefinition{
+			Instrument:        instrument,
+			NetUnrealizedPL:   formatAmount(position.unrealizedPL),
+			LongUnrealizedPL:  formatAmount(long.unrealizedPL),
+			ShortUnrealizedPL: formatAmount(short.unrealizedPL),
+			MarginUsed:        formatAmount(position.marginUsed),
+		})
+	}
+
+	nav := balance + account.unrealizedPL
+	closeoutNAV := balance + account.closeoutUnrealizedPL
+	marginAvailable := math.Max(0, nav-account.marginUsed)
+	state.UnrealizedPL = formatAmount(account.unrealizedPL)
+	state.NAV = formatAmount(nav)
+	state.MarginUsed = formatAmount(account.marginUsed)
+	state.MarginAvailable = formatAmount(marginAvailable)
+	state.PositionValue = formatAmount(account.positionValue)
+	state.MarginCloseoutUnrealizedPL = formatAmount(account.closeoutUnrealizedPL)
+	state.MarginCloseoutNAV = formatAmount(closeoutNAV)
+	state.MarginCloseoutMarginUsed = formatAmount(account.closeoutMarginUsed / 2)
+	state.MarginCloseoutPositionValue = formatAmount(account.closeoutValue)
+	state.MarginCloseoutPercent = formatPercent(account.closeoutMarginUsed/2, closeoutNAV)
+	state.WithdrawalLimit = formatAmount(marginAvailable)
+	state.MarginCallMarginUsed = formatAmount(account.marginUsed)
+	state.MarginCallPercent = formatPercent(account.marginUsed, closeoutNAV)
+	return state, nil
+}
+
+/* Utils */
+
+// trade calculates the values of a trade at the margin rate.
+func (c *AccountCalculator) trade(t *TradeSummaryDefinition, units, rate float64) (*calculatorValues, error) {
+	p, ok := c.prices[t.Instrument]
+	if !ok {
+		v := new(calculatorValues)
+		var err error
+		if t.UnrealizedPL != "" {
+			if v.unrealizedPL, err = parseDecimal(t.UnrealizedPL); err != nil {
+				return nil, err
+			}
+		}
+		if t.MarginUsed != "" {
+			if v.marginUsed, err = parseDecimal(t.MarginUsed); err != nil {
+				return nil, err
+			}
+		}
+		if rate > 0 {
+			v.positionValue = v.marginUsed / rate
+		}
+		v.closeoutUnrealizedPL, v.closeoutMarginUsed, v.closeoutValue = v.unrealizedPL, v.marginUsed, v.positionValue
+		return v, nil
+	}
+
+	price, err := parseDecimal(t.Price)
+	if err != nil {
+		return nil, err
+	}
+	quote := QuoteCurrency(t.Instrument)
+	closing, closeout := p.bid, p.closeoutBid
+	if units < 0 {
+		closing, closeout = p.ask, p.closeoutAsk
+	}
+
+	v := new(calculatorValues)
+	for _, f := range []struct {
+		dst     *float64
+		convert func(CurrencyDefinition, float64) (float64, error)
+		amount  float64
+	}{
+		{&v.unrealizedPL, c.Converter.ConvertPL, units * (closing - price)},
+		{&v.closeoutUnrealizedPL, c.Converter.ConvertPL, units * (closeout - price)},
+		{&v.positionValue, c.Converter.ConvertPositionValue, math.Abs(units) * (p.bid + p.ask) / 2},
+		{&v.closeoutValue, c.Converter.ConvertPositionValue, math.Abs(units) * (p.closeoutBid + p.closeoutAsk) / 2},
+	} {
+		amount, err := f.convert(quote, f.amount)
+		if err != nil {
+			return nil, err
+		}
+		*f.dst = amount
+	}
+	v.unrealizedPL = round(v.unrealizedPL)
+	v.closeoutUnrealizedPL = round(v.closeoutUnrealizedPL)
+	v.marginUsed = round(v.positionValue * rate)
+	v.closeoutMarginUsed = round(v.closeoutValue * rate)
+	return v, nil
+}
+
+func (v *calculatorValues) add(o *calculatorValues) {
+	v.unrealizedPL += o.unrealizedPL
+	v.marginUsed += o.marginUsed
+	v.positionValue += o.positionValue
+	v.closeoutUnrealizedPL += o.closeoutUnrealizedPL
+	v.closeoutMarginUsed += o.closeoutMarginUsed
+	v.closeoutValue += o.closeoutValue
+}
+
+// parseCalculatorPrice parses the best bid and ask of price, its closeout
+// prices default to them.
+func parseCalculatorPrice(price *PriceDefinition) (*calculatorPrice, error) {
+	if len(price.Bids) == 0 || len(price.Asks) == 0 {
+		return nil, errors.Errorf("Price has no bids or asks")
+	}
+	p := new(calculatorPrice)
+	var err error
+	if p.bid, err = parseDecimal(price.Bids[0].Price); err != nil {
+		return nil, err
+	}
+	if p.ask, err = parseDecimal(price.Asks[0].Price); err != nil {
+		return nil, err
+	}
+	p.closeoutBid, p.closeoutAsk = p.bid, p.ask
+	if price.CloseoutBid != "" {
+		if p.closeoutBid, err = parseDecimal(price.CloseoutBid); err != nil {
+			return nil, err
+		}
+	}
+	if price.CloseoutAsk != "" {
+		if p.closeoutAsk, err = parseDecimal(price.CloseoutAsk); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
@@ -0,0 +1,280 @@
+package oanda
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// calculatorFixture holds responses of an account with the state of its
+// changes at the prices.
+type calculatorFixture struct {
+	Account     *GetAccountIDSchema          `json:"account"`
+	Instruments *GetAccountInstrumentsSchema `json:"instruments"`
+	OrderFill   *TransactionDefinition       `json:"orderFill"`
+	Pricing     *GetPricingSchema            `json:"pricing"`
+	Changes     *GetAccountChangesSchema     `json:"changes"`
+}
+
+func Test_AccountCalculator(t *testing.T) {
+	t.Run("Fixture", func(t *testing.T) {
+		data, err := ioutil.ReadFile(filepath.Join("testdata", "calculator", "account.json"))
+		if err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+		fixture := new(calculatorFixture)
+		if err := json.Unmarshal(data, fixture); err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+
+		converter := NewHomeConverter(fixture.Account.Account.Currency)
+		if err := converter.UpdateFromFactors(fixture.OrderFill.Instrument, &fixture.OrderFill.HomeConversionFactors); err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+		calculator := NewAccountCalculator(fixture.Account.Account, converter)
+		for _, instrument := range fixture.Instruments.Instruments {
+			rate, err := parseDecimal(instrument.MarginRate)
+			if err != nil {
+				t.Fatalf("Error occurred.\n%+v", err)
+			}
+			calculator.SetMarginRate(instrument.Name, rate)
+		}
+		var state *AccountChangesStateDefinition
+		for _, price := range fixture.Pricing.Prices {
+			if state, err = calculator.UpdatePrice(price); err != nil {
+				t.Fatalf("Error occurred.\n%+v", err)
+			}
+		}
+
+		type value struct {
+			name           string
+			expect, actual DecimalNumberDefinition
+		}
+		expect := fixture.Changes.State
+		values := []value{
+			{"unrealized P/L", expect.UnrealizedPL, state.UnrealizedPL},
+			{"NAV", expect.NAV, state.NAV},
+			{"margin used", expect.MarginUsed, state.MarginUsed},
+			{"margin available", expect.MarginAvailable, state.MarginAvailable},
+			{"position value", expect.PositionValue, state.PositionValue},
+			{"margin closeout unrealized P/L", expect.MarginCloseoutUnrealizedPL, state.MarginCloseoutUnrealizedPL},
+			{"margin closeout NAV", expect.MarginCloseoutNAV, state.MarginCloseoutNAV},
+			{"margin closeout margin used", expect.MarginCloseoutMarginUsed, state.MarginCloseoutMarginUsed},
+			{"margin closeout position value", expect.MarginCloseoutPositionValue, state.MarginCloseoutPositionValue},
+			{"margin closeout percent", expect.MarginCloseoutPercent, state.MarginCloseoutPercent},
+			{"withdrawal limit", expect.WithdrawalLimit, state.WithdrawalLimit},
+			{"margin call margin used", expect.MarginCallMarginUsed, state.MarginCallMarginUsed},
+			{"margin call percent", expect.MarginCallPercent, state.MarginCallPercent},
+		}
+		if len(state.Trades) != len(expect.Trades) || len(state.Positions) != len(expect.Positions) {
+			t.Fatalf("Got unexpected state.\n%+v", state)
+		}
+		for i, trade := range expect.Trades {
+			values = append(values, []value{
+				{"trade " + trade.ID + " ID", trade.ID, state.Trades[i].ID},
+				{"trade " + trade.ID + " unrealized P/L", trade.UnrealizedPL, state.Trades[i].UnrealizedPL},
+				{"trade " + trade.ID + " margin used", trade.MarginUsed, state.Trades[i].MarginUsed},
+			}...)
+		}
+		for i, position := range expect.Positions {
+			values = append(values, []value{
+				{"position " + position.Instrument + " instrument", position.Instrument, state.Positions[i].Instrument},
+				{"position " + position.Instrument + " net unrealized P/L", position.NetUnrealizedPL, state.Positions[i].NetUnrealizedPL},
+				{"position " + position.Instrument + " long unrealized P/L", position.LongUnrealizedPL, state.Positions[i].LongUnrealizedPL},
+				{"position " + position.Instrument + " short unrealized P/L", position.ShortUnrealizedPL, state.Positions[i].ShortUnrealizedPL},
+				{"position " + position.Instrument + " margin used", position.MarginUsed, state.Positions[i].MarginUsed},
+			}...)
+		}
+		for _, v := range values {
+			if v.expect != v.actual {
+				t.Fatalf("Got unexpected %s.\nExpect: %s\nActual: %s", v.name, v.expect, v.actual)
+			}
+		}
+	})
+
+	t.Run("Simulator", func(t *testing.T) {
+		// The calculator agrees with the account of the simulator.
+		sim, account := newTestSimulator(t, &SimulatorParams{})
+		updateSimPrice(t, sim, newSimPrice("GBP_USD", "2022-01-04T10:00:00Z", "1.3500", "1.3503"))
+		postSimOrder(t, account, &MarketOrderRequestDefinition{Type: "MARKET", Instrument: "EUR_USD", Units: "1000"})
+		postSimOrder(t, account, &MarketOrderRequestDefinition{Type: "MARKET", Instrument: "GBP_USD", Units: "-2000"})
+
+		resp, err := account.Get(context.Background())
+		if err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+		calculator := NewAccountCalculator(resp.Account, nil)
+
+		var state *AccountChangesStateDefinition
+		for _, price := range []*PriceDefinition{
+			newSimPrice("EUR_USD", "2022-01-04T10:01:00Z", "1.1030", "1.1032"),
+			newSimPrice("GBP_USD", "2022-01-04T10:01:00Z", "1.3520", "1.3524"),
+		} {
+			updateSimPrice(t, sim, price)
+			if state, err = calculator.UpdatePrice(price); err != nil {
+				t.Fatalf("Error occurred.\n%+v", err)
+			}
+		}
+
+		summary := getSimSummary(t, account)
+		for _, v := range []struct {
+			name           string
+			expect, actual DecimalNumberDefinition
+		}{
+			{"unrealized P/L", summary.UnrealizedPL, state.UnrealizedPL},
+			{"NAV", summary.NAV, state.NAV},
+			{"margin used", summary.MarginUsed, state.MarginUsed},
+			{"margin available", summary.MarginAvailable, state.MarginAvailable},
+			{"position value", summary.PositionValue, state.PositionValue},
+			{"margin closeout percent", summary.MarginCloseoutPercent, state.MarginCloseoutPercent},
+			{"withdrawal limit", summary.WithdrawalLimit, state.WithdrawalLimit},
+		} {
+			if v.expect != v.actual {
+				t.Fatalf("Got unexpected %s.\nExpect: %s\nActual: %s", v.name, v.expect, v.actual)
+			}
+		}
+		if len(state.Trades) != 2 || len(state.Positions) != 2 || state.Positions[0].Instrument != "EUR_USD" {
+			t.Fatalf("Got unexpected state.\n%+v", state)
+		}
+	})
+
+	t.Run("Mirror", func(t *testing.T) {
+		// The calculator follows the balance of a polled AccountMirror after
+		// fills.
+		sim, account := newTestSimulator(t, &SimulatorParams{})
+		mirror, err := account.Mirror(context.Background(), &GetAccountMirrorParams{Interval: time.Hour})
+		if err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+		defer mirror.Close()
+		snapshot, err := mirror.Snapshot()
+		if err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+		calculator := NewAccountCalculator(snapshot, nil)
+
+		postSimOrder(t, account, &MarketOrderRequestDefinition{Type: "MARKET", Instrument: "EUR_USD", Units: "3000"})
+		updateSimPrice(t, sim, newSimPrice("EUR_USD", "2022-01-04T10:01:00Z", "1.1030", "1.1032"))
+		postSimOrder(t, account, &MarketOrderRequestDefinition{Type: "MARKET", Instrument: "EUR_USD", Units: "-1000"})
+		if err := mirror.Poll(context.Background(), account.Changes()); err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+		if snapshot, err = mirror.Snapshot(); err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+		calculator.SetAccount(snapshot)
+
+		price := newSimPrice("EUR_USD", "2022-01-04T10:02:00Z", "1.1040", "1.1042")
+		updateSimPrice(t, sim, price)
+		state, err := calculator.UpdatePrice(price)
+		if err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+
+		summary := getSimSummary(t, account)
+		if summary.Balance == "10000.0000" {
+			t.Fatalf("Got no realized P/L.\n%+v", summary)
+		}
+		for _, v := range []struct {
+			name           string
+			expect, actual DecimalNumberDefinition
+		}{
+			{"balance", summary.Balance, snapshot.Balance},
+			{"NAV", summary.NAV, state.NAV},
+			{"margin available", summary.MarginAvailable, state.MarginAvailable},
+			{"margin closeout percent", summary.MarginCloseoutPercent, state.MarginCloseoutPercent},
+			{"margin call percent", summary.MarginCallPercent, state.MarginCallPercent},
+		} {
+			if v.expect != v.actual {
+				t.Fatalf("Got unexpected %s.\nExpect: %s\nActual: %s", v.name, v.expect, v.actual)
+			}
+		}
+	})
+
+	t.Run("Conversion", func(t *testing.T) {
+		converter := NewHomeConverter("USD")
+		if err := converter.UpdateFromFactors("EUR_JPY", &HomeConversionFactorsDefinition{
+			GainQuoteHome: ConversionFactorDefinition{Factor: "0.0090"},
+			LossQuoteHome: ConversionFactorDefinition{Factor: "0.0092"},
+		}); err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+		calculator := NewAccountCalculator(&AccountDefinition{
+			Currency:   "USD",
+			Balance:    "10000.0000",
+			MarginRate: "0.02",
+			Trades: []*TradeSummaryDefinition{
+				{ID: "1", Instrument: "EUR_JPY", Price: "130.50", CurrentUnits: "1000"},
+				{ID: "2", Instrument: "EUR_JPY", Price: "130.50", CurrentUnits: "-2000"},
+				// Trades without a price keep their values.
+				{ID: "3", Instrument: "AUD_USD", Price: "0.7000", CurrentUnits: "1000", UnrealizedPL: "5.0000", MarginUsed: "14.0000"},
+			},
+		}, converter)
+		calculator.SetMarginRate("EUR_JPY", 0.05)
+
+		price := newSimPrice("EUR_JPY", "2022-01-04T10:00:00Z", "130.20", "130.24")
+		price.CloseoutBid, price.CloseoutAsk = "130.18", "130.26"
+		state, err := calculator.UpdatePrice(price)
+		if err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+
+		// The loss of the long trade is converted with the loss factor, the
+		// position takes the margin of its short side.
+		for _, v := range []struct {
+			name           string
+			expect, actual DecimalNumberDefinition
+		}{
+			{"trade 1 unrealized P/L", "-2.7600", state.Trades[0].UnrealizedPL},
+			{"trade 1 margin used", "59.2501", state.Trades[0].MarginUsed},
+			{"trade 2 unrealized P/L", "4.6800", state.Trades[1].UnrealizedPL},
+			{"trade 2 margin used", "118.5002", state.Trades[1].MarginUsed},
+			{"trade 3 unrealized P/L", "5.0000", state.Trades[2].UnrealizedPL},
+			{"position unrealized P/L", "1.9200", state.Positions[0].NetUnrealizedPL},
+			{"position long unrealized P/L", "-2.7600", state.Positions[0].LongUnrealizedPL},
+			{"position margin used", "118.5002", state.Positions[0].MarginUsed},
+			{"unrealized P/L", "6.9200", state.UnrealizedPL},
+			{"NAV", "10006.9200", state.NAV},
+			{"margin used", "132.5002", state.MarginUsed},
+			{"margin available", "9874.4198", state.MarginAvailable},
+			{"position value", "4255.0060", state.PositionValue},
+			{"margin closeout unrealized P/L", "6.3760", state.MarginCloseoutUnrealizedPL},
+			{"margin closeout NAV", "10006.3760", state.MarginCloseoutNAV},
+			{"margin closeout margin used", "66.2501", state.MarginCloseoutMarginUsed},
+			{"margin closeout percent", "0.00662", state.MarginCloseoutPercent},
+			{"margin call percent", "0.01324", state.MarginCallPercent},
+		} {
+			if v.expect != v.actual {
+				t.Fatalf("Got unexpected %s.\nExpect: %s\nActual: %s", v.name, v.expect, v.actual)
+			}
+		}
+
+		// Heartbeats keep the state.
+		heartbeat, err := calculator.UpdatePrice(&PriceDefinition{Type: "HEARTBEAT"})
+		if err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+		if heartbeat.NAV != state.NAV {
+			t.Fatalf("Got unexpected NAV %s.", heartbeat.NAV)
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		calculator := NewAccountCalculator(&AccountDefinition{
+			Currency:   "USD",
+			Balance:    "10000.0000",
+			MarginRate: "0.02",
+			Trades:     []*TradeSummaryDefinition{{ID: "1", Instrument: "EUR_JPY", Price: "130.50", CurrentUnits: "1000"}},
+		}, nil)
+		if _, err := calculator.UpdatePrice(&PriceDefinition{Type: "PRICE", Instrument: "EUR_JPY"}); err == nil {
+			t.Fatalf("Error did not occur.")
+		}
+		// The conversion of JPY is unknown.
+		if _, err := calculator.UpdatePrice(newSimPrice("EUR_JPY", "2022-01-04T10:00:00Z", "130.20", "130.24")); err == nil {
+			t.Fatalf("Error did not occur.")
+		}
+	})
+}
@@ -0,0 +1,301 @@
+package oanda
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+/* Cassettes */
+
+// Cassette is a recording of HTTP exchanges with the API, made by a Recorder
+// and served by a Replayer.
+type Cassette struct {
+	Interactions []*Interaction `json:"interactions"`
+}
+
+type Interaction struct {
+	Request  *InteractionRequest  `json:"request"`
+	Response *InteractionResponse `json:"response"`
+}
+
+type InteractionRequest struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+	Body   string `json:"body,omitempty"`
+}
+
+type InteractionResponse struct {
+	StatusCode int         `json:"statusCode"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+	// Whether the response is a stream, which is kept open after its body
+	// was replayed until it is closed. Error responses of streams are not.
+	Stream bool `json:"stream,omitempty"`
+}
+
+func LoadCassette(path string) (*Cassette, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Errorf("Read cassette failed: %v", err)
+	}
+	cassette := new(Cassette)
+	if err := json.Unmarshal(data, cassette); err != nil {
+		return nil, errors.Errorf("Unmarshal cassette %s failed: %v", path, err)
+	}
+	return cassette, nil
+}
+
+func (c *Cassette) Save(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return errors.Errorf("Marshal cassette failed: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return errors.Errorf("Create cassette directory failed: %v", err)
+	}
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		return errors.Errorf("Write cassette failed: %v", err)
+	}
+	return nil
+}
+
+/* Recorder */
+
+// Recorder is an http.RoundTripper recording the exchanges it passes on to
+// Transport. Set it as the Transport of a Connection and Save it when done.
+// Exchanges are recorded when their response body is closed, streams
+// included. The token is never recorded.
+type Recorder struct {
+	// Transport sends the requests, defaults to http.DefaultTransport.
+	Transport http.RoundTripper
+	// Redact rewrites the URLs and bodies that are recorded, e.g. to replace
+	// account IDs.
+	Redact func(s string) string
+
+	mu       sync.Mutex
+	cassette Cassette
+}
+
+func NewRecorder() *Recorder {
+	return new(Recorder)
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil {
+		var err error
+		if reqBody, err = ioutil.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+		req.Body = ioutil.NopCloser(bytes.NewReader(reqBody))
+	}
+
+	transport := r.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	token := strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")
+	redact := func(s string) string {
+		if token != "" {
+			s = strings.ReplaceAll(s, token, redacted)
+		}
+		if r.Redact != nil {
+			s = r.Redact(s)
+		}
+		return s
+	}
+
+	header := resp.Header.Clone()
+	header.Del("Set-Cookie")
+
+	interaction := &Interaction{
+		Request: &InteractionRequest{
+			Method: req.Method,
+			URL:    redact(req.URL.String()),
+			Body:   redact(string(reqBody)),
+		},
+		Response: &InteractionResponse{
+			StatusCode: resp.StatusCode,
+			Header:     header,
+			Stream:     resp.StatusCode == http.StatusOK && strings.HasSuffix(req.URL.Path, "/stream"),
+		},
+	}
+	resp.Body = &recordingBody{
+		ReadCloser: resp.Body,
+		done: func(body []byte) {
+			interaction.Response.Body = redact(string(body))
+			r.mu.Lock()
+			r.cassette.Interactions = append(r.cassette.Interactions, interaction)
+			r.mu.Unlock()
+		},
+	}
+	return resp, nil
+}
+
+// Cassette returns the exchanges recorded so far.
+func (r *Recorder) Cassette() *Cassette {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return &Cassette{Interactions: append([]*Interaction(nil), r.cassette.Interactions...)}
+}
+
+func (r *Recorder) Save(path string) error {
+	return r.Cassette().Save(path)
+}
+
+// recordingBody keeps what was read. Streams are closed while they are read.
+type recordingBody struct {
+	io.ReadCloser
+	mu   sync.Mutex
+	buf  bytes.Buffer
+	once sync.Once
+	done func(body []byte)
+}
+
+func (b *recordingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.mu.Lock()
+	b.buf.Write(p[:n])
+	b.mu.Unlock()
+	return n, err
+}
+
+func (b *recordingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(func() {
+		b.mu.Lock()
+		body := append([]byte(nil), b.buf.Bytes()...)
+		b.mu.Unlock()
+		b.done(body)
+	})
+	return err
+}
+
+/* Replayer */
+
+// Replayer is an http.RoundTripper serving the exchanges of a cassette
+// without a network. Requests are matched by method, URL and body, each
+// interaction is served once in the order it was recorded.
+type Replayer struct {
+	// Redact rewrites the URLs and bodies of requests before they are
+	// matched, like the Redact of the Recorder did.
+	Redact func(s string) string
+
+	mu       sync.Mutex
+	cassette *Cassette
+	used     []bool
+}
+
+func NewReplayer(cassette *Cassette) *Replayer {
+	return &Replayer{
+		cassette: cassette,
+		used:     make([]bool, len(cassette.Interactions)),
+	}
+}
+
+func LoadReplayer(path string) (*Replayer, error) {
+	cassette, err := LoadCassette(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewReplayer(cassette), nil
+}
+
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil {
+		var err error
+		if reqBody, err = ioutil.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+	}
+
+	redact := func(s string) string {
+		if r.Redact != nil {
+			return r.Redact(s)
+		}
+		return s
+	}
+	url, body := redact(req.URL.String()), redact(string(reqBody))
+
+	r.mu.Lock()
+	var interaction *Interaction
+	for i, in := range r.cassette.Interactions {
+		if r.used[i] || in.Request.Method != req.Method || in.Request.URL != url || in.Request.Body != body {
+			continue
+		}
+		r.used[i] = true
+		interaction = in
+		break
+	}
+	r.mu.Unlock()
+
+	if interaction == nil {
+		return nil, errors.Errorf("No recorded interaction for %s %s", req.Method, url)
+	}
+
+	resp := interaction.Response
+	var respBody io.ReadCloser = ioutil.NopCloser(strings.NewReader(resp.Body))
+	if resp.Stream {
+		respBody = &replayStreamBody{
+			Reader: strings.NewReader(resp.Body),
+			done:   req.Context().Done(),
+			closed: make(chan struct{}),
+		}
+	}
+
+	return &http.Response{
+		Status:     http.StatusText(resp.StatusCode),
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header.Clone(),
+		Body:       respBody,
+		Request:    req,
+	}, nil
+}
+
+// replayStreamBody blocks after its data like a stream waiting for messages
+// until it is closed.
+type replayStreamBody struct {
+	io.Reader
+	done      <-chan struct{}
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func (b *replayStreamBody) Read(p []byte) (int, error) {
+	n, err := b.Reader.Read(p)
+	if err != io.EOF {
+		return n, err
+	}
+	if n > 0 {
+		return n, nil
+	}
+	select {
+	case <-b.done:
+	case <-b.closed:
+	}
+	return 0, io.EOF
+}
+
+func (b *replayStreamBody) Close() error {
+	b.closeOnce.Do(func() {
+		close(b.closed)
+	})
+	return nil
+}
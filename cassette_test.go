@@ -0,0 +1,151 @@
+package oanda
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func Test_Cassette(t *testing.T) {
+	const accountID = "101-001-1234567-001"
+
+	server := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		body := `{"accounts":[{"id":"` + accountID + `","tags":[]}]}`
+		if strings.HasSuffix(req.URL.Path, "/pricing/stream") {
+			body = `{"type":"PRICE","instrument":"EUR_USD","time":"2022-01-01T00:00:00.000000000Z","bids":[{"price":"1.10000","liquidity":1000000}],"asks":[{"price":"1.10010","liquidity":1000000}],"tradeable":true}` + "\n" +
+				`{"type":"HEARTBEAT","time":"2022-01-01T00:00:05.000000000Z"}` + "\n"
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Requestid": {"42"}, "Set-Cookie": {"session=secret"}},
+			Body:       ioutil.NopCloser(strings.NewReader(body)),
+			Request:    req,
+		}, nil
+	})
+	path := filepath.Join(t.TempDir(), "cassette.json")
+
+	t.Run("Record", func(t *testing.T) {
+		recorder := &Recorder{
+			Transport: server,
+			Redact: func(s string) string {
+				return strings.ReplaceAll(s, accountID, testReplayAccountID)
+			},
+		}
+		connection := &Connection{Token: "secret-token", Environemnt: OandaPractice, Transport: recorder}
+
+		if _, err := connection.Accounts().Get(context.Background()); err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+		chs, err := connection.Accounts().AccountID(accountID).Pricing().Stream().Ticks(context.Background(), &GetPricingStreamParams{Instruments: []string{"EUR_USD"}})
+		if err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+		<-chs.TickCh
+		chs.Close()
+
+		if err := recorder.Save(path); err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+		for _, secret := range []string{"secret-token", "session=secret", accountID} {
+			if strings.Contains(string(data), secret) {
+				t.Fatalf("Cassette contains %q.\n%s", secret, data)
+			}
+		}
+	})
+
+	t.Run("Replay", func(t *testing.T) {
+		replayer, err := LoadReplayer(path)
+		if err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+		connection := &Connection{Token: "replay", Environemnt: OandaPractice, Strict: true, Transport: replayer}
+
+		accounts, err := connection.Accounts().Get(context.Background())
+		if err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+		if len(accounts.Accounts) != 1 || accounts.Accounts[0].ID != testReplayAccountID {
+			t.Fatalf("Got unexpected accounts.\n%+v", accounts.Accounts)
+		}
+		if accounts.Headers.RequestID != "42" {
+			t.Fatalf("Got unexpected request ID %q.", accounts.Headers.RequestID)
+		}
+
+		chs, err := connection.Accounts().AccountID(testReplayAccountID).Pricing().Stream().Ticks(context.Background(), &GetPricingStreamParams{Instruments: []string{"EUR_USD"}})
+		if err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+		tick := <-chs.TickCh
+		if tick.Instrument != "EUR_USD" || tick.Bid != 1.1 || tick.Ask != 1.1001 {
+			t.Fatalf("Got unexpected tick.\n%+v", tick)
+		}
+		// The stream stays open after the recording like a quiet market.
+		select {
+		case tick, ok := <-chs.TickCh:
+			t.Fatalf("Got unexpected tick %+v, open %v.", tick, ok)
+		case <-time.After(50 * time.Millisecond):
+		}
+		chs.Close()
+		if err := chs.Err(); err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+	})
+
+	t.Run("Unrecorded", func(t *testing.T) {
+		replayer, err := LoadReplayer(path)
+		if err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+		connection := &Connection{Token: "replay", Environemnt: OandaPractice, Transport: replayer}
+
+		if _, err := connection.Accounts().Get(context.Background()); err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+		// Every interaction is served once.
+		if _, err := connection.Accounts().Get(context.Background()); err == nil {
+			t.Fatal("Got no error for a request that wasn't recorded.")
+		}
+	})
+	t.Run("StreamError", func(t *testing.T) {
+		// Error responses of streams are replayed as plain responses.
+		recorder := &Recorder{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusUnauthorized,
+				Header:     http.Header{"Requestid": {"43"}},
+				Body:       ioutil.NopCloser(strings.NewReader(`{"errorMessage":"Insufficient authorization to perform request."}`)),
+				Request:    req,
+			}, nil
+		})}
+		connection := &Connection{Token: "secret-token", Environemnt: OandaPractice, Timeout: time.Second, Transport: recorder}
+		if _, err := connection.Accounts().AccountID(accountID).Pricing().Stream().Get(context.Background(), &GetPricingStreamParams{Instruments: []string{"EUR_USD"}}); err == nil {
+			t.Fatal("Error did not occur.")
+		}
+		cassette := recorder.Cassette()
+		if len(cassette.Interactions) != 1 || cassette.Interactions[0].Response.Stream {
+			t.Fatalf("Got unexpected interactions.\n%+v", cassette.Interactions)
+		}
+
+		connection.Transport = NewReplayer(cassette)
+		_, err := connection.Accounts().AccountID(accountID).Pricing().Stream().Get(context.Background(), &GetPricingStreamParams{Instruments: []string{"EUR_USD"}})
+		var unauthorized *UnauthorizedError
+		if !errors.As(err, &unauthorized) {
+			t.Fatalf("Got unexpected error.\n%+v", err)
+		}
+	})
+}
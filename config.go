@@ -0,0 +1,282 @@
+package oanda
+
+import (
+	"encoding/json"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+/* Config */
+
+// Config holds named credential profiles, e.g. "practice", "live" or one per
+// desk.
+type Config struct {
+	Profiles map[string]*Profile `json:"profiles"`
+	// Profile used when no name is given.
+	Default string `json:"default,omitempty"`
+
+	// Connections to live profiles are refused unless AllowLive is set. It is
+	// not read from config files on purpose, so that it has to be set by the
+	// program or the OANDA_ALLOW_LIVE environment variable.
+	AllowLive bool `json:"-"`
+}
+
+type Profile struct {
+	Name        string `json:"-"`
+	Token       string `json:"token"`
+	AccountID   string `json:"accountID,omitempty"`
+	Environment string `json:"environment"`
+	// Request timeout as a duration string like "30s", defaults to no timeout.
+	Timeout string `json:"timeout,omitempty"`
+	Strict  bool   `json:"strict,omitempty"`
+}
+
+const allowLiveEnv = "OANDA_ALLOW_LIVE"
+
+/* Loaders */
+
+// LoadConfig reads the profiles of a JSON file if path is not empty and adds
+// the profiles of the environment variables with the OANDA prefix, which take
+// precedence.
+func LoadConfig(path string) (*Config, error) {
+	c := &Config{Profiles: make(map[string]*Profile)}
+	if path != "" {
+		file, err := LoadConfigFile(path)
+		if err != nil {
+			return nil, err
+		}
+		c = file
+	}
+
+	env, err := LoadConfigEnv("OANDA")
+	if err != nil {
+		return nil, err
+	}
+	for name, p := range env.Profiles {
+		c.Profiles[name] = p
+	}
+	if env.Default != "" {
+		c.Default = env.Default
+	}
+	c.AllowLive = env.AllowLive
+
+	return c, c.Validate()
+}
+
+// LoadConfigFile reads the profiles of a JSON file like
+//
+//	{
+//	  "default": "practice",
+//	  "profiles": {
+//	    "practice": {"token": "...", "accountID": "...", "environment": "practice"},
+//	    "live": {"token": "...", "accountID": "...", "environment": "live"}
+//	  }
+//	}
+func LoadConfigFile(path string) (*Config, error) {
+	body, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Errorf("Read config file failed: %v", err)
+	}
+
+	c := new(Config)
+	if err := json.Unmarshal(body, c); err != nil {
+		return nil, errors.Errorf("Parse config file %s failed: %v", path, err)
+	}
+	if c.Profiles == nil {
+		c.Profiles = make(map[string]*Profile)
+	}
+	for name, p := range c.Profiles {
+		if p == nil {
+			return nil, errors.Errorf("Parse config file %s failed: profile %#v is empty", path, name)
+		}
+		p.Name = name
+	}
+
+	return c, c.Validate()
+}
+
+// LoadConfigEnv reads the profiles of environment variables. With the prefix
+// "OANDA" the variables
+//
+//	OANDA_TOKEN, OANDA_ACCOUNT_ID, OANDA_ENVIRONMENT, OANDA_TIMEOUT, OANDA_STRICT
+//
+// make up the "default" profile, and for every name in the comma separated
+// OANDA_PROFILES the variables OANDA_<NAME>_TOKEN and so on make up a named
+// profile. An empty prefix reads TOKEN, ACCOUNT_ID and so on.
+func LoadConfigEnv(prefix string) (*Config, error) {
+	c := &Config{Profiles: make(map[string]*Profile)}
+
+	if p := profileFromEnv("default", envKey(prefix)); p != nil {
+		c.Profiles[p.Name] = p
+		c.Default = p.Name
+	}
+
+	for _, name := range strings.Split(os.Getenv(envKey(prefix, "PROFILES")), ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		p := profileFromEnv(name, envKey(prefix, strings.ToUpper(name)))
+		if p == nil {
+			return nil, errors.Errorf("Load config env failed: profile %#v has no %s", name, envKey(prefix, strings.ToUpper(name), "TOKEN"))
+		}
+		c.Profiles[name] = p
+	}
+
+	if v := os.Getenv(envKey(prefix, "DEFAULT_PROFILE")); v != "" {
+		c.Default = v
+	}
+
+	if v := os.Getenv(allowLiveEnv); v != "" {
+		allow, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, errors.Errorf("Load config env failed: %s: %v", allowLiveEnv, err)
+		}
+		c.AllowLive = allow
+	}
+
+	return c, c.Validate()
+}
+
+/* Validation */
+
+func (c *Config) Validate() error {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if err := c.Profiles[name].Validate(); err != nil {
+			return errors.Errorf("Invalid config: %v", err)
+		}
+	}
+
+	if c.Default != "" && c.Profiles[c.Default] == nil {
+		return errors.Errorf("Invalid config: default profile %#v does not exist", c.Default)
+	}
+	return nil
+}
+
+func (p *Profile) Validate() error {
+	if p.Token == "" {
+		return errors.Errorf("profile %#v has no token", p.Name)
+	}
+	if _, err := ParseOandaEnvironment(p.Environment); err != nil {
+		return errors.Errorf("profile %#v: %v", p.Name, err)
+	}
+	if p.Timeout != "" {
+		if d, err := time.ParseDuration(p.Timeout); err != nil || d < 0 {
+			return errors.Errorf("profile %#v has an invalid timeout %#v", p.Name, p.Timeout)
+		}
+	}
+	return nil
+}
+
+/* Connections */
+
+// Profile returns the named profile, or the default profile when name is
+// empty.
+func (c *Config) Profile(name string) (*Profile, error) {
+	if name == "" {
+		name = c.Default
+	}
+	if name == "" {
+		return nil, errors.Errorf("Get profile failed: no profile name given and no default profile")
+	}
+
+	p, ok := c.Profiles[name]
+	if !ok {
+		return nil, &ProfileNotFoundError{ErrorMessage: "Profile " + strconv.Quote(name) + " not found", Name: name}
+	}
+	return p, nil
+}
+
+// Connection builds a Connection from the named profile. Live profiles are
+// refused with a LiveProfileError unless the config allows them.
+func (c *Config) Connection(name string) (*Connection, error) {
+	p, err := c.Profile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	env, err := ParseOandaEnvironment(p.Environment)
+	if err != nil {
+		return nil, errors.Errorf("Build connection failed: %v", err)
+	}
+	if env == OandaLive && !c.AllowLive {
+		return nil, &LiveProfileError{
+			ErrorMessage: "Profile " + strconv.Quote(p.Name) + " is live, set Config.AllowLive or " + allowLiveEnv + " to use it",
+			Name:         p.Name,
+		}
+	}
+
+	return p.connection(env)
+}
+
+func (p *Profile) connection(env OandaEnvironment) (*Connection, error) {
+	var timeout time.Duration
+	if p.Timeout != "" {
+		d, err := time.ParseDuration(p.Timeout)
+		if err != nil {
+			return nil, errors.Errorf("Build connection failed: %v", err)
+		}
+		timeout = d
+	}
+
+	return &Connection{
+		Token:       p.Token,
+		Environemnt: env,
+		Timeout:     timeout,
+		Strict:      p.Strict,
+	}, nil
+}
+
+/* Utils */
+
+// ParseOandaEnvironment parses "practice" or "live".
+func ParseOandaEnvironment(s string) (OandaEnvironment, error) {
+	switch strings.ToLower(s) {
+	case "practice", "fxpractice":
+		return OandaPractice, nil
+	case "live", "fxtrade", "trade":
+		return OandaLive, nil
+	}
+	return 0, errors.Errorf("unknown environment %#v, expect \"practice\" or \"live\"", s)
+}
+
+func profileFromEnv(name, prefix string) *Profile {
+	token := os.Getenv(envKey(prefix, "TOKEN"))
+	if token == "" {
+		return nil
+	}
+
+	p := &Profile{
+		Name:        name,
+		Token:       token,
+		AccountID:   os.Getenv(envKey(prefix, "ACCOUNT_ID")),
+		Environment: os.Getenv(envKey(prefix, "ENVIRONMENT")),
+		Timeout:     os.Getenv(envKey(prefix, "TIMEOUT")),
+	}
+	if p.Environment == "" {
+		p.Environment = "practice"
+	}
+	p.Strict, _ = strconv.ParseBool(os.Getenv(envKey(prefix, "STRICT")))
+	return p
+}
+
+func envKey(parts ...string) string {
+	kept := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if part != "" {
+			kept = append(kept, part)
+		}
+	}
+	return strings.Join(kept, "_")
+}
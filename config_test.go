@@ -0,0 +1,101 @@
+package oanda
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func Test_Config(t *testing.T) {
+	t.Run("File", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "oanda.json")
+		err := os.WriteFile(path, []byte(`{
+			"default": "practice",
+			"profiles": {
+				"practice": {"token": "p-token", "accountID": "101-001", "environment": "practice", "timeout": "10s", "strict": true},
+				"live": {"token": "l-token", "accountID": "001-001", "environment": "live"}
+			}
+		}`), 0600)
+		if err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+
+		config, err := LoadConfigFile(path)
+		if err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+
+		connection, err := config.Connection("")
+		if err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+		if connection.Token != "p-token" || connection.Environemnt != OandaPractice || connection.Timeout != 10*time.Second || !connection.Strict {
+			t.Fatalf("Got unexpected connection.\n%#v", connection)
+		}
+
+		if _, err := config.Connection("live"); err == nil {
+			t.Fatal("Connected to a live profile without opting in.")
+		} else if _, ok := err.(*LiveProfileError); !ok {
+			t.Fatalf("Got unexpected error.\n%+v", err)
+		}
+
+		config.AllowLive = true
+		if connection, err := config.Connection("live"); err != nil || connection.Environemnt != OandaLive {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+
+		if _, err := config.Connection("desk"); err == nil {
+			t.Fatal("Connected to an unknown profile.")
+		} else if _, ok := err.(*ProfileNotFoundError); !ok {
+			t.Fatalf("Got unexpected error.\n%+v", err)
+		}
+	})
+
+	t.Run("Env", func(t *testing.T) {
+		t.Setenv("OANDA_TOKEN", "p-token")
+		t.Setenv("OANDA_ACCOUNT_ID", "101-001")
+		t.Setenv("OANDA_PROFILES", "desk1, desk2")
+		t.Setenv("OANDA_DESK1_TOKEN", "d1-token")
+		t.Setenv("OANDA_DESK1_ENVIRONMENT", "live")
+		t.Setenv("OANDA_DESK2_TOKEN", "d2-token")
+		t.Setenv("OANDA_ALLOW_LIVE", "")
+
+		config, err := LoadConfig("")
+		if err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+		if config.Default != "default" || len(config.Profiles) != 3 {
+			t.Fatalf("Got unexpected config.\n%#v", config)
+		}
+		if p, _ := config.Profile(""); p.AccountID != "101-001" || p.Environment != "practice" {
+			t.Fatalf("Got unexpected profile.\n%#v", p)
+		}
+		if _, err := config.Connection("desk1"); err == nil {
+			t.Fatal("Connected to a live profile without opting in.")
+		}
+
+		t.Setenv("OANDA_ALLOW_LIVE", "true")
+		config, err = LoadConfig("")
+		if err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+		if connection, err := config.Connection("desk1"); err != nil || connection.Token != "d1-token" {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		patterns := map[string]*Config{
+			"NoToken":        {Profiles: map[string]*Profile{"a": {Environment: "practice"}}},
+			"Environment":    {Profiles: map[string]*Profile{"a": {Token: "t", Environment: "sandbox"}}},
+			"Timeout":        {Profiles: map[string]*Profile{"a": {Token: "t", Environment: "live", Timeout: "soon"}}},
+			"DefaultMissing": {Profiles: map[string]*Profile{"a": {Token: "t", Environment: "live"}}, Default: "b"},
+		}
+		for name, config := range patterns {
+			if err := config.Validate(); err == nil {
+				t.Errorf("%s: validated an invalid config.", name)
+			}
+		}
+	})
+}
@@ -24,79 +24,132 @@ type Connection struct {
 	Token       string
 	Environemnt OandaEnvironment
 	Timeout     time.Duration
-	Strict      bool
+	// Strict fails calls whose responses don't match their schemas.
+	Strict bool
+	// Drift reports the responses that don't match their schemas without
+	// failing the calls. Strict takes precedence.
+	Drift *DriftReporter
+
+	// DryRun makes mutating calls log the request and return a simulated
+	// response instead of sending it.
+	DryRun bool
+	// OrderPolicy can veto orders before they are created or replaced.
+	OrderPolicy OrderPolicy
+
+	Middlewares []Middleware
+	StreamHooks []*StreamHook
+
+	// Logger receives the logs at LogLevel and above, with the token and
+	// account IDs redacted.
+	Logger   Logger
+	LogLevel LogLevel
+
+	Metrics Metrics
+
+	// Transport sends the requests, defaults to http.DefaultTransport. A
+	// Recorder or Replayer records or replays the exchanges with the API.
+	Transport http.RoundTripper
 }
 
-func (c *Connection) request(ctx context.Context, params *requestParams) (*http.Response, error) {
-	destURL := oandaBaseURL(c.Environemnt).rest
-	destURL.Path = path.Join(destURL.Path, params.endPoint)
-
-	var reader io.Reader
-	if params.body != nil {
-		body, _ := json.Marshal(params.body)
-		reader = bytes.NewBuffer(body)
-	}
-
-	req, err := http.NewRequestWithContext(ctx, params.method, destURL.String(), reader)
+func (c *Connection) request(ctx context.Context, params *requestParams) (interface{}, error) {
+	start := time.Now()
+	req := newRequest(params)
+	resp, err := c.chain(c.send(params))(ctx, req)
+	c.observeRequest(req, resp, time.Since(start))
+	c.logRequest(ctx, req, resp, err)
 	if err != nil {
-		return nil, errors.Errorf("Prepare new request failed: %v", err)
+		return nil, err
 	}
-
-	// req.Header.Set("User-Agent", "Go 1.1 package http")
-	req.Header.Set("Authorization", "Bearer "+c.Token)
-	req.Header.Set("Content-Type", "application/json")
-	for _, h := range params.headers {
-		req.Header.Set(h.key, h.value)
+	if resp == nil {
+		return nil, errors.New("Middleware returned no response")
 	}
+	return resp.Result, nil
+}
 
-	reqQ := req.URL.Query()
-	for _, q := range params.queries {
-		reqQ.Add(q.key, q.value)
+// send returns the innermost handler, which sends the request and decodes the
+// response into the result registered for its status code.
+func (c *Connection) send(params *requestParams) Handler {
+	return func(ctx context.Context, req *Request) (*Response, error) {
+		start := time.Now()
+
+		destURL := oandaBaseURL(c.Environemnt).rest
+		destURL.Path = path.Join(destURL.Path, req.EndPoint)
+		destURL.RawQuery = req.Query.Encode()
+
+		var httpResp *http.Response
+		var err error
+		if c.DryRun && req.Method != "GET" {
+			httpResp, err = c.simulated(ctx, req.Method, destURL.String(), req.Body, params.simulate)
+		} else {
+			httpResp, err = c.do(ctx, req, destURL.String(), c.Timeout)
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "Request canceled")
+		}
+		defer httpResp.Body.Close()
+
+		resp := &Response{
+			StatusCode: httpResp.StatusCode,
+			Header:     httpResp.Header,
+		}
+		resp.Result, err = parseResponse(httpResp, params.results[httpResp.StatusCode], c.compare(ctx, req, httpResp))
+		resp.Latency = time.Since(start)
+
+		return resp, err
 	}
-	req.URL.RawQuery = reqQ.Encode()
+}
 
-	client := http.Client{
-		Timeout: c.Timeout,
-	}
+// stream opens a stream. Middlewares don't apply to streams, they are
+// observed by the stream hooks instead.
+func (c *Connection) stream(ctx context.Context, params *requestParams) (*http.Response, *streamObserver, error) {
+	start := time.Now()
+
+	req := newRequest(params)
+	req.Stream = true
+	observer := c.newStreamObserver(req)
 
-	resp, err := client.Do(req)
+	destURL := oandaBaseURL(c.Environemnt).stream
+	destURL.Path = path.Join(destURL.Path, req.EndPoint)
+	destURL.RawQuery = req.Query.Encode()
+
+	resp, err := c.do(ctx, req, destURL.String(), 0)
 	if err != nil {
-		return nil, errors.Errorf("Request canceled: %v", err)
+		observer.open(ctx, nil, err)
+		return nil, nil, errors.Errorf("error in stream method: %v", err)
 	}
 
-	return resp, nil
+	observer.open(ctx, &Response{
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		Latency:    time.Since(start),
+	}, nil)
+
+	return resp, observer, nil
 }
 
-func (c *Connection) stream(ctx context.Context, params *requestParams) (*http.Response, error) {
-	destURL := oandaBaseURL(c.Environemnt).stream
-	destURL.Path = path.Join(destURL.Path, params.endPoint)
+func (c *Connection) do(ctx context.Context, req *Request, destURL string, timeout time.Duration) (*http.Response, error) {
+	var reader io.Reader
+	if req.Body != nil {
+		body, _ := json.Marshal(req.Body)
+		reader = bytes.NewBuffer(body)
+	}
 
-	req, err := http.NewRequestWithContext(ctx, params.method, destURL.String(), nil)
+	httpReq, err := http.NewRequestWithContext(ctx, req.Method, destURL, reader)
 	if err != nil {
-		return nil, errors.Errorf("error in stream method: %v", err)
+		return nil, errors.Errorf("Prepare new request failed: %v", err)
 	}
 
 	// req.Header.Set("User-Agent", "Go 1.1 package http")
-	req.Header.Set("Authorization", "Bearer "+c.Token)
-	req.Header.Set("Content-Type", "application/json")
-	for _, h := range params.headers {
-		req.Header.Set(h.key, h.value)
-	}
-
-	reqQ := req.URL.Query()
-	for _, q := range params.queries {
-		reqQ.Add(q.key, q.value)
+	httpReq.Header.Set("Authorization", "Bearer "+c.Token)
+	httpReq.Header.Set("Content-Type", "application/json")
+	for k, v := range req.Header {
+		httpReq.Header[k] = v
 	}
-	req.URL.RawQuery = reqQ.Encode()
 
 	client := http.Client{
-		Timeout: 0,
-	}
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, errors.Errorf("error in stream method: %v", err)
+		Timeout:   timeout,
+		Transport: c.Transport,
 	}
 
-	return resp, nil
+	return client.Do(httpReq)
 }
@@ -0,0 +1,295 @@
+package oanda
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+/* Params */
+
+type GetHomeConverterParams struct {
+	// Instruments whose quote currencies have to be convertible.
+	Instruments []string
+	// Interval of refreshing the conversion factors. Zero disables refreshing.
+	Interval time.Duration
+}
+
+/* Converter */
+
+// HomeConverter converts amounts between the quote currencies of instruments
+// and the home currency of an account.
+type HomeConverter struct {
+	HomeCurrency CurrencyDefinition
+
+	mu        sync.RWMutex
+	factors   map[CurrencyDefinition]*homeConversionFactors
+	updated   time.Time
+	lastError error
+
+	close     context.CancelFunc
+	closeWait *sync.WaitGroup
+}
+
+type homeConversionFactors struct {
+	accountGain   float64
+	accountLoss   float64
+	positionValue float64
+}
+
+func NewHomeConverter(homeCurrency CurrencyDefinition) *HomeConverter {
+	return &HomeConverter{
+		HomeCurrency: homeCurrency,
+		factors:      make(map[CurrencyDefinition]*homeConversionFactors),
+		close:        func() {},
+		closeWait:    new(sync.WaitGroup),
+	}
+}
+
+/* API */
+
+// HomeConverter creates a converter for the account's home currency that is
+// kept current by polling GET /v3/accounts/{accountID}/pricing with
+// includeHomeConversions.
+func (r *ReceiverAccountID) HomeConverter(ctx context.Context, params *GetHomeConverterParams) (*HomeConverter, error) {
+	summary, err := r.Summary().Get(ctx)
+	if err != nil {
+		return nil, errors.Errorf("Get home converter failed: %v", err)
+	}
+
+	c := NewHomeConverter(summary.Account.Currency)
+	if err := c.Refresh(ctx, r.Pricing(), params.Instruments); err != nil {
+		return nil, errors.Errorf("Get home converter failed: %v", err)
+	}
+
+	if params.Interval > 0 {
+		childCtx, cancel := context.WithCancel(ctx)
+		c.close = cancel
+
+		c.closeWait.Add(1)
+		go func() {
+			defer c.closeWait.Done()
+
+			ticker := time.NewTicker(params.Interval)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-childCtx.Done():
+					return
+				case <-ticker.C:
+				}
+
+				if err := c.Refresh(childCtx, r.Pricing(), params.Instruments); err != nil {
+					select {
+					case <-childCtx.Done():
+						return
+					default:
+					}
+					c.mu.Lock()
+					c.lastError = err
+					c.mu.Unlock()
+				}
+			}
+		}()
+	}
+
+	return c, nil
+}
+
+// Refresh fetches the current conversion factors.
+func (c *HomeConverter) Refresh(ctx context.Context, r *ReceiverPricing, instruments []string) error {
+	data, err := r.Get(ctx, &GetPricingParams{
+		Instruments:            instruments,
+		IncludeHomeConversions: Bool(true),
+	})
+	if err != nil {
+		return errors.Errorf("Refresh home conversions failed: %v", err)
+	}
+
+	return c.Update(data.HomeConversions)
+}
+
+// Update replaces the conversion factors of the given currencies.
+func (c *HomeConverter) Update(conversions []*HomeConversionsDefinition) error {
+	factors := make(map[CurrencyDefinition]*homeConversionFactors, len(conversions))
+	for _, conversion := range conversions {
+		f := new(homeConversionFactors)
+		var err error
+		if f.accountGain, err = parseDecimal(conversion.AccountGain); err != nil {
+			return errors.Errorf("Update %s home conversion failed: %v", conversion.Currency, err)
+		}
+		if f.accountLoss, err = parseDecimal(conversion.AccountLoss); err != nil {
+			return errors.Errorf("Update %s home conversion failed: %v", conversion.Currency, err)
+		}
+		if f.positionValue, err = parseDecimal(conversion.PositionValue); err != nil {
+			return errors.Errorf("Update %s home conversion failed: %v", conversion.Currency, err)
+		}
+		factors[conversion.Currency] = f
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for currency, f := range factors {
+		c.factors[currency] = f
+	}
+	c.updated = time.Now()
+
+	return nil
+}
+
+// UpdateFromPrice takes the deprecated quoteHomeConversionFactors of a
+// streamed price. The stream provides no position value factor, the mean of
+// the gain and loss factors is used instead.
+func (c *HomeConverter) UpdateFromPrice(price *PriceDefinition) error {
+	if price.QuoteHomeConversionFactors == nil {
+		return nil
+	}
+
+	positive, err := parseDecimal(price.QuoteHomeConversionFactors.PositiveUnits)
+	if err != nil {
+		return errors.Errorf("Update %s home conversion failed: %v", price.Instrument, err)
+	}
+	negative, err := parseDecimal(price.QuoteHomeConversionFactors.NegativeUnits)
+	if err != nil {
+		return errors.Errorf("Update %s home conversion failed: %v", price.Instrument, err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.factors[QuoteCurrency(price.Instrument)] = &homeConversionFactors{
+		accountGain:   positive,
+		accountLoss:   negative,
+		positionValue: (positive + negative) / 2,
+	}
+	c.updated = time.Now()
+
+	return nil
+}
+
+// UpdateFromFactors takes the conversion factors of the quote and base
+// currencies of an instrument, e.g. of the homeConversionFactors of an order
+// fill. The factors provide no position value factor, the mean of the gain
+// and loss factors is used instead.
+func (c *HomeConverter) UpdateFromFactors(instrument InstrumentNameDefinition, factors *HomeConversionFactorsDefinition) error {
+	updates := make(map[CurrencyDefinition]*homeConversionFactors, 2)
+	for _, f := range []struct {
+		currency   CurrencyDefinition
+		gain, loss ConversionFactorDefinition
+	}{
+		{QuoteCurrency(instrument), factors.GainQuoteHome, factors.LossQuoteHome},
+		{BaseCurrency(instrument), factors.GainBaseHome, factors.LossBaseHome},
+	} {
+		if f.gain.Factor == "" || f.loss.Factor == "" {
+			continue
+		}
+		gain, err := parseDecimal(f.gain.Factor)
+		if err != nil {
+			return errors.Errorf("Update %s home conversion failed: %v", f.currency, err)
+		}
+		loss, err := parseDecimal(f.loss.Factor)
+		if err != nil {
+			return errors.Errorf("Update %s home conversion failed: %v", f.currency, err)
+		}
+		updates[f.currency] = &homeConversionFactors{
+			accountGain:   gain,
+			accountLoss:   loss,
+			positionValue: (gain + loss) / 2,
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for currency, f := range updates {
+		c.factors[currency] = f
+	}
+	c.updated = time.Now()
+
+	return nil
+}
+
+// ConvertPL converts a profit or loss in currency into the home currency.
+func (c *HomeConverter) ConvertPL(currency CurrencyDefinition, amount float64) (float64, error) {
+	f, err := c.lookup(currency)
+	if err != nil {
+		return 0, err
+	}
+	if amount < 0 {
+		return amount * f.accountLoss, nil
+	}
+	return amount * f.accountGain, nil
+}
+
+// ConvertPositionValue converts a position value in currency into the home
+// currency.
+func (c *HomeConverter) ConvertPositionValue(currency CurrencyDefinition, amount float64) (float64, error) {
+	f, err := c.lookup(currency)
+	if err != nil {
+		return 0, err
+	}
+	return amount * f.positionValue, nil
+}
+
+// ConvertMargin converts a margin in currency into the home currency. Margin
+// is derived from the position value and uses the same factor.
+func (c *HomeConverter) ConvertMargin(currency CurrencyDefinition, amount float64) (float64, error) {
+	return c.ConvertPositionValue(currency, amount)
+}
+
+// ConvertFromHome converts an amount in the home currency into currency.
+func (c *HomeConverter) ConvertFromHome(currency CurrencyDefinition, amount float64) (float64, error) {
+	f, err := c.lookup(currency)
+	if err != nil {
+		return 0, err
+	}
+	if f.positionValue == 0 {
+		return 0, errors.Errorf("Home conversion factor of %s is zero", currency)
+	}
+	return amount / f.positionValue, nil
+}
+
+// Convert converts an amount between two currencies via the home currency.
+func (c *HomeConverter) Convert(from, to CurrencyDefinition, amount float64) (float64, error) {
+	home, err := c.ConvertPositionValue(from, amount)
+	if err != nil {
+		return 0, err
+	}
+	return c.ConvertFromHome(to, home)
+}
+
+/* Utils */
+
+// Updated returns when the conversion factors were updated last.
+func (c *HomeConverter) Updated() time.Time {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.updated
+}
+
+func (c *HomeConverter) Close() {
+	c.close()
+	c.closeWait.Wait()
+}
+
+// Err returns the last error of refreshing the conversion factors.
+func (c *HomeConverter) Err() error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.lastError
+}
+
+func (c *HomeConverter) lookup(currency CurrencyDefinition) (*homeConversionFactors, error) {
+	if currency == c.HomeCurrency {
+		return &homeConversionFactors{accountGain: 1, accountLoss: 1, positionValue: 1}, nil
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	f, ok := c.factors[currency]
+	if !ok {
+		return nil, errors.Errorf("Home conversion of %s is unknown", currency)
+	}
+	return f, nil
+}
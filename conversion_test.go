@@ -0,0 +1,65 @@
+package oanda
+
+import (
+	"math"
+	"testing"
+)
+
+func Test_HomeConverter(t *testing.T) {
+	c := NewHomeConverter("EUR")
+	err := c.Update([]*HomeConversionsDefinition{
+		{Currency: "USD", AccountGain: "0.9", AccountLoss: "0.92", PositionValue: "0.91"},
+		{Currency: "JPY", AccountGain: "0.0061", AccountLoss: "0.0063", PositionValue: "0.0062"},
+	})
+	if err != nil {
+		t.Fatalf("Error occurred.\n%+v", err)
+	}
+
+	patterns := []struct {
+		name    string
+		convert func() (float64, error)
+		expect  float64
+	}{
+		{"Gain", func() (float64, error) { return c.ConvertPL("USD", 100) }, 90},
+		{"Loss", func() (float64, error) { return c.ConvertPL("USD", -100) }, -92},
+		{"PositionValue", func() (float64, error) { return c.ConvertPositionValue("JPY", 10000) }, 62},
+		{"Margin", func() (float64, error) { return c.ConvertMargin("USD", 1000) }, 910},
+		{"Home", func() (float64, error) { return c.ConvertPL("EUR", -5) }, -5},
+		{"FromHome", func() (float64, error) { return c.ConvertFromHome("USD", 91) }, 100},
+		{"CrossCurrency", func() (float64, error) { return c.Convert("USD", "JPY", 62) }, 9100},
+	}
+
+	for _, pattern := range patterns {
+		t.Run(pattern.name, func(t *testing.T) {
+			actual, err := pattern.convert()
+			if err != nil {
+				t.Fatalf("Error occurred.\n%+v", err)
+			}
+			if math.Abs(actual-pattern.expect) > 1e-9 {
+				t.Fatalf("Got unexpected amount.\nExpect: %v\nActual: %v", pattern.expect, actual)
+			}
+		})
+	}
+
+	t.Run("UnknownCurrency", func(t *testing.T) {
+		if _, err := c.ConvertPL("CHF", 1); err == nil {
+			t.Fatal("Converted an unknown currency.")
+		}
+	})
+
+	t.Run("UpdateFromPrice", func(t *testing.T) {
+		err := c.UpdateFromPrice(&PriceDefinition{
+			Instrument: "EUR_CHF",
+			QuoteHomeConversionFactors: &QuoteHomeConversionFactorsDefinition{
+				PositiveUnits: "1.02",
+				NegativeUnits: "1.04",
+			},
+		})
+		if err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+		if actual, _ := c.ConvertPL("CHF", -1); actual != -1.04 {
+			t.Fatalf("Got unexpected amount.\nExpect: %v\nActual: %v", -1.04, actual)
+		}
+	})
+}
@@ -0,0 +1,147 @@
+package oanda
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+/* Drift */
+
+// SchemaDrift is a value of a response that is lost when the response is
+// decoded into its schema, e.g. a field OANDA added to the API.
+type SchemaDrift struct {
+	Method string
+	// Endpoint is the route of the request, e.g.
+	// "/v3/accounts/{accountID}/summary".
+	Endpoint string
+	// Path of the value in the response, e.g. "account.trades[0].foo".
+	Path string
+	// Type is the JSON type of Expect: "object", "array", "string", "number"
+	// or "bool".
+	Type   string
+	Expect interface{}
+	// Actual is the value after decoding and encoding the schema, nil when
+	// the schema lacks it.
+	Actual    interface{}
+	RequestID string
+}
+
+// DriftReporter reports the drifts of responses from their schemas instead
+// of failing the calls like Connection.Strict. Every drift is reported once
+// per endpoint.
+type DriftReporter struct {
+	// Report receives the drifts. They are logged by the Logger of the
+	// connection as warnings when Report is nil.
+	Report func(ctx context.Context, drift *SchemaDrift)
+
+	mu   sync.Mutex
+	seen map[string]bool
+}
+
+func NewDriftReporter(report func(ctx context.Context, drift *SchemaDrift)) *DriftReporter {
+	return &DriftReporter{Report: report}
+}
+
+// report reports drift unless a drift with the same key was reported for the
+// endpoint.
+func (r *DriftReporter) report(ctx context.Context, c *Connection, key string, drift *SchemaDrift) {
+	key = drift.Method + " " + drift.Endpoint + " " + key
+
+	r.mu.Lock()
+	if r.seen == nil {
+		r.seen = make(map[string]bool)
+	}
+	seen := r.seen[key]
+	r.seen[key] = true
+	r.mu.Unlock()
+	if seen {
+		return
+	}
+
+	if r.Report != nil {
+		r.Report(ctx, drift)
+		return
+	}
+	c.log(ctx, LogLevelWarn, "oanda: schema drift",
+		"method", drift.Method,
+		"endpoint", drift.Endpoint,
+		"path", drift.Path,
+		"type", drift.Type,
+		"request_id", drift.RequestID,
+	)
+}
+
+// compare returns the comparison of response bodies with their decoded data
+// for parseResponse, nil when responses aren't compared.
+func (c *Connection) compare(ctx context.Context, req *Request, resp *http.Response) func(data interface{}, body []byte) error {
+	switch {
+	case c.Strict:
+		return func(data interface{}, body []byte) error {
+			if err := compareJson(data, body); err != nil {
+				return errors.Errorf("Response body JSON is different from unmarshalled it: %v", err)
+			}
+			return nil
+		}
+	case c.Drift != nil:
+		return func(data interface{}, body []byte) error {
+			err := diffJson(data, body, func(d *jsonDiff) {
+				// Elements of lists drift alike, so their indices are left
+				// out of the key.
+				c.Drift.report(ctx, c, jsonPath(d.Breadcrumbs[1:], true), &SchemaDrift{
+					Method:    req.Method,
+					Endpoint:  req.Route,
+					Path:      jsonPath(d.Breadcrumbs[1:], false),
+					Type:      jsonType(d.Expect),
+					Expect:    d.Expect,
+					Actual:    d.Actual,
+					RequestID: resp.Header.Get("RequestID"),
+				})
+			})
+			if err != nil {
+				c.log(ctx, LogLevelWarn, "oanda: schema drift not checked", "endpoint", req.Route, "error", err)
+			}
+			return nil
+		}
+	}
+	return nil
+}
+
+/* Utils */
+
+func jsonPath(breadcrumbs []string, anyIndex bool) string {
+	var b strings.Builder
+	for _, crumb := range breadcrumbs {
+		if strings.HasPrefix(crumb, "[") {
+			if anyIndex {
+				crumb = "[]"
+			}
+		} else if b.Len() > 0 {
+			b.WriteByte('.')
+		}
+		b.WriteString(crumb)
+	}
+	return b.String()
+}
+
+func jsonType(v interface{}) string {
+	switch v.(type) {
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case float64, json.Number:
+		return "number"
+	case bool:
+		return "bool"
+	case nil:
+		return "null"
+	}
+	return "unknown"
+}
@@ -0,0 +1,104 @@
+package oanda
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func Test_Drift(t *testing.T) {
+	ctx := context.Background()
+	body := `{"account":{"id":"101","trades":[{"id":"1","newField":"a"},{"id":"2","newField":"b"}]},"lastTransactionID":"7","newTop":{"x":1}}`
+
+	parse := func(connection *Connection) error {
+		header := http.Header{}
+		header.Set("RequestID", "42")
+		resp := &http.Response{StatusCode: 200, Header: header, Body: io.NopCloser(strings.NewReader(body))}
+		req := newRequest(&requestParams{method: "GET", endPoint: "/v3/accounts/101"})
+
+		_, err := parseResponse(resp, new(GetAccountIDSchema), connection.compare(ctx, req, resp))
+		return err
+	}
+
+	t.Run("Report", func(t *testing.T) {
+		var drifts []*SchemaDrift
+		connection := &Connection{Drift: NewDriftReporter(func(ctx context.Context, drift *SchemaDrift) {
+			drifts = append(drifts, drift)
+		})}
+
+		for i := 0; i < 2; i++ {
+			if err := parse(connection); err != nil {
+				t.Fatalf("Error occurred.\n%+v", err)
+			}
+		}
+
+		if len(drifts) != 2 {
+			t.Fatalf("Got unexpected drifts.\n%+v", drifts)
+		}
+		paths := map[string]*SchemaDrift{}
+		for _, d := range drifts {
+			paths[d.Path] = d
+		}
+		trade, top := paths["account.trades[0].newField"], paths["newTop"]
+		if trade == nil || top == nil {
+			t.Fatalf("Got unexpected drifts.\n%+v %+v", drifts[0], drifts[1])
+		}
+		if trade.Type != "string" || trade.Expect != "a" || trade.Actual != nil || trade.Endpoint != "/v3/accounts/{accountID}" || trade.RequestID != "42" {
+			t.Fatalf("Got unexpected drift.\n%+v", trade)
+		}
+		if top.Type != "object" {
+			t.Fatalf("Got unexpected drift.\n%+v", top)
+		}
+	})
+
+	t.Run("Log", func(t *testing.T) {
+		var messages []string
+		connection := &Connection{
+			Drift: new(DriftReporter),
+			Logger: LoggerFunc(func(ctx context.Context, level LogLevel, msg string, keyvals ...interface{}) {
+				messages = append(messages, msg)
+			}),
+		}
+		if err := parse(connection); err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+		if len(messages) != 2 || messages[0] != "oanda: schema drift" {
+			t.Fatalf("Got unexpected messages.\n%v", messages)
+		}
+	})
+
+	t.Run("Strict", func(t *testing.T) {
+		connection := &Connection{Strict: true, Drift: new(DriftReporter)}
+		if err := parse(connection); err == nil {
+			t.Fatalf("Error did not occur.")
+		}
+	})
+}
+
+func Test_deepEqual(t *testing.T) {
+	t.Run("Null", func(t *testing.T) {
+		if err := deepEqual(map[string]interface{}{"a": nil}, map[string]interface{}{}, nil); err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+	})
+
+	t.Run("ShortArray", func(t *testing.T) {
+		err := deepEqual([]interface{}{"a", "b"}, []interface{}{"a"}, []string{"root"})
+		if err == nil || !strings.Contains(err.Error(), "root > [1]") {
+			t.Fatalf("Got unexpected error.\n%v", err)
+		}
+	})
+
+	t.Run("AllKeys", func(t *testing.T) {
+		err := deepEqual(
+			map[string]interface{}{"empty": []interface{}{}, "b": "x"},
+			map[string]interface{}{},
+			[]string{"root"},
+		)
+		if err == nil || !strings.Contains(err.Error(), "root > b") {
+			t.Fatalf("Got unexpected error.\n%v", err)
+		}
+	})
+}
@@ -0,0 +1,216 @@
+package oanda
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+/* Policy */
+
+// OrderPolicy is consulted before an order is created or replaced. Returning
+// an error vetoes the order, which is then never sent.
+type OrderPolicy func(ctx context.Context, req *OrderPolicyRequest) error
+
+type OrderPolicyRequest struct {
+	AccountID AccountIDDefinition
+	Order     OrderRequestDefinition
+	// ID of the order that is replaced, empty when a new order is created.
+	ReplacesOrderID string
+	// Whether the order goes to the live environment.
+	Live   bool
+	DryRun bool
+
+	// Fields of the order, empty when the order type doesn't have them.
+	Type       OrderTypeDefinition
+	Instrument InstrumentNameDefinition
+	Units      DecimalNumberDefinition
+	Price      PriceValueDefinition
+	PriceBound PriceValueDefinition
+	TradeID    TradeIDDefinition
+}
+
+// OrderLimits vetoes live orders exceeding a number of units or a notional
+// value in the quote currency of the instrument. A zero limit is not checked.
+type OrderLimits struct {
+	MaxUnits    float64
+	MaxNotional float64
+	// Price used for the notional of orders without a price, e.g. market
+	// orders without a price bound. Such orders are vetoed when Price is nil.
+	Price func(ctx context.Context, instrument InstrumentNameDefinition) (float64, error)
+}
+
+// Policy returns an OrderPolicy checking live orders against the limits.
+func (l *OrderLimits) Policy() OrderPolicy {
+	return func(ctx context.Context, req *OrderPolicyRequest) error {
+		if !req.Live || req.Units == "" {
+			return nil
+		}
+
+		units, err := parseDecimal(req.Units)
+		if err != nil {
+			return errors.Errorf("Check order limits failed: %v", err)
+		}
+		units = math.Abs(units)
+
+		if l.MaxUnits > 0 && units > l.MaxUnits {
+			return errors.Errorf("%v units of %s exceed the limit of %v units", units, req.Instrument, l.MaxUnits)
+		}
+
+		if l.MaxNotional > 0 {
+			price, err := l.price(ctx, req)
+			if err != nil {
+				return errors.Errorf("Check order limits failed: %v", err)
+			}
+			if notional := units * price; notional > l.MaxNotional {
+				return errors.Errorf("Notional %v of %s exceeds the limit of %v", notional, req.Instrument, l.MaxNotional)
+			}
+		}
+		return nil
+	}
+}
+
+func (l *OrderLimits) price(ctx context.Context, req *OrderPolicyRequest) (float64, error) {
+	for _, p := range []PriceValueDefinition{req.Price, req.PriceBound} {
+		if p != "" {
+			return parseDecimal(p)
+		}
+	}
+	if l.Price == nil {
+		return 0, errors.Errorf("no price to check the notional of %s", req.Instrument)
+	}
+	return l.Price(ctx, req.Instrument)
+}
+
+func (c *Connection) checkOrderPolicy(ctx context.Context, accountID AccountIDDefinition, replaces string, order OrderRequestDefinition) error {
+	if c.OrderPolicy == nil {
+		return nil
+	}
+
+	req, err := newOrderPolicyRequest(accountID, replaces, order)
+	if err != nil {
+		return err
+	}
+	req.Live = c.Environemnt == OandaLive
+	req.DryRun = c.DryRun
+
+	if err := c.OrderPolicy(ctx, req); err != nil {
+		return &OrderPolicyError{
+			ErrorMessage: "Order vetoed by policy: " + err.Error(),
+			Err:          err,
+		}
+	}
+	return nil
+}
+
+func newOrderPolicyRequest(accountID AccountIDDefinition, replaces string, order OrderRequestDefinition) (*OrderPolicyRequest, error) {
+	// Every order request type is a flat struct sharing the JSON names of its
+	// fields, so the fields are picked out of its JSON.
+	body, err := json.Marshal(order)
+	if err != nil {
+		return nil, errors.Errorf("Marshal order request failed: %v", err)
+	}
+
+	var fields struct {
+		Type       OrderTypeDefinition      `json:"type"`
+		Instrument InstrumentNameDefinition `json:"instrument"`
+		Units      DecimalNumberDefinition  `json:"units"`
+		Price      PriceValueDefinition     `json:"price"`
+		PriceBound PriceValueDefinition     `json:"priceBound"`
+		TradeID    TradeIDDefinition        `json:"tradeID"`
+	}
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return nil, errors.Errorf("Unmarshal order request failed: %v", err)
+	}
+
+	return &OrderPolicyRequest{
+		AccountID:       accountID,
+		Order:           order,
+		ReplacesOrderID: replaces,
+		Type:            fields.Type,
+		Instrument:      fields.Instrument,
+		Units:           fields.Units,
+		Price:           fields.Price,
+		PriceBound:      fields.PriceBound,
+		TradeID:         fields.TradeID,
+	}, nil
+}
+
+/* Dry run */
+
+// simulated returns the response the request would have received in dry-run
+// mode and logs what would have been sent.
+func (c *Connection) simulated(ctx context.Context, method, destURL string, reqBody interface{}, simulate func() (int, interface{})) (*http.Response, error) {
+	var body []byte
+	if reqBody != nil {
+		body, _ = json.Marshal(reqBody)
+	}
+	c.log(ctx, LogLevelInfo, "oanda: dry run, not sending request", "method", method, "url", destURL, "body", body)
+
+	if simulate == nil {
+		return nil, errors.Errorf("Dry run of %s %s is not supported", method, destURL)
+	}
+	status, data := simulate()
+
+	return jsonResponse(status, data, "dry-run")
+}
+
+// jsonResponse returns a response carrying data as its JSON body.
+func jsonResponse(status int, data interface{}, requestID string) (*http.Response, error) {
+	resBody, err := json.Marshal(data)
+	if err != nil {
+		return nil, errors.Errorf("Marshal simulated response failed: %v", err)
+	}
+
+	// Schemas carry untagged fields like Headers that the API never sends.
+	fields := make(map[string]interface{})
+	if err := json.Unmarshal(resBody, &fields); err == nil {
+		for k, v := range fields {
+			if v == nil {
+				delete(fields, k)
+			}
+		}
+		resBody, _ = json.Marshal(fields)
+	}
+
+	header := make(http.Header)
+	header.Set("Content-Type", "application/json")
+	header.Set("RequestID", requestID)
+
+	return &http.Response{
+		Status:     http.StatusText(status),
+		StatusCode: status,
+		Header:     header,
+		Body:       io.NopCloser(bytes.NewReader(resBody)),
+	}, nil
+}
+
+func dryRunTransaction(accountID AccountIDDefinition, typ TransactionTypeDefinition, reason Reason) *TransactionDefinition {
+	return &TransactionDefinition{
+		AccountID: accountID,
+		Type:      typ,
+		Reason:    reason,
+		Time:      time.Now().UTC().Format(time.RFC3339Nano),
+	}
+}
+
+func dryRunOrderTransaction(accountID AccountIDDefinition, order OrderRequestDefinition) *TransactionDefinition {
+	tx := dryRunTransaction(accountID, "", "CLIENT_ORDER")
+	if req, err := newOrderPolicyRequest(accountID, "", order); err == nil {
+		if req.Type != "" {
+			tx.Type = strings.TrimSuffix(req.Type, "_ORDER") + "_ORDER"
+		}
+		tx.Instrument = req.Instrument
+		tx.Units = req.Units
+		tx.PriceBound = req.PriceBound
+		tx.TradeID = req.TradeID
+	}
+	return tx
+}
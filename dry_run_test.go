@@ -0,0 +1,89 @@
+package oanda
+
+import (
+	"context"
+	"testing"
+)
+
+func Test_DryRun(t *testing.T) {
+	connection := &Connection{
+		Token:       "dummy",
+		Environemnt: OandaLive,
+		Strict:      true,
+		DryRun:      true,
+		OrderPolicy: (&OrderLimits{MaxUnits: 1000, MaxNotional: 1500}).Policy(),
+	}
+	ctx := context.Background()
+	account := connection.Accounts().AccountID("001-001-0000001-001")
+
+	t.Run("PostOrders", func(t *testing.T) {
+		data, err := account.Orders().Post(ctx, &PostOrdersParams{
+			Body: PostOrdersBodyParams{
+				Order: &LimitOrderRequestDefinition{Type: "LIMIT", Instrument: "EUR_USD", Units: "-1000", Price: "1.1"},
+			},
+		})
+		if err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+		tx := data.OrderCreateTransaction
+		if tx.Type != "LIMIT_ORDER" || tx.Instrument != "EUR_USD" || tx.Units != "-1000" {
+			t.Fatalf("Got unexpected transaction.\n%#v", tx)
+		}
+	})
+
+	t.Run("PolicyVeto", func(t *testing.T) {
+		patterns := map[string]OrderRequestDefinition{
+			"Units":        &MarketOrderRequestDefinition{Type: "MARKET", Instrument: "EUR_USD", Units: "1001", PriceBound: "1.1"},
+			"Notional":     &LimitOrderRequestDefinition{Type: "LIMIT", Instrument: "EUR_USD", Units: "1000", Price: "1.6"},
+			"UnknownPrice": &MarketOrderRequestDefinition{Type: "MARKET", Instrument: "EUR_USD", Units: "10"},
+		}
+		for name, order := range patterns {
+			_, err := account.Orders().Post(ctx, &PostOrdersParams{Body: PostOrdersBodyParams{Order: order}})
+			if _, ok := err.(*OrderPolicyError); !ok {
+				t.Errorf("%s: got unexpected error.\n%+v", name, err)
+			}
+		}
+
+		_, err := account.Orders().OrderSpecifier("42").Put(ctx, &PutOrderSpecifierParams{
+			Body: PutOrderSpecifierBodyParams{
+				Order: &StopOrderRequestDefinition{Type: "STOP", Instrument: "EUR_USD", Units: "5000", Price: "1.1"},
+			},
+		})
+		if _, ok := err.(*OrderPolicyError); !ok {
+			t.Errorf("Replace: got unexpected error.\n%+v", err)
+		}
+	})
+
+	t.Run("PolicyPractice", func(t *testing.T) {
+		practice := *connection
+		practice.Environemnt = OandaPractice
+		_, err := practice.Accounts().AccountID("101").Orders().Post(ctx, &PostOrdersParams{
+			Body: PostOrdersBodyParams{
+				Order: &MarketOrderRequestDefinition{Type: "MARKET", Instrument: "EUR_USD", Units: "1000000"},
+			},
+		})
+		if err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+	})
+
+	t.Run("Mutations", func(t *testing.T) {
+		if data, err := account.Orders().OrderSpecifier("42").Cancel().Put(ctx); err != nil || data.OrderCancelTransaction.OrderID != "42" {
+			t.Errorf("Cancel: error occurred.\n%+v", err)
+		}
+		if data, err := account.Trades().TradeSpecifier("7").Close().Put(ctx, &PutTradeSpecifierCloseParams{}); err != nil || data.OrderCreateTransaction.TradeClose.Units != "ALL" {
+			t.Errorf("Close trade: error occurred.\n%+v", err)
+		}
+		data, err := account.Positions().Instrument("EUR_USD").Close().Put(ctx, &PutPositionsInstrumentCloseParams{
+			Body: &PutPositionsInstrumentCloseBodyParams{LongUnits: "ALL", ShortUnits: "NONE"},
+		})
+		if err != nil || data.LongOrderCreateTransaction == nil || data.ShortOrderCreateTransaction != nil {
+			t.Errorf("Close position: error occurred.\n%+v", err)
+		}
+		if data, err := account.Configuration().Patch(ctx, &PatchAccountConfigurationParams{
+			Body: &PatchAccountConfigurationBodyParams{Alias: "dry"},
+		}); err != nil || data.ClientConfigureTransaction.Alias != "dry" {
+			t.Errorf("Patch configuration: error occurred.\n%+v", err)
+		}
+	})
+}
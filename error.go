@@ -1,8 +1,41 @@
 package oanda
 
+import "net/http"
+
+// Headers of error responses
+
+type ErrorHeaders struct {
+	RequestID string
+}
+
+// ResponseError is implemented by the error types decoded from the error
+// responses of the API, e.g. to find the request ID with errors.As.
+type ResponseError interface {
+	error
+	ResponseHeaders() *ErrorHeaders
+}
+
+type errorHeaders struct {
+	Headers *ErrorHeaders `json:"-"`
+}
+
+func (e *errorHeaders) ResponseHeaders() *ErrorHeaders {
+	return e.Headers
+}
+
+func (e *errorHeaders) setHeaders(resp *http.Response) error {
+	e.Headers = new(ErrorHeaders)
+	if h, err := copyHeader(resp, "Requestid"); err == nil {
+		e.Headers.RequestID = h[0]
+	}
+	return nil
+}
+
 // 400 Bad Request
 
 type BadRequestError struct {
+	errorHeaders
+
 	ErrorMessage string `json:"errorMessage"`
 }
 
@@ -13,6 +46,8 @@ func (r *BadRequestError) Error() string {
 // 401 Unauthorized
 
 type UnauthorizedError struct {
+	errorHeaders
+
 	ErrorMessage string `json:"errorMessage"`
 }
 
@@ -23,6 +58,8 @@ func (r *UnauthorizedError) Error() string {
 // 403 Forbidden
 
 type ForbiddenError struct {
+	errorHeaders
+
 	ErrorMessage string `json:"errorMessage"`
 }
 
@@ -33,6 +70,8 @@ func (r *ForbiddenError) Error() string {
 // 404 Not Found
 
 type NotFoundError struct {
+	errorHeaders
+
 	ErrorMessage      string `json:"errorMessage"`
 	ErrorCode         Reason `json:"errorCode"`
 	LastTransactionID string `json:"lastTransactionID"`
@@ -51,3 +90,83 @@ type StreamHeartbeatBroken struct {
 func (r *StreamHeartbeatBroken) Error() string {
 	return r.ErrorMessage
 }
+
+// Subscriber could not keep up with the price hub
+
+type SlowConsumerError struct {
+	ErrorMessage string
+}
+
+func (r *SlowConsumerError) Error() string {
+	return r.ErrorMessage
+}
+
+// Transaction handler panicked
+
+type TransactionHandlerPanic struct {
+	ErrorMessage string
+	Value        interface{}
+}
+
+func (r *TransactionHandlerPanic) Error() string {
+	return r.ErrorMessage
+}
+
+// Multi account query failed for every account
+
+type MultiAccountError struct {
+	ErrorMessage string
+	Errors       map[AccountIDDefinition]error
+}
+
+func (r *MultiAccountError) Error() string {
+	return r.ErrorMessage
+}
+
+// Config profile not found
+
+type ProfileNotFoundError struct {
+	ErrorMessage string
+	Name         string
+}
+
+func (r *ProfileNotFoundError) Error() string {
+	return r.ErrorMessage
+}
+
+// Live profile used without opting in
+
+type LiveProfileError struct {
+	ErrorMessage string
+	Name         string
+}
+
+func (r *LiveProfileError) Error() string {
+	return r.ErrorMessage
+}
+
+// Order vetoed by the order policy
+
+type OrderPolicyError struct {
+	ErrorMessage string
+	Err          error
+}
+
+func (r *OrderPolicyError) Error() string {
+	return r.ErrorMessage
+}
+
+func (r *OrderPolicyError) Unwrap() error {
+	return r.Err
+}
+
+// Order breaks a pre-trade risk rule
+
+type RiskViolation struct {
+	ErrorMessage string
+	Rule         RiskRule
+}
+
+func (r *RiskViolation) Error() string {
+	return r.ErrorMessage
+}
@@ -0,0 +1,144 @@
+package oanda
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+/* Extra fields */
+
+// Definitions keeping unknown fields in Extra decode and encode through an
+// alias type without these methods.
+
+func (d *AccountDefinition) UnmarshalJSON(data []byte) error {
+	type plain AccountDefinition
+	return unmarshalExtra(data, (*plain)(d), &d.Extra)
+}
+
+func (d AccountDefinition) MarshalJSON() ([]byte, error) {
+	type plain AccountDefinition
+	return marshalExtra((*plain)(&d), d.Extra)
+}
+
+func (d *TransactionDefinition) UnmarshalJSON(data []byte) error {
+	type plain TransactionDefinition
+	return unmarshalExtra(data, (*plain)(d), &d.Extra)
+}
+
+func (d TransactionDefinition) MarshalJSON() ([]byte, error) {
+	type plain TransactionDefinition
+	return marshalExtra((*plain)(&d), d.Extra)
+}
+
+func (d *OrderDefinition) UnmarshalJSON(data []byte) error {
+	type plain OrderDefinition
+	return unmarshalExtra(data, (*plain)(d), &d.Extra)
+}
+
+func (d OrderDefinition) MarshalJSON() ([]byte, error) {
+	type plain OrderDefinition
+	return marshalExtra((*plain)(&d), d.Extra)
+}
+
+func (d *InstrumentDefinition) UnmarshalJSON(data []byte) error {
+	type plain InstrumentDefinition
+	return unmarshalExtra(data, (*plain)(d), &d.Extra)
+}
+
+func (d InstrumentDefinition) MarshalJSON() ([]byte, error) {
+	type plain InstrumentDefinition
+	return marshalExtra((*plain)(&d), d.Extra)
+}
+
+/* Utils */
+
+// unmarshalExtra decodes data into v, a pointer to a struct, and the fields
+// v has no field for into extra.
+func unmarshalExtra(data []byte, v interface{}, extra *map[string]json.RawMessage) error {
+	if err := json.Unmarshal(data, v); err != nil {
+		return err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+
+	// Like encoding/json, keys match fields regardless of case.
+	known := knownFields(reflect.TypeOf(v).Elem())
+	for k := range fields {
+		if known[strings.ToLower(k)] {
+			delete(fields, k)
+		}
+	}
+
+	*extra = nil
+	if len(fields) > 0 {
+		*extra = fields
+	}
+	return nil
+}
+
+// marshalExtra encodes v and appends the fields in extra.
+func marshalExtra(v interface{}, extra map[string]json.RawMessage) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil || len(extra) == 0 {
+		return data, err
+	}
+
+	keys := make([]string, 0, len(extra))
+	for k := range extra {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	buf := bytes.NewBuffer(data[:len(data)-1])
+	for _, k := range keys {
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(k)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(extra[k])
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+var knownFieldsCache sync.Map
+
+// knownFields returns the lowercased JSON names of the fields of a struct.
+func knownFields(t reflect.Type) map[string]bool {
+	if known, ok := knownFieldsCache.Load(t); ok {
+		return known.(map[string]bool)
+	}
+
+	known := make(map[string]bool, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		name := f.Name
+		if tag, ok := f.Tag.Lookup("json"); ok {
+			if tag == "-" {
+				continue
+			}
+			if n := strings.Split(tag, ",")[0]; n != "" {
+				name = n
+			}
+		}
+		known[strings.ToLower(name)] = true
+	}
+
+	knownFieldsCache.Store(t, known)
+	return known
+}
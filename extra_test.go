@@ -0,0 +1,68 @@
+package oanda
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func Test_Extra(t *testing.T) {
+	body := []byte(`{"lastTransactionID":"7","account":{"id":"101","currency":"USD","newField":{"a":[1,2]},"newFlag":true}}`)
+
+	t.Run("Unmarshal", func(t *testing.T) {
+		data := new(GetAccountIDSchema)
+		if err := json.Unmarshal(body, data); err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+
+		extra := data.Account.Extra
+		if len(extra) != 2 || string(extra["newField"]) != `{"a":[1,2]}` || string(extra["newFlag"]) != "true" {
+			t.Fatalf("Got unexpected extra fields.\n%s", extra)
+		}
+		if data.Account.ID != "101" || data.Account.Currency != "USD" {
+			t.Fatalf("Got unexpected account.\n%+v", data.Account)
+		}
+	})
+
+	t.Run("RoundTrip", func(t *testing.T) {
+		data := new(GetAccountIDSchema)
+		if err := json.Unmarshal(body, data); err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+		if err := compareJson(data, body); err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+	})
+
+	t.Run("NoExtra", func(t *testing.T) {
+		tx := new(TransactionDefinition)
+		if err := json.Unmarshal([]byte(`{"id":"1","type":"ORDER_FILL","ID":"1"}`), tx); err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+		if tx.Extra != nil {
+			t.Fatalf("Got unexpected extra fields.\n%s", tx.Extra)
+		}
+	})
+
+	t.Run("Value", func(t *testing.T) {
+		order := OrderDefinition{ID: "3", Extra: map[string]json.RawMessage{"b": json.RawMessage(`2`), "a": json.RawMessage(`"x"`)}}
+		data, err := json.Marshal([]OrderDefinition{order})
+		if err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+		var orders []map[string]interface{}
+		if err := json.Unmarshal(data, &orders); err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+		if orders[0]["id"] != "3" || orders[0]["a"] != "x" || orders[0]["b"] != 2.0 {
+			t.Fatalf("Got unexpected JSON.\n%s", data)
+		}
+
+		data, err = json.Marshal(&InstrumentDefinition{Extra: map[string]json.RawMessage{"a": json.RawMessage(`1`)}})
+		if err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+		if fields := map[string]interface{}{}; json.Unmarshal(data, &fields) != nil || fields["a"] != 1.0 {
+			t.Fatalf("Got unexpected JSON.\n%s", data)
+		}
+	})
+}
@@ -0,0 +1,648 @@
+// Package indicators computes technical indicators from the candles of an
+// instrument.
+//
+// Indicators are updated bar by bar, e.g. with the candles of a historical
+// fetch followed by every live candle once it is complete. Values are NaN
+// until an indicator has seen enough bars. The functions named after an
+// indicator with the suffix Of compute its values for a series of candles,
+// skipping candles that are not complete, so there is a value for every
+// complete candle.
+//
+// Constructors take periods below 1 as 1, the functions with the suffix Of
+// return an error for them.
+package indicators
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	oanda "github.com/denkhaus/oanda-client"
+	"github.com/pkg/errors"
+)
+
+/* Bars */
+
+// Component selects the prices of a candle.
+type Component int
+
+const (
+	Mid Component = iota
+	Bid
+	Ask
+)
+
+// Bar is a candle with parsed prices.
+type Bar struct {
+	Time   time.Time
+	Open   float64
+	High   float64
+	Low    float64
+	Close  float64
+	Volume int
+}
+
+// NewBar parses the component of candle. The component has to be requested
+// when fetching candles, e.g. with PriceBid for Bid.
+func NewBar(candle *oanda.CandlestickDefinition, component Component) (*Bar, error) {
+	var data *oanda.CandlestickDataDefinition
+	switch component {
+	case Mid:
+		data = candle.Mid
+	case Bid:
+		data = candle.Bid
+	case Ask:
+		data = candle.Ask
+	default:
+		return nil, errors.Errorf("Unknown component %d", component)
+	}
+	if data == nil {
+		return nil, errors.Errorf("Candle at %s has no %s prices", candle.Time, component)
+	}
+
+	bar := new(Bar)
+	var err error
+	if bar.Time, err = time.Parse(time.RFC3339Nano, candle.Time); err != nil {
+		return nil, errors.Errorf("Parse candle time failed: %v", err)
+	}
+	for _, v := range []struct {
+		dst *float64
+		src oanda.PriceValueDefinition
+	}{
+		{&bar.Open, data.O},
+		{&bar.High, data.H},
+		{&bar.Low, data.L},
+		{&bar.Close, data.C},
+	} {
+		if *v.dst, err = strconv.ParseFloat(v.src, 64); err != nil {
+			return nil, errors.Errorf("Parse candle at %s failed: %v", candle.Time, err)
+		}
+	}
+	if candle.Volume != nil {
+		bar.Volume = *candle.Volume
+	}
+	return bar, nil
+}
+
+// NewBars parses the component of candles.
+func NewBars(candles []*oanda.CandlestickDefinition, component Component) ([]*Bar, error) {
+	bars := make([]*Bar, len(candles))
+	for i, candle := range candles {
+		bar, err := NewBar(candle, component)
+		if err != nil {
+			return nil, err
+		}
+		bars[i] = bar
+	}
+	return bars, nil
+}
+
+func (c Component) String() string {
+	switch c {
+	case Mid:
+		return "mid"
+	case Bid:
+		return "bid"
+	case Ask:
+		return "ask"
+	}
+	return strconv.Itoa(int(c))
+}
+
+/* SMA */
+
+// SMA is the simple moving average of the closes of period bars.
+type SMA struct {
+	period int
+	window []float64
+	next   int
+	sum    float64
+	value  float64
+}
+
+func NewSMA(period int) *SMA {
+	return &SMA{period: clampPeriod(period), value: math.NaN()}
+}
+
+func (i *SMA) Update(bar *Bar) float64 {
+	i.value = i.add(bar.Close)
+	return i.value
+}
+
+func (i *SMA) Value() float64 {
+	return i.value
+}
+
+func (i *SMA) add(v float64) float64 {
+	if len(i.window) < i.period {
+		i.window = append(i.window, v)
+	} else {
+		i.sum -= i.window[i.next]
+		i.window[i.next] = v
+		i.next = (i.next + 1) % i.period
+	}
+	i.sum += v
+	if len(i.window) < i.period {
+		return math.NaN()
+	}
+	return i.sum / float64(i.period)
+}
+
+func SMAOf(candles []*oanda.CandlestickDefinition, component Component, period int) ([]float64, error) {
+	if err := checkPeriods(period); err != nil {
+		return nil, err
+	}
+	bars, err := completeBars(candles, component)
+	if err != nil {
+		return nil, err
+	}
+	i := NewSMA(period)
+	values := make([]float64, len(bars))
+	for n, bar := range bars {
+		values[n] = i.Update(bar)
+	}
+	return values, nil
+}
+
+/* EMA */
+
+// EMA is the exponential moving average of the closes with a smoothing of
+// 2/(period+1), starting with the simple moving average of the first period
+// bars.
+type EMA struct {
+	period int
+	seed   *SMA
+	value  float64
+}
+
+func NewEMA(period int) *EMA {
+	period = clampPeriod(period)
+	return &EMA{period: period, seed: NewSMA(period), value: math.NaN()}
+}
+
+func (i *EMA) Update(bar *Bar) float64 {
+	i.value = i.add(bar.Close)
+	return i.value
+}
+
+func (i *EMA) Value() float64 {
+	return i.value
+}
+
+func (i *EMA) add(v float64) float64 {
+	if i.seed != nil {
+		value := i.seed.add(v)
+		if !math.IsNaN(value) {
+			i.seed = nil
+		}
+		return value
+	}
+	return i.value + 2/float64(i.period+1)*(v-i.value)
+}
+
+func EMAOf(candles []*oanda.CandlestickDefinition, component Component, period int) ([]float64, error) {
+	if err := checkPeriods(period); err != nil {
+		return nil, err
+	}
+	bars, err := completeBars(candles, component)
+	if err != nil {
+		return nil, err
+	}
+	i := NewEMA(period)
+	values := make([]float64, len(bars))
+	for n, bar := range bars {
+		values[n] = i.Update(bar)
+	}
+	return values, nil
+}
+
+/* RSI */
+
+// RSI is Wilder's relative strength index of the closes, between 0 and 100.
+type RSI struct {
+	period int
+	prev   float64
+	gain   *wilder
+	loss   *wilder
+	value  float64
+}
+
+func NewRSI(period int) *RSI {
+	return &RSI{period: period, prev: math.NaN(), gain: newWilder(period), loss: newWilder(period), value: math.NaN()}
+}
+
+func (i *RSI) Update(bar *Bar) float64 {
+	prev := i.prev
+	i.prev = bar.Close
+	if math.IsNaN(prev) {
+		return i.value
+	}
+	change := bar.Close - prev
+	gain := i.gain.add(math.Max(change, 0))
+	loss := i.loss.add(math.Max(-change, 0))
+	switch {
+	case math.IsNaN(gain):
+	case loss == 0 && gain == 0:
+		i.value = 50
+	case loss == 0:
+		i.value = 100
+	default:
+		i.value = 100 - 100/(1+gain/loss)
+	}
+	return i.value
+}
+
+func (i *RSI) Value() float64 {
+	return i.value
+}
+
+func RSIOf(candles []*oanda.CandlestickDefinition, component Component, period int) ([]float64, error) {
+	if err := checkPeriods(period); err != nil {
+		return nil, err
+	}
+	bars, err := completeBars(candles, component)
+	if err != nil {
+		return nil, err
+	}
+	i := NewRSI(period)
+	values := make([]float64, len(bars))
+	for n, bar := range bars {
+		values[n] = i.Update(bar)
+	}
+	return values, nil
+}
+
+/* MACD */
+
+type MACDValue struct {
+	MACD      float64
+	Signal    float64
+	Histogram float64
+}
+
+// MACD is the difference of a fast and a slow EMA of the closes, and its EMA
+// as the signal line, commonly with periods of 12, 26 and 9.
+type MACD struct {
+	fast   *EMA
+	slow   *EMA
+	signal *EMA
+	value  MACDValue
+}
+
+func NewMACD(fast, slow, signal int) *MACD {
+	return &MACD{
+		fast:   NewEMA(fast),
+		slow:   NewEMA(slow),
+		signal: NewEMA(signal),
+		value:  MACDValue{MACD: math.NaN(), Signal: math.NaN(), Histogram: math.NaN()},
+	}
+}
+
+func (i *MACD) Update(bar *Bar) MACDValue {
+	fast := i.fast.Update(bar)
+	slow := i.slow.Update(bar)
+	if math.IsNaN(fast) || math.IsNaN(slow) {
+		return i.value
+	}
+	i.value.MACD = fast - slow
+	i.value.Signal = i.signal.Update(&Bar{Close: i.value.MACD})
+	i.value.Histogram = i.value.MACD - i.value.Signal
+	return i.value
+}
+
+func (i *MACD) Value() MACDValue {
+	return i.value
+}
+
+func MACDOf(candles []*oanda.CandlestickDefinition, component Component, fast, slow, signal int) ([]MACDValue, error) {
+	if err := checkPeriods(fast, slow, signal); err != nil {
+		return nil, err
+	}
+	bars, err := completeBars(candles, component)
+	if err != nil {
+		return nil, err
+	}
+	i := NewMACD(fast, slow, signal)
+	values := make([]MACDValue, len(bars))
+	for n, bar := range bars {
+		values[n] = i.Update(bar)
+	}
+	return values, nil
+}
+
+/* ATR */
+
+// ATR is Wilder's average true range.
+type ATR struct {
+	prev  *Bar
+	tr    *wilder
+	value float64
+}
+
+func NewATR(period int) *ATR {
+	return &ATR{tr: newWilder(period), value: math.NaN()}
+}
+
+func (i *ATR) Update(bar *Bar) float64 {
+	i.value = i.tr.add(trueRange(bar, i.prev))
+	i.prev = bar
+	return i.value
+}
+
+func (i *ATR) Value() float64 {
+	return i.value
+}
+
+func ATROf(candles []*oanda.CandlestickDefinition, component Component, period int) ([]float64, error) {
+	if err := checkPeriods(period); err != nil {
+		return nil, err
+	}
+	bars, err := completeBars(candles, component)
+	if err != nil {
+		return nil, err
+	}
+	i := NewATR(period)
+	values := make([]float64, len(bars))
+	for n, bar := range bars {
+		values[n] = i.Update(bar)
+	}
+	return values, nil
+}
+
+/* Bollinger Bands */
+
+type BollingerValue struct {
+	Middle float64
+	Upper  float64
+	Lower  float64
+}
+
+// Bollinger is the SMA of the closes with bands at a multiple of their
+// standard deviation, commonly with a period of 20 and a multiple of 2.
+type Bollinger struct {
+	sma   *SMA
+	k     float64
+	value BollingerValue
+}
+
+func NewBollinger(period int, k float64) *Bollinger {
+	return &Bollinger{
+		sma:   NewSMA(period),
+		k:     k,
+		value: BollingerValue{Middle: math.NaN(), Upper: math.NaN(), Lower: math.NaN()},
+	}
+}
+
+func (i *Bollinger) Update(bar *Bar) BollingerValue {
+	middle := i.sma.Update(bar)
+	if math.IsNaN(middle) {
+		return i.value
+	}
+	var variance float64
+	for _, v := range i.sma.window {
+		variance += (v - middle) * (v - middle)
+	}
+	width := i.k * math.Sqrt(variance/float64(len(i.sma.window)))
+	i.value = BollingerValue{Middle: middle, Upper: middle + width, Lower: middle - width}
+	return i.value
+}
+
+func (i *Bollinger) Value() BollingerValue {
+	return i.value
+}
+
+func BollingerOf(candles []*oanda.CandlestickDefinition, component Component, period int, k float64) ([]BollingerValue, error) {
+	if err := checkPeriods(period); err != nil {
+		return nil, err
+	}
+	bars, err := completeBars(candles, component)
+	if err != nil {
+		return nil, err
+	}
+	i := NewBollinger(period, k)
+	values := make([]BollingerValue, len(bars))
+	for n, bar := range bars {
+		values[n] = i.Update(bar)
+	}
+	return values, nil
+}
+
+/* ADX */
+
+type ADXValue struct {
+	ADX     float64
+	PlusDI  float64
+	MinusDI float64
+}
+
+// ADX is Wilder's average directional index with the directional
+// indicators, between 0 and 100.
+type ADX struct {
+	prev    *Bar
+	tr      *wilderSum
+	plusDM  *wilderSum
+	minusDM *wilderSum
+	dx      *wilder
+	value   ADXValue
+}
+
+func NewADX(period int) *ADX {
+	return &ADX{
+		tr:      newWilderSum(period),
+		plusDM:  newWilderSum(period),
+		minusDM: newWilderSum(period),
+		dx:      newWilder(period),
+		value:   ADXValue{ADX: math.NaN(), PlusDI: math.NaN(), MinusDI: math.NaN()},
+	}
+}
+
+func (i *ADX) Update(bar *Bar) ADXValue {
+	prev := i.prev
+	i.prev = bar
+	if prev == nil {
+		return i.value
+	}
+
+	up, down := bar.High-prev.High, prev.Low-bar.Low
+	var plusDM, minusDM float64
+	if up > down && up > 0 {
+		plusDM = up
+	}
+	if down > up && down > 0 {
+		minusDM = down
+	}
+	tr := i.tr.add(trueRange(bar, prev))
+	plus := i.plusDM.add(plusDM)
+	minus := i.minusDM.add(minusDM)
+	if math.IsNaN(tr) {
+		return i.value
+	}
+
+	if tr == 0 {
+		i.value.PlusDI, i.value.MinusDI = 0, 0
+	} else {
+		i.value.PlusDI, i.value.MinusDI = 100*plus/tr, 100*minus/tr
+	}
+	var dx float64
+	if sum := i.value.PlusDI + i.value.MinusDI; sum != 0 {
+		dx = 100 * math.Abs(i.value.PlusDI-i.value.MinusDI) / sum
+	}
+	i.value.ADX = i.dx.add(dx)
+	return i.value
+}
+
+func (i *ADX) Value() ADXValue {
+	return i.value
+}
+
+func ADXOf(candles []*oanda.CandlestickDefinition, component Component, period int) ([]ADXValue, error) {
+	if err := checkPeriods(period); err != nil {
+		return nil, err
+	}
+	bars, err := completeBars(candles, component)
+	if err != nil {
+		return nil, err
+	}
+	i := NewADX(period)
+	values := make([]ADXValue, len(bars))
+	for n, bar := range bars {
+		values[n] = i.Update(bar)
+	}
+	return values, nil
+}
+
+/* Pivot Points */
+
+// PivotPoints are the classic floor pivot points of a bar, commonly of the
+// previous day as support and resistance levels of the current day.
+type PivotPoints struct {
+	Pivot float64
+	R1    float64
+	R2    float64
+	R3    float64
+	S1    float64
+	S2    float64
+	S3    float64
+}
+
+func NewPivotPoints(bar *Bar) *PivotPoints {
+	p := (bar.High + bar.Low + bar.Close) / 3
+	return &PivotPoints{
+		Pivot: p,
+		R1:    2*p - bar.Low,
+		R2:    p + bar.High - bar.Low,
+		R3:    bar.High + 2*(p-bar.Low),
+		S1:    2*p - bar.High,
+		S2:    p - bar.High + bar.Low,
+		S3:    bar.Low - 2*(bar.High-p),
+	}
+}
+
+// PivotPointsOf returns the pivot points of every complete candle, which
+// apply to the candle after it.
+func PivotPointsOf(candles []*oanda.CandlestickDefinition, component Component) ([]*PivotPoints, error) {
+	bars, err := completeBars(candles, component)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]*PivotPoints, len(bars))
+	for n, bar := range bars {
+		values[n] = NewPivotPoints(bar)
+	}
+	return values, nil
+}
+
+/* Utils */
+
+// completeBars parses the component of the complete candles.
+func completeBars(candles []*oanda.CandlestickDefinition, component Component) ([]*Bar, error) {
+	bars := make([]*Bar, 0, len(candles))
+	for _, candle := range candles {
+		if candle.Complete != nil && !*candle.Complete {
+			continue
+		}
+		bar, err := NewBar(candle, component)
+		if err != nil {
+			return nil, err
+		}
+		bars = append(bars, bar)
+	}
+	return bars, nil
+}
+
+func checkPeriods(periods ...int) error {
+	for _, period := range periods {
+		if period < 1 {
+			return errors.Errorf("Invalid period %d", period)
+		}
+	}
+	return nil
+}
+
+func clampPeriod(period int) int {
+	if period < 1 {
+		return 1
+	}
+	return period
+}
+
+// wilder is Wilder's moving average, starting with the simple average of the
+// first period values.
+type wilder struct {
+	period int
+	n      int
+	value  float64
+}
+
+func newWilder(period int) *wilder {
+	return &wilder{period: clampPeriod(period)}
+}
+
+func (w *wilder) add(v float64) float64 {
+	if w.n < w.period {
+		w.n++
+		w.value += v
+		if w.n < w.period {
+			return math.NaN()
+		}
+		w.value /= float64(w.period)
+		return w.value
+	}
+	w.value = (w.value*float64(w.period-1) + v) / float64(w.period)
+	return w.value
+}
+
+// wilderSum is Wilder's running sum of the ADX, starting with the sum of the
+// first period values.
+type wilderSum struct {
+	period int
+	n      int
+	value  float64
+}
+
+func newWilderSum(period int) *wilderSum {
+	return &wilderSum{period: clampPeriod(period)}
+}
+
+func (w *wilderSum) add(v float64) float64 {
+	if w.n < w.period {
+		w.n++
+		w.value += v
+		if w.n < w.period {
+			return math.NaN()
+		}
+		return w.value
+	}
+	w.value = w.value - w.value/float64(w.period) + v
+	return w.value
+}
+
+// trueRange returns the true range of bar after prev, its range when prev is
+// nil.
+func trueRange(bar, prev *Bar) float64 {
+	tr := bar.High - bar.Low
+	if prev != nil {
+		tr = math.Max(tr, math.Max(math.Abs(bar.High-prev.Close), math.Abs(bar.Low-prev.Close)))
+	}
+	return tr
+}
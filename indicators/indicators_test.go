@@ -0,0 +1,219 @@
+package indicators
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	oanda "github.com/denkhaus/oanda-client"
+)
+
+var (
+	testHighs  = []string{"1.1010", "1.1030", "1.1025", "1.1050", "1.1045", "1.1060", "1.1040", "1.1035", "1.1070", "1.1080"}
+	testLows   = []string{"1.0990", "1.1000", "1.1005", "1.1015", "1.1020", "1.1030", "1.1010", "1.1005", "1.1030", "1.1050"}
+	testCloses = []string{"1.1000", "1.1025", "1.1010", "1.1045", "1.1030", "1.1040", "1.1015", "1.1030", "1.1065", "1.1060"}
+)
+
+// nan stands for values that aren't ready.
+var nan = math.NaN()
+
+func newTestCandles() []*oanda.CandlestickDefinition {
+	start := time.Date(2022, 1, 4, 0, 0, 0, 0, time.UTC)
+	candles := make([]*oanda.CandlestickDefinition, len(testCloses))
+	for i := range candles {
+		candles[i] = &oanda.CandlestickDefinition{
+			Time:   start.Add(time.Duration(i) * time.Hour).Format(time.RFC3339),
+			Mid:    &oanda.CandlestickDataDefinition{O: testCloses[i], H: testHighs[i], L: testLows[i], C: testCloses[i]},
+			Volume: oanda.Int(i),
+		}
+	}
+	return candles
+}
+
+func assertValues(t *testing.T, name string, expect, actual []float64) {
+	t.Helper()
+	if len(expect) != len(actual) {
+		t.Fatalf("Got unexpected number of %s values %d.", name, len(actual))
+	}
+	for i := range expect {
+		if math.IsNaN(expect[i]) != math.IsNaN(actual[i]) || math.Abs(expect[i]-actual[i]) > 1e-9 {
+			t.Fatalf("Got unexpected %s value %d.\nExpect: %v\nActual: %v", name, i, expect[i], actual[i])
+		}
+	}
+}
+
+func Test_Indicators(t *testing.T) {
+	candles := newTestCandles()
+
+	t.Run("Bars", func(t *testing.T) {
+		bar, err := NewBar(candles[1], Mid)
+		if err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+		expect := &Bar{Time: time.Date(2022, 1, 4, 1, 0, 0, 0, time.UTC), Open: 1.1025, High: 1.1030, Low: 1.1000, Close: 1.1025, Volume: 1}
+		if *bar != *expect {
+			t.Fatalf("Got unexpected bar.\nExpect: %+v\nActual: %+v", expect, bar)
+		}
+		if _, err := NewBar(candles[1], Bid); err == nil {
+			t.Fatalf("Error did not occur.")
+		}
+		if _, err := SMAOf(candles, Ask, 3); err == nil {
+			t.Fatalf("Error did not occur.")
+		}
+	})
+
+	t.Run("SMA", func(t *testing.T) {
+		values, err := SMAOf(candles, Mid, 3)
+		if err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+		assertValues(t, "SMA", []float64{nan, nan, 1.1011666666666666, 1.1026666666666667, 1.1028333333333333, 1.1038333333333333, 1.1028333333333333, 1.1028333333333333, 1.1036666666666667, 1.1051666666666666}, values)
+	})
+
+	t.Run("EMA", func(t *testing.T) {
+		values, err := EMAOf(candles, Mid, 3)
+		if err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+		assertValues(t, "EMA", []float64{nan, nan, 1.1011666666666666, 1.1028333333333333, 1.1029166666666668, 1.1034583333333334, 1.1024791666666667, 1.1027395833333333, 1.1046197916666667, 1.1053098958333334}, values)
+	})
+
+	t.Run("RSI", func(t *testing.T) {
+		values, err := RSIOf(candles, Mid, 3)
+		if err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+		assertValues(t, "RSI", []float64{nan, nan, nan, 79.9999999999994, 61.53846153846066, 68.74999999999986, 40.36697247706357, 56.52173913043419, 77.68240343347578, 70.34589972794441}, values)
+	})
+
+	t.Run("MACD", func(t *testing.T) {
+		values, err := MACDOf(candles, Mid, 2, 3, 2)
+		if err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+		macd := make([]float64, len(values))
+		signal := make([]float64, len(values))
+		histogram := make([]float64, len(values))
+		for i, v := range values {
+			macd[i], signal[i], histogram[i] = v.MACD, v.Signal, v.Histogram
+		}
+		expectSignal := []float64{nan, nan, nan, 0.00022222222222234578, 0.00020987654320991922, 0.0002355967078189932, -8.384773662544693e-05, -2.420553269320168e-05, 0.00041100346745926564, 0.0004300482967535291}
+		expectMACD := []float64{nan, nan, -8.333333333321313e-05, 0.0005277777777779047, 0.00020370370370370594, 0.00024845679012353017, -0.00024356995884766697, 5.6155692729209505e-06, 0.0006286079675354994, 0.00043957071140066084}
+		expectHistogram := make([]float64, len(values))
+		for i := range expectHistogram {
+			expectHistogram[i] = expectMACD[i] - expectSignal[i]
+		}
+		assertValues(t, "MACD", expectMACD, macd)
+		assertValues(t, "signal", expectSignal, signal)
+		assertValues(t, "histogram", expectHistogram, histogram)
+	})
+
+	t.Run("ATR", func(t *testing.T) {
+		values, err := ATROf(candles, Mid, 3)
+		if err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+		assertValues(t, "ATR", []float64{nan, nan, 0.0023333333333332984, 0.0028888888888888666, 0.0027592592592592265, 0.0028395061728395225, 0.0028930041152263864, 0.0029286694101508886, 0.0032857796067672605, 0.0031905197378448782}, values)
+	})
+
+	t.Run("Bollinger", func(t *testing.T) {
+		values, err := BollingerOf(candles, Mid, 3, 2)
+		if err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+		if v := values[1]; !math.IsNaN(v.Middle) || !math.IsNaN(v.Upper) || !math.IsNaN(v.Lower) {
+			t.Fatalf("Got unexpected value.\n%+v", v)
+		}
+		last := values[len(values)-1]
+		assertValues(t, "Bollinger", []float64{1.1051666666666666, 1.108257872831832, 1.1020754605015013}, []float64{last.Middle, last.Upper, last.Lower})
+	})
+
+	t.Run("ADX", func(t *testing.T) {
+		values, err := ADXOf(candles, Mid, 3)
+		if err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+		adx := make([]float64, len(values))
+		plus := make([]float64, len(values))
+		minus := make([]float64, len(values))
+		for i, v := range values {
+			adx[i], plus[i], minus[i] = v.ADX, v.PlusDI, v.MinusDI
+		}
+		assertValues(t, "ADX", []float64{nan, nan, nan, nan, nan, 100, 69.23076923076938, 48.88608659100408, 51.77614775949089, 56.626275377076205}, adx)
+		assertValues(t, "+DI", []float64{nan, nan, nan, 50, 35.294117647058926, 40.38461538461562, 26.5822784810125, 17.57322175732238, 45.822942643392246, 41.920274324904724}, plus)
+		assertValues(t, "-DI", []float64{nan, nan, nan, 0, 0, 0, 22.784810126581945, 20.711297071129138, 12.344139650872465, 8.486926703814484}, minus)
+	})
+
+	t.Run("PivotPoints", func(t *testing.T) {
+		values, err := PivotPointsOf(candles, Mid)
+		if err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+		p := values[len(values)-1]
+		assertValues(t, "pivot point", []float64{1.1063333333333333, 1.1076666666666666, 1.1093333333333333, 1.1106666666666667, 1.1046666666666667, 1.1033333333333333, 1.1016666666666667}, []float64{p.Pivot, p.R1, p.R2, p.R3, p.S1, p.S2, p.S3})
+	})
+
+	t.Run("Incomplete", func(t *testing.T) {
+		// The candle in progress is skipped.
+		live := *candles[len(candles)-1]
+		live.Complete = oanda.Bool(false)
+		live.Mid = &oanda.CandlestickDataDefinition{O: "1.1060", H: "1.1200", L: "1.1060", C: "1.1200"}
+		expect, err := SMAOf(candles, Mid, 3)
+		if err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+		actual, err := SMAOf(append(candles[:len(candles):len(candles)], &live), Mid, 3)
+		if err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+		assertValues(t, "SMA", expect, actual)
+	})
+
+	t.Run("Periods", func(t *testing.T) {
+		if _, err := SMAOf(candles, Mid, 0); err == nil {
+			t.Fatalf("Error did not occur.")
+		}
+		if _, err := MACDOf(candles, Mid, 12, 26, -1); err == nil {
+			t.Fatalf("Error did not occur.")
+		}
+		// Constructors take the period as 1.
+		bars, err := NewBars(candles[:2], Mid)
+		if err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+		sma, ema, rsi, atr, bollinger, adx := NewSMA(0), NewEMA(0), NewRSI(0), NewATR(-1), NewBollinger(0, 2), NewADX(0)
+		for _, bar := range bars {
+			sma.Update(bar)
+			ema.Update(bar)
+			rsi.Update(bar)
+			atr.Update(bar)
+			bollinger.Update(bar)
+			adx.Update(bar)
+		}
+		assertValues(t, "period 0", []float64{1.1025, 1.1025, 100, 0.0030, 1.1025, 100}, []float64{sma.Value(), ema.Value(), rsi.Value(), atr.Value(), bollinger.Value().Upper, adx.Value().ADX})
+	})
+
+	t.Run("Incremental", func(t *testing.T) {
+		// Updating with live bars continues the values of a fetch.
+		history, err := RSIOf(candles[:6], Mid, 3)
+		if err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+		bars, err := NewBars(candles, Mid)
+		if err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+		rsi := NewRSI(3)
+		for _, bar := range bars[:6] {
+			rsi.Update(bar)
+		}
+		if rsi.Value() != history[5] {
+			t.Fatalf("Got unexpected value %v.", rsi.Value())
+		}
+		actual := make([]float64, 0, 4)
+		for _, bar := range bars[6:] {
+			actual = append(actual, rsi.Update(bar))
+		}
+		assertValues(t, "RSI", []float64{40.36697247706357, 56.52173913043419, 77.68240343347578, 70.34589972794441}, actual)
+	})
+}
@@ -216,7 +216,7 @@ func (r *ReceiverInstrumentCandles) Get(ctx context.Context, params *GetInstrume
 	childCtx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	resp, err := r.Connection.request(
+	data, err := r.Connection.request(
 		childCtx,
 		&requestParams{
 			method:   "GET",
@@ -292,22 +292,13 @@ func (r *ReceiverInstrumentCandles) Get(ctx context.Context, params *GetInstrume
 
 				return q
 			}(),
+			results: map[int]interface{}{
+				200: new(GetInstrumentCandlesSchema),
+			},
 		},
 	)
 	if err != nil {
-		return nil, errors.Errorf("Get instrument candles canceled: %v", err)
-	}
-	defer resp.Body.Close()
-
-	var data interface{}
-	switch resp.StatusCode {
-	case 200:
-		data = new(GetInstrumentCandlesSchema)
-	}
-
-	data, err = parseResponse(resp, data, r.Connection.Strict)
-	if err != nil {
-		return nil, errors.Errorf("Get instrument candles failed: %v", err)
+		return nil, errors.Wrap(err, "Get instrument candles failed")
 	}
 	return data.(*GetInstrumentCandlesSchema), nil
 }
@@ -319,7 +310,7 @@ func (r *ReceiverInstrumentOrderBook) Get(ctx context.Context, params *GetInstru
 	childCtx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	resp, err := r.Connection.request(
+	data, err := r.Connection.request(
 		childCtx,
 		&requestParams{
 			method:   "GET",
@@ -340,22 +331,13 @@ func (r *ReceiverInstrumentOrderBook) Get(ctx context.Context, params *GetInstru
 
 				return q
 			}(),
+			results: map[int]interface{}{
+				200: new(GetInstrumentOrderBookSchema),
+			},
 		},
 	)
 	if err != nil {
-		return nil, errors.Errorf("Get instrument order book canceled: %v", err)
-	}
-	defer resp.Body.Close()
-
-	var data interface{}
-	switch resp.StatusCode {
-	case 200:
-		data = new(GetInstrumentOrderBookSchema)
-	}
-
-	data, err = parseResponse(resp, data, r.Connection.Strict)
-	if err != nil {
-		return nil, errors.Errorf("Get instrument order book failed: %v", err)
+		return nil, errors.Wrap(err, "Get instrument order book failed")
 	}
 	return data.(*GetInstrumentOrderBookSchema), nil
 }
@@ -367,7 +349,7 @@ func (r *ReceiverInstrumentPositionBook) Get(ctx context.Context, params *GetIns
 	childCtx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	resp, err := r.Connection.request(
+	data, err := r.Connection.request(
 		childCtx,
 		&requestParams{
 			method:   "GET",
@@ -388,22 +370,13 @@ func (r *ReceiverInstrumentPositionBook) Get(ctx context.Context, params *GetIns
 
 				return q
 			}(),
+			results: map[int]interface{}{
+				200: new(GetInstrumentPositionBookSchema),
+			},
 		},
 	)
 	if err != nil {
-		return nil, errors.Errorf("Get instrument position book canceled: %v", err)
-	}
-	defer resp.Body.Close()
-
-	var data interface{}
-	switch resp.StatusCode {
-	case 200:
-		data = new(GetInstrumentPositionBookSchema)
-	}
-
-	data, err = parseResponse(resp, data, r.Connection.Strict)
-	if err != nil {
-		return nil, errors.Errorf("Get instrument position book failed: %v", err)
+		return nil, errors.Wrap(err, "Get instrument position book failed")
 	}
 	return data.(*GetInstrumentPositionBookSchema), nil
 }
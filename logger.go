@@ -0,0 +1,142 @@
+package oanda
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+/* Logging */
+
+// LogLevel has the values of the levels of log/slog, so a LogLevel converts
+// to a slog.Level.
+type LogLevel int
+
+const (
+	LogLevelDebug LogLevel = -4
+	LogLevelInfo  LogLevel = 0
+	LogLevelWarn  LogLevel = 4
+	LogLevelError LogLevel = 8
+)
+
+func (l LogLevel) String() string {
+	switch {
+	case l < LogLevelInfo:
+		return "DEBUG"
+	case l < LogLevelWarn:
+		return "INFO"
+	case l < LogLevelError:
+		return "WARN"
+	default:
+		return "ERROR"
+	}
+}
+
+// Logger receives the logs of a Connection as a message and alternating keys
+// and values, like the Log method of a slog.Logger. Secrets are redacted
+// before they reach the logger.
+type Logger interface {
+	Log(ctx context.Context, level LogLevel, msg string, keyvals ...interface{})
+}
+
+// LoggerFunc adapts a function to a Logger, e.g. to forward to a slog.Logger:
+//
+//	oanda.LoggerFunc(func(ctx context.Context, level oanda.LogLevel, msg string, keyvals ...interface{}) {
+//		logger.Log(ctx, slog.Level(level), msg, keyvals...)
+//	})
+type LoggerFunc func(ctx context.Context, level LogLevel, msg string, keyvals ...interface{})
+
+func (f LoggerFunc) Log(ctx context.Context, level LogLevel, msg string, keyvals ...interface{}) {
+	f(ctx, level, msg, keyvals...)
+}
+
+func (c *Connection) log(ctx context.Context, level LogLevel, msg string, keyvals ...interface{}) {
+	if c.Logger == nil || level < c.LogLevel {
+		return
+	}
+	for i, v := range keyvals {
+		keyvals[i] = c.redactValue(v)
+	}
+	c.Logger.Log(ctx, level, c.redact(msg), keyvals...)
+}
+
+func (c *Connection) logRequest(ctx context.Context, req *Request, resp *Response, err error) {
+	keyvals := []interface{}{"method", req.Method, "endpoint", req.EndPoint}
+	if resp != nil {
+		keyvals = append(keyvals,
+			"status", resp.StatusCode,
+			"latency", resp.Latency,
+			"request_id", resp.Header.Get("RequestID"),
+		)
+	}
+
+	if err != nil {
+		c.log(ctx, LogLevelError, "oanda: request failed", append(keyvals, "error", err)...)
+		return
+	}
+	c.log(ctx, LogLevelDebug, "oanda: request", keyvals...)
+}
+
+// logStreamHook logs when streams open and close.
+func (c *Connection) logStreamHook() *StreamHook {
+	return &StreamHook{
+		Open: func(ctx context.Context, req *Request, resp *Response, err error) {
+			keyvals := []interface{}{"endpoint", req.EndPoint}
+			if err != nil {
+				c.log(ctx, LogLevelError, "oanda: stream failed", append(keyvals, "error", err)...)
+				return
+			}
+			keyvals = append(keyvals,
+				"status", resp.StatusCode,
+				"latency", resp.Latency,
+				"request_id", resp.Header.Get("RequestID"),
+			)
+			c.log(ctx, LogLevelDebug, "oanda: stream opened", keyvals...)
+		},
+		Close: func(req *Request, err error) {
+			if err != nil {
+				c.log(context.Background(), LogLevelWarn, "oanda: stream closed", "endpoint", req.EndPoint, "error", err)
+				return
+			}
+			c.log(context.Background(), LogLevelDebug, "oanda: stream closed", "endpoint", req.EndPoint)
+		},
+	}
+}
+
+/* Redaction */
+
+var (
+	redactBearer    = regexp.MustCompile(`(?i)(bearer\s+)\S+`)
+	redactAccountID = regexp.MustCompile(`\b\d{3}-\d{3}-\d+-\d{3}\b`)
+	redactAccounts  = regexp.MustCompile(`(/accounts/)[^/?#\s]+`)
+)
+
+const redacted = "[REDACTED]"
+
+// redact removes the token and account IDs from s.
+func (c *Connection) redact(s string) string {
+	if c.Token != "" {
+		s = strings.ReplaceAll(s, c.Token, redacted)
+	}
+	s = redactBearer.ReplaceAllString(s, "${1}"+redacted)
+	s = redactAccountID.ReplaceAllString(s, redacted)
+	return redactAccounts.ReplaceAllString(s, "${1}"+redacted)
+}
+
+func (c *Connection) redactValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case string:
+		return c.redact(v)
+	case time.Duration, time.Time:
+		return v
+	case error:
+		return c.redact(v.Error())
+	case fmt.Stringer:
+		return c.redact(v.String())
+	case []byte:
+		return c.redact(string(v))
+	}
+	return v
+}
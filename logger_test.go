@@ -0,0 +1,103 @@
+package oanda
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+type testLogEntry struct {
+	level   LogLevel
+	msg     string
+	keyvals []interface{}
+}
+
+func (e *testLogEntry) String() string {
+	return fmt.Sprint(e.level, " ", e.msg, " ", e.keyvals)
+}
+
+func Test_Logger(t *testing.T) {
+	ctx := context.Background()
+
+	newLogged := func(level LogLevel) (*Connection, *[]*testLogEntry) {
+		entries := new([]*testLogEntry)
+		connection := &Connection{
+			Token:       "0123456789abcdef-fedcba9876543210",
+			Environemnt: OandaPractice,
+			DryRun:      true,
+			LogLevel:    level,
+			Logger: LoggerFunc(func(ctx context.Context, level LogLevel, msg string, keyvals ...interface{}) {
+				*entries = append(*entries, &testLogEntry{level: level, msg: msg, keyvals: keyvals})
+			}),
+		}
+		return connection, entries
+	}
+
+	t.Run("Request", func(t *testing.T) {
+		connection, entries := newLogged(LogLevelDebug)
+
+		_, err := connection.Accounts().AccountID("101-001-1234567-001").Orders().OrderSpecifier("42").Cancel().Put(ctx)
+		if err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+
+		if len(*entries) != 2 {
+			t.Fatalf("Got unexpected entries.\n%v", *entries)
+		}
+		dryRun, request := (*entries)[0], (*entries)[1]
+		if dryRun.level != LogLevelInfo || request.level != LogLevelDebug || request.msg != "oanda: request" {
+			t.Fatalf("Got unexpected entries.\n%v", *entries)
+		}
+		for _, e := range *entries {
+			if s := e.String(); strings.Contains(s, "1234567") {
+				t.Fatalf("Account ID was not redacted.\n%s", s)
+			}
+		}
+		if s := request.String(); !strings.Contains(s, "request_id dry-run") || !strings.Contains(s, "/v3/accounts/[REDACTED]/orders/42/cancel") {
+			t.Fatalf("Got unexpected entry.\n%s", s)
+		}
+	})
+
+	t.Run("Level", func(t *testing.T) {
+		connection, entries := newLogged(LogLevelWarn)
+
+		_, err := connection.Accounts().AccountID("101").Orders().OrderSpecifier("42").Cancel().Put(ctx)
+		if err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+		if len(*entries) != 0 {
+			t.Fatalf("Got unexpected entries.\n%v", *entries)
+		}
+
+		connection.Use(func(next Handler) Handler {
+			return func(ctx context.Context, req *Request) (*Response, error) {
+				return nil, errors.New("unavailable")
+			}
+		})
+		_, err = connection.Accounts().AccountID("101").Orders().OrderSpecifier("42").Cancel().Put(ctx)
+		if err == nil {
+			t.Fatalf("Error did not occur.")
+		}
+		if len(*entries) != 1 || (*entries)[0].level != LogLevelError {
+			t.Fatalf("Got unexpected entries.\n%v", *entries)
+		}
+	})
+
+	t.Run("Redact", func(t *testing.T) {
+		connection, entries := newLogged(LogLevelDebug)
+
+		connection.log(ctx, LogLevelError, "oanda: failed for 101-004-7654321-002",
+			"header", "Authorization: Bearer secret-token",
+			"error", errors.New("token "+connection.Token+" rejected"),
+			"url", "https://api-fxpractice.oanda.com/v3/accounts/101?x=1",
+			"count", 3,
+		)
+
+		expect := "ERROR oanda: failed for [REDACTED] [header Authorization: Bearer [REDACTED] error token [REDACTED] rejected url https://api-fxpractice.oanda.com/v3/accounts/[REDACTED]?x=1 count 3]"
+		if actual := (*entries)[0].String(); actual != expect {
+			t.Fatalf("Got unexpected entry.\nExpect: %s\nActual: %s", expect, actual)
+		}
+	})
+}
@@ -0,0 +1,336 @@
+package oanda
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Market data files are gzip compressed newline-delimited JSON, a record per
+// line holding a message of a stream and the time it was received, e.g.
+//
+//	{"received":"2022-01-04T09:00:00.123456789Z","message":{"type":"PRICE",...}}
+
+/* Params */
+
+const (
+	// ReplayMaxSpeed replays messages as fast as they are read.
+	ReplayMaxSpeed float64 = 0
+	// ReplayRealTime replays messages at the pace they were received.
+	ReplayRealTime float64 = 1
+)
+
+type ReplayParams struct {
+	BufferSize int
+	// Speed is the pace of the replay relative to the recording, e.g. 60
+	// replays a minute per second. Defaults to ReplayMaxSpeed.
+	Speed float64
+}
+
+/* Recording */
+
+type marketDataRecord struct {
+	Received time.Time       `json:"received"`
+	Message  json.RawMessage `json:"message"`
+}
+
+// RecordPrices passes the prices of chs on while writing them to w. Close the
+// returned channels to stop the recording, w is complete once they are
+// closed but is not closed itself.
+func RecordPrices(chs *PriceChannels, w io.Writer) *PriceChannels {
+	ctx, cancel := context.WithCancel(context.Background())
+	closeWait := new(sync.WaitGroup)
+	priceCh := make(chan *PriceDefinition, cap(chs.PriceCh))
+	errorCh := make(chan error, 1)
+
+	closeWait.Add(1)
+	go func() {
+		writer := newMarketDataWriter(w)
+		defer func() {
+			chs.Close()
+			if err := writer.close(); err != nil {
+				errorCh <- errors.Errorf("Record prices failed: %v", err)
+			} else if err := chs.Err(); err != nil {
+				errorCh <- err
+			}
+			close(priceCh)
+			cancel()
+			closeWait.Done()
+		}()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case data, ok := <-chs.PriceCh:
+				if !ok {
+					return
+				}
+				if err := writer.write(time.Now(), data); err != nil {
+					return
+				}
+				select {
+				case priceCh <- data:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return &PriceChannels{
+		PriceCh:   priceCh,
+		lastError: nil,
+		errorCh:   errorCh,
+		close:     cancel,
+		closeWait: closeWait,
+	}
+}
+
+// RecordTransactions passes the transactions of chs on while writing them to
+// w, like RecordPrices.
+func RecordTransactions(chs *TransactionsChannels, w io.Writer) *TransactionsChannels {
+	ctx, cancel := context.WithCancel(context.Background())
+	closeWait := new(sync.WaitGroup)
+	transactionCh := make(chan *TransactionDefinition, cap(chs.TransactionCh))
+	errorCh := make(chan error, 1)
+
+	closeWait.Add(1)
+	go func() {
+		writer := newMarketDataWriter(w)
+		defer func() {
+			chs.Close()
+			if err := writer.close(); err != nil {
+				errorCh <- errors.Errorf("Record transactions failed: %v", err)
+			} else if err := chs.Err(); err != nil {
+				errorCh <- err
+			}
+			close(transactionCh)
+			cancel()
+			closeWait.Done()
+		}()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case data, ok := <-chs.TransactionCh:
+				if !ok {
+					return
+				}
+				if err := writer.write(time.Now(), data); err != nil {
+					return
+				}
+				select {
+				case transactionCh <- data:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return &TransactionsChannels{
+		TransactionCh: transactionCh,
+		lastError:     nil,
+		errorCh:       errorCh,
+		close:         cancel,
+		closeWait:     closeWait,
+	}
+}
+
+/* Replay */
+
+// ReplayPrices sends the prices recorded by RecordPrices from r like
+// ReceiverPricingStream.Get. PriceCh is closed at the end of the recording.
+func ReplayPrices(ctx context.Context, r io.Reader, params *ReplayParams) (*PriceChannels, error) {
+	reader, err := newMarketDataReader(r, params.Speed)
+	if err != nil {
+		return nil, errors.Errorf("Replay prices failed: %v", err)
+	}
+
+	childCtx, cancel := context.WithCancel(ctx)
+	closeWait := new(sync.WaitGroup)
+	priceCh := make(chan *PriceDefinition, params.BufferSize)
+	errorCh := make(chan error, 1)
+
+	closeWait.Add(1)
+	go func() {
+		defer func() {
+			close(priceCh)
+			cancel()
+			closeWait.Done()
+		}()
+
+		for {
+			data := new(PriceDefinition)
+			if err := reader.next(childCtx, data); err != nil {
+				if err != io.EOF && childCtx.Err() == nil {
+					errorCh <- errors.Errorf("Replay prices failed: %v", err)
+				}
+				return
+			}
+			select {
+			case priceCh <- data:
+			case <-childCtx.Done():
+				return
+			}
+		}
+	}()
+
+	return &PriceChannels{
+		PriceCh:   priceCh,
+		lastError: nil,
+		errorCh:   errorCh,
+		close:     cancel,
+		closeWait: closeWait,
+	}, nil
+}
+
+// ReplayTransactions sends the transactions recorded by RecordTransactions
+// from r like ReceiverTransactionsStream.Get. TransactionCh is closed at the
+// end of the recording.
+func ReplayTransactions(ctx context.Context, r io.Reader, params *ReplayParams) (*TransactionsChannels, error) {
+	reader, err := newMarketDataReader(r, params.Speed)
+	if err != nil {
+		return nil, errors.Errorf("Replay transactions failed: %v", err)
+	}
+
+	childCtx, cancel := context.WithCancel(ctx)
+	closeWait := new(sync.WaitGroup)
+	transactionCh := make(chan *TransactionDefinition, params.BufferSize)
+	errorCh := make(chan error, 1)
+
+	closeWait.Add(1)
+	go func() {
+		defer func() {
+			close(transactionCh)
+			cancel()
+			closeWait.Done()
+		}()
+
+		for {
+			data := new(TransactionDefinition)
+			if err := reader.next(childCtx, data); err != nil {
+				if err != io.EOF && childCtx.Err() == nil {
+					errorCh <- errors.Errorf("Replay transactions failed: %v", err)
+				}
+				return
+			}
+			select {
+			case transactionCh <- data:
+			case <-childCtx.Done():
+				return
+			}
+		}
+	}()
+
+	return &TransactionsChannels{
+		TransactionCh: transactionCh,
+		lastError:     nil,
+		errorCh:       errorCh,
+		close:         cancel,
+		closeWait:     closeWait,
+	}, nil
+}
+
+/* Utils */
+
+type marketDataWriter struct {
+	gz  *gzip.Writer
+	err error
+}
+
+func newMarketDataWriter(w io.Writer) *marketDataWriter {
+	return &marketDataWriter{gz: gzip.NewWriter(w)}
+}
+
+// write writes a record of message, errors are kept and returned by close.
+func (w *marketDataWriter) write(received time.Time, message interface{}) error {
+	if w.err != nil {
+		return w.err
+	}
+	data, err := json.Marshal(message)
+	if err != nil {
+		w.err = errors.Errorf("Marshal message failed: %v", err)
+		return w.err
+	}
+	line, err := json.Marshal(&marketDataRecord{Received: received.UTC(), Message: data})
+	if err != nil {
+		w.err = errors.Errorf("Marshal record failed: %v", err)
+		return w.err
+	}
+	if _, err := w.gz.Write(append(line, '\n')); err != nil {
+		w.err = errors.Errorf("Write record failed: %v", err)
+		return w.err
+	}
+	return nil
+}
+
+func (w *marketDataWriter) close() error {
+	if err := w.gz.Close(); err != nil && w.err == nil {
+		w.err = errors.Errorf("Flush records failed: %v", err)
+	}
+	return w.err
+}
+
+type marketDataReader struct {
+	decoder *json.Decoder
+	speed   float64
+
+	// The wall time the replay started and the received time of the first
+	// record, records are due at the same distance from both.
+	start time.Time
+	first time.Time
+}
+
+func newMarketDataReader(r io.Reader, speed float64) (*marketDataReader, error) {
+	if speed < 0 {
+		return nil, errors.Errorf("Speed must not be negative: %v", speed)
+	}
+	gz, err := gzip.NewReader(bufio.NewReader(r))
+	if err != nil {
+		return nil, errors.Errorf("Open market data failed: %v", err)
+	}
+	return &marketDataReader{decoder: json.NewDecoder(gz), speed: speed}, nil
+}
+
+// next decodes the message of the next record into v once it is due. It
+// returns io.EOF at the end of the recording.
+func (r *marketDataReader) next(ctx context.Context, v interface{}) error {
+	record := new(marketDataRecord)
+	if err := r.decoder.Decode(record); err != nil {
+		if err == io.EOF {
+			return err
+		}
+		return decodeFailed(err, "market data record")
+	}
+	if err := json.Unmarshal(record.Message, v); err != nil {
+		return decodeFailed(err, "market data message")
+	}
+
+	if r.speed == ReplayMaxSpeed {
+		return nil
+	}
+	if r.start.IsZero() {
+		r.start, r.first = time.Now(), record.Received
+		return nil
+	}
+	due := r.start.Add(time.Duration(float64(record.Received.Sub(r.first)) / r.speed))
+	if wait := time.Until(due); wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
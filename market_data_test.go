@@ -0,0 +1,134 @@
+package oanda
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func Test_MarketData(t *testing.T) {
+	t.Run("Prices", func(t *testing.T) {
+		buf := new(bytes.Buffer)
+		chs, srcCh := newFakePriceChannels()
+		recording := RecordPrices(chs, buf)
+
+		for _, instrument := range []InstrumentNameDefinition{"EUR_USD", "USD_JPY"} {
+			srcCh <- &PriceDefinition{Type: "PRICE", Instrument: instrument}
+			if data := <-recording.PriceCh; data.Instrument != instrument {
+				t.Fatalf("Got unexpected price.\n%#v", data)
+			}
+		}
+		srcCh <- &PriceDefinition{Type: "HEARTBEAT"}
+		<-recording.PriceCh
+		recording.Close()
+		if err := recording.Err(); err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+
+		replay, err := ReplayPrices(context.Background(), buf, &ReplayParams{})
+		if err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+		var actual []string
+		for data := range replay.PriceCh {
+			actual = append(actual, data.Type+" "+string(data.Instrument))
+		}
+		replay.Close()
+		if err := replay.Err(); err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+		if expect := "PRICE EUR_USD,PRICE USD_JPY,HEARTBEAT "; strings.Join(actual, ",") != expect {
+			t.Fatalf("Got unexpected replay.\nExpect: %s\nActual: %s", expect, strings.Join(actual, ","))
+		}
+	})
+
+	t.Run("Transactions", func(t *testing.T) {
+		buf := new(bytes.Buffer)
+		ctx, cancel := context.WithCancel(context.Background())
+		srcCh := make(chan *TransactionDefinition, 2)
+		recording := RecordTransactions(&TransactionsChannels{
+			TransactionCh: srcCh,
+			errorCh:       make(chan error, 1),
+			close:         cancel,
+			closeWait:     new(sync.WaitGroup),
+		}, buf)
+
+		srcCh <- &TransactionDefinition{ID: "1", Type: "ORDER_FILL"}
+		srcCh <- &TransactionDefinition{ID: "2", Type: "DAILY_FINANCING"}
+		close(srcCh)
+		for range recording.TransactionCh {
+		}
+		recording.Close()
+		<-ctx.Done()
+
+		replay, err := ReplayTransactions(context.Background(), buf, &ReplayParams{BufferSize: 2})
+		if err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+		var actual []string
+		for data := range replay.TransactionCh {
+			actual = append(actual, string(data.ID)+" "+string(data.Type))
+		}
+		replay.Close()
+		if expect := "1 ORDER_FILL,2 DAILY_FINANCING"; strings.Join(actual, ",") != expect {
+			t.Fatalf("Got unexpected replay.\nExpect: %s\nActual: %s", expect, strings.Join(actual, ","))
+		}
+	})
+
+	t.Run("Speed", func(t *testing.T) {
+		buf := new(bytes.Buffer)
+		writer := newMarketDataWriter(buf)
+		received := time.Date(2022, 1, 4, 9, 0, 0, 0, time.UTC)
+		for i := 0; i < 3; i++ {
+			if err := writer.write(received.Add(time.Duration(i)*time.Second), &PriceDefinition{Type: "PRICE"}); err != nil {
+				t.Fatalf("Error occurred.\n%+v", err)
+			}
+		}
+		if err := writer.close(); err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+
+		// Two seconds of recording are replayed in 200ms.
+		start := time.Now()
+		replay, err := ReplayPrices(context.Background(), buf, &ReplayParams{Speed: 10})
+		if err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+		for range replay.PriceCh {
+		}
+		if elapsed := time.Since(start); elapsed < 200*time.Millisecond || elapsed > time.Second {
+			t.Fatalf("Got unexpected replay duration %v.", elapsed)
+		}
+	})
+
+	t.Run("Close", func(t *testing.T) {
+		buf := new(bytes.Buffer)
+		writer := newMarketDataWriter(buf)
+		received := time.Now()
+		writer.write(received, &PriceDefinition{Type: "PRICE"})
+		writer.write(received.Add(time.Hour), &PriceDefinition{Type: "PRICE"})
+		writer.close()
+
+		replay, err := ReplayPrices(context.Background(), buf, &ReplayParams{Speed: ReplayRealTime})
+		if err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+		<-replay.PriceCh
+		replay.Close()
+		if err := replay.Err(); err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		if _, err := ReplayPrices(context.Background(), strings.NewReader("{}"), &ReplayParams{}); err == nil {
+			t.Fatal("Got no error for data that isn't gzip compressed.")
+		}
+		if _, err := ReplayPrices(context.Background(), new(bytes.Buffer), &ReplayParams{Speed: -1}); err == nil {
+			t.Fatal("Got no error for a negative speed.")
+		}
+	})
+}
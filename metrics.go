@@ -0,0 +1,127 @@
+package oanda
+
+import (
+	"strings"
+	"time"
+)
+
+/* Metrics */
+
+// Metrics receives the instrumentation of a Connection. Endpoints are route
+// templates like "/v3/accounts/{accountID}/orders/{orderSpecifier}" to keep
+// the cardinality of labels low. The prometheus subpackage provides an
+// implementation for the Prometheus client.
+type Metrics interface {
+	// ObserveRequest is called after every REST call. Status is 0 when the
+	// server didn't answer.
+	ObserveRequest(method, endpoint string, status int, latency time.Duration)
+	// ObserveStreamMessage is called for every message read from a stream,
+	// with the time since the previous message of the same type. A growing
+	// gap between heartbeats means OANDA is lagging.
+	ObserveStreamMessage(stream, msgType string, gap time.Duration)
+	// IncStreamReconnect is called when a stream is reopened.
+	IncStreamReconnect(stream string)
+	// SetStreamBuffer is called with the number of messages waiting in the
+	// channel of a stream whenever one is added. A full buffer means the
+	// consumer is lagging.
+	SetStreamBuffer(stream string, used, capacity int)
+}
+
+const (
+	StreamPricing      = "pricing"
+	StreamTransactions = "transactions"
+)
+
+func (c *Connection) observeRequest(req *Request, resp *Response, latency time.Duration) {
+	if c.Metrics == nil {
+		return
+	}
+	status := 0
+	if resp != nil {
+		status = resp.StatusCode
+	}
+	c.Metrics.ObserveRequest(req.Method, req.Route, status, latency)
+}
+
+func (c *Connection) incStreamReconnect(stream string) {
+	if c.Metrics != nil {
+		c.Metrics.IncStreamReconnect(stream)
+	}
+}
+
+func (c *Connection) setStreamBuffer(stream string, used, capacity int) {
+	if c.Metrics != nil {
+		c.Metrics.SetStreamBuffer(stream, used, capacity)
+	}
+}
+
+// metricsStreamHook observes the messages of a stream.
+func (c *Connection) metricsStreamHook() *StreamHook {
+	if c.Metrics == nil {
+		return nil
+	}
+
+	// Message is called by the single goroutine reading the stream.
+	last := make(map[string]time.Time)
+	return &StreamHook{
+		Message: func(req *Request, msg interface{}) {
+			var msgType string
+			switch msg := msg.(type) {
+			case *PriceDefinition:
+				msgType = msg.Type
+			case *TransactionDefinition:
+				msgType = msg.Type
+			case Tick:
+				msgType = "PRICE"
+			}
+
+			now := time.Now()
+			var gap time.Duration
+			if t, ok := last[msgType]; ok {
+				gap = now.Sub(t)
+			}
+			last[msgType] = now
+
+			c.Metrics.ObserveStreamMessage(streamName(req.EndPoint), msgType, gap)
+		},
+	}
+}
+
+/* Utils */
+
+// routeSegments are the path segments followed by an ID or name.
+var routeSegments = map[string]string{
+	"accounts":     "{accountID}",
+	"orders":       "{orderSpecifier}",
+	"trades":       "{tradeSpecifier}",
+	"positions":    "{instrument}",
+	"instruments":  "{instrument}",
+	"transactions": "{transactionID}",
+}
+
+// parseRoute replaces the IDs and names in the path of an endpoint with
+// placeholders and returns them by placeholder name.
+func parseRoute(endPoint string) (string, map[string]string) {
+	params := make(map[string]string)
+	segments := strings.Split(endPoint, "/")
+	for i := 1; i < len(segments); i++ {
+		placeholder, ok := routeSegments[segments[i-1]]
+		if !ok {
+			continue
+		}
+		switch segments[i] {
+		case "", "idrange", "sinceid", "stream":
+			continue
+		}
+		params[strings.Trim(placeholder, "{}")] = segments[i]
+		segments[i] = placeholder
+	}
+	return strings.Join(segments, "/"), params
+}
+
+func streamName(endPoint string) string {
+	if strings.Contains(endPoint, "/transactions/") {
+		return StreamTransactions
+	}
+	return StreamPricing
+}
@@ -0,0 +1,76 @@
+package oanda
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+type testMetrics struct {
+	requests []string
+	messages []string
+	gaps     []time.Duration
+}
+
+func (m *testMetrics) ObserveRequest(method, endpoint string, status int, latency time.Duration) {
+	m.requests = append(m.requests, method+" "+endpoint)
+}
+
+func (m *testMetrics) ObserveStreamMessage(stream, msgType string, gap time.Duration) {
+	m.messages = append(m.messages, stream+" "+msgType)
+	m.gaps = append(m.gaps, gap)
+}
+
+func (m *testMetrics) IncStreamReconnect(stream string) {}
+
+func (m *testMetrics) SetStreamBuffer(stream string, used, capacity int) {}
+
+func Test_Metrics(t *testing.T) {
+	t.Run("RouteTemplate", func(t *testing.T) {
+		for endPoint, expect := range map[string]string{
+			"/v3/accounts": "/v3/accounts",
+			"/v3/accounts/101-001-1-001/orders/42/cancel": "/v3/accounts/{accountID}/orders/{orderSpecifier}/cancel",
+			"/v3/accounts/101/positions/EUR_USD/close":    "/v3/accounts/{accountID}/positions/{instrument}/close",
+			"/v3/accounts/101/transactions/idrange":       "/v3/accounts/{accountID}/transactions/idrange",
+			"/v3/accounts/101/transactions/7":             "/v3/accounts/{accountID}/transactions/{transactionID}",
+			"/v3/accounts/101/instruments":                "/v3/accounts/{accountID}/instruments",
+			"/v3/instruments/EUR_USD/candles":             "/v3/instruments/{instrument}/candles",
+			"/v3/accounts/101/trades/@7/clientExtensions": "/v3/accounts/{accountID}/trades/{tradeSpecifier}/clientExtensions",
+		} {
+			if actual, _ := parseRoute(endPoint); actual != expect {
+				t.Fatalf("Got unexpected route of %s.\nExpect: %s\nActual: %s", endPoint, expect, actual)
+			}
+		}
+	})
+
+	t.Run("Request", func(t *testing.T) {
+		metrics := new(testMetrics)
+		connection := &Connection{Environemnt: OandaPractice, DryRun: true, Metrics: metrics}
+
+		_, err := connection.Accounts().AccountID("101").Orders().OrderSpecifier("42").Cancel().Put(context.Background())
+		if err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+		if len(metrics.requests) != 1 || metrics.requests[0] != "PUT /v3/accounts/{accountID}/orders/{orderSpecifier}/cancel" {
+			t.Fatalf("Got unexpected requests.\n%v", metrics.requests)
+		}
+	})
+
+	t.Run("StreamMessage", func(t *testing.T) {
+		metrics := new(testMetrics)
+		connection := &Connection{Metrics: metrics}
+
+		observer := connection.newStreamObserver(&Request{EndPoint: "/v3/accounts/101/pricing/stream", Stream: true})
+		observer.message(&PriceDefinition{Type: "HEARTBEAT"})
+		observer.message(&PriceDefinition{Type: "PRICE"})
+		time.Sleep(time.Millisecond)
+		observer.message(&PriceDefinition{Type: "HEARTBEAT"})
+
+		if len(metrics.messages) != 3 || metrics.messages[0] != "pricing HEARTBEAT" || metrics.messages[1] != "pricing PRICE" {
+			t.Fatalf("Got unexpected messages.\n%v", metrics.messages)
+		}
+		if metrics.gaps[0] != 0 || metrics.gaps[1] != 0 || metrics.gaps[2] < time.Millisecond {
+			t.Fatalf("Got unexpected gaps.\n%v", metrics.gaps)
+		}
+	})
+}
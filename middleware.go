@@ -0,0 +1,165 @@
+package oanda
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+/* Middleware */
+
+// Request is a REST call as seen by middlewares. Middlewares may modify it,
+// e.g. to inject headers, before passing it on.
+type Request struct {
+	Method   string
+	EndPoint string
+	// Route is the endpoint with placeholders for the IDs and names in its
+	// path, e.g. "/v3/accounts/{accountID}/orders/{orderSpecifier}", and
+	// Params holds them by placeholder name, e.g. "accountID".
+	Route  string
+	Params map[string]string
+	Query  url.Values
+	Header http.Header
+	// Body is marshalled to JSON when the request is sent.
+	Body interface{}
+	// Whether the request opens a stream.
+	Stream bool
+}
+
+// Response is the outcome of a REST call. It is returned along with the error
+// whenever the server answered.
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Latency    time.Duration
+	// Result is the decoded body, a schema on success or the error type of
+	// the endpoint otherwise.
+	Result interface{}
+}
+
+type Handler func(ctx context.Context, req *Request) (*Response, error)
+
+// Middleware wraps the handler of every REST call of a Connection.
+type Middleware func(next Handler) Handler
+
+// Use appends middlewares to the chain of the connection. The first
+// middleware is the outermost.
+func (c *Connection) Use(middlewares ...Middleware) {
+	c.Middlewares = append(c.Middlewares, middlewares...)
+}
+
+func (c *Connection) chain(handler Handler) Handler {
+	for i := len(c.Middlewares) - 1; i >= 0; i-- {
+		handler = c.Middlewares[i](handler)
+	}
+	return handler
+}
+
+func newRequest(params *requestParams) *Request {
+	req := &Request{
+		Method:   params.method,
+		EndPoint: params.endPoint,
+		Query:    make(url.Values),
+		Header:   make(http.Header),
+		Body:     params.body,
+	}
+	req.Route, req.Params = parseRoute(params.endPoint)
+	for _, h := range params.headers {
+		req.Header.Set(h.key, h.value)
+	}
+	for _, q := range params.queries {
+		req.Query.Add(q.key, q.value)
+	}
+	return req
+}
+
+/* Stream hooks */
+
+// StreamHook observes the streams of a Connection. Any of its functions may
+// be nil.
+type StreamHook struct {
+	// Open is called when a stream was requested, with the response or the
+	// error of the request.
+	Open func(ctx context.Context, req *Request, resp *Response, err error)
+	// Message is called with every message of a stream, heartbeats included.
+	Message func(req *Request, msg interface{})
+	// Close is called once a stream that opened has ended, with the error
+	// that ended it or nil when it was closed by the client.
+	Close func(req *Request, err error)
+}
+
+type streamObserver struct {
+	hooks []*StreamHook
+	req   *Request
+
+	mu     sync.Mutex
+	err    error
+	closed bool
+}
+
+func (c *Connection) newStreamObserver(req *Request) *streamObserver {
+	hooks := append(c.StreamHooks[:len(c.StreamHooks):len(c.StreamHooks)], c.logStreamHook())
+	if h := c.metricsStreamHook(); h != nil {
+		hooks = append(hooks, h)
+	}
+	return &streamObserver{
+		hooks: hooks,
+		req:   req,
+	}
+}
+
+func (o *streamObserver) open(ctx context.Context, resp *Response, err error) {
+	for _, h := range o.hooks {
+		if h.Open != nil {
+			h.Open(ctx, o.req, resp, err)
+		}
+	}
+}
+
+// observesMessages tells whether any hook observes messages, so that messages
+// are only built for hooks.
+func (o *streamObserver) observesMessages() bool {
+	for _, h := range o.hooks {
+		if h.Message != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func (o *streamObserver) message(msg interface{}) {
+	for _, h := range o.hooks {
+		if h.Message != nil {
+			h.Message(o.req, msg)
+		}
+	}
+}
+
+// fail records the first error that ends the stream and returns it.
+func (o *streamObserver) fail(err error) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.err == nil {
+		o.err = err
+	}
+	return err
+}
+
+func (o *streamObserver) close() {
+	o.mu.Lock()
+	if o.closed {
+		o.mu.Unlock()
+		return
+	}
+	o.closed = true
+	err := o.err
+	o.mu.Unlock()
+
+	for _, h := range o.hooks {
+		if h.Close != nil {
+			h.Close(o.req, err)
+		}
+	}
+}
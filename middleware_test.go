@@ -0,0 +1,104 @@
+package oanda
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func Test_Middleware(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Chain", func(t *testing.T) {
+		var calls []string
+		trace := func(name string) Middleware {
+			return func(next Handler) Handler {
+				return func(ctx context.Context, req *Request) (*Response, error) {
+					calls = append(calls, name+">")
+					req.Header.Set("X-Trace", name)
+					resp, err := next(ctx, req)
+					calls = append(calls, "<"+name)
+					return resp, err
+				}
+			}
+		}
+
+		var seen *Request
+		var result interface{}
+		connection := &Connection{Environemnt: OandaPractice, DryRun: true}
+		connection.Use(trace("outer"), trace("inner"), func(next Handler) Handler {
+			return func(ctx context.Context, req *Request) (*Response, error) {
+				seen = req
+				resp, err := next(ctx, req)
+				if resp != nil {
+					result = resp.Result
+				}
+				return resp, err
+			}
+		})
+
+		data, err := connection.Accounts().AccountID("101").Orders().OrderSpecifier("42").Cancel().Put(ctx)
+		if err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+
+		if expect := []string{"outer>", "inner>", "<inner", "<outer"}; !reflect.DeepEqual(calls, expect) {
+			t.Fatalf("Got unexpected calls.\nExpect: %v\nActual: %v", expect, calls)
+		}
+		if seen.Method != "PUT" || seen.EndPoint != "/v3/accounts/101/orders/42/cancel" || seen.Header.Get("X-Trace") != "inner" {
+			t.Fatalf("Got unexpected request.\n%#v", seen)
+		}
+		if result != data {
+			t.Fatalf("Got unexpected result.\n%#v", result)
+		}
+	})
+
+	t.Run("ShortCircuit", func(t *testing.T) {
+		connection := &Connection{Environemnt: OandaPractice}
+		connection.Use(func(next Handler) Handler {
+			return func(ctx context.Context, req *Request) (*Response, error) {
+				if req.Query.Get("instruments") != "EUR_USD" {
+					return nil, errors.New("unexpected query")
+				}
+				return &Response{StatusCode: 200, Result: &GetPricingSchema{Time: "cached"}}, nil
+			}
+		})
+
+		data, err := connection.Accounts().AccountID("101").Pricing().Get(ctx, &GetPricingParams{Instruments: []string{"EUR_USD"}})
+		if err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+		if data.Time != "cached" {
+			t.Fatalf("Got unexpected result.\n%#v", data)
+		}
+	})
+
+	t.Run("StreamHooks", func(t *testing.T) {
+		var events []string
+		connection := &Connection{}
+		connection.StreamHooks = append(connection.StreamHooks, &StreamHook{
+			Open: func(ctx context.Context, req *Request, resp *Response, err error) {
+				events = append(events, "open")
+			},
+			Message: func(req *Request, msg interface{}) {
+				events = append(events, "message:"+msg.(*PriceDefinition).Type)
+			},
+			Close: func(req *Request, err error) {
+				events = append(events, "close:"+err.Error())
+			},
+		})
+
+		observer := connection.newStreamObserver(&Request{Stream: true})
+		observer.open(ctx, &Response{StatusCode: 200}, nil)
+		observer.message(&PriceDefinition{Type: "HEARTBEAT"})
+		observer.fail(errors.New("broken"))
+		observer.fail(errors.New("later"))
+		observer.close()
+		observer.close()
+
+		if expect := []string{"open", "message:HEARTBEAT", "close:broken"}; !reflect.DeepEqual(events, expect) {
+			t.Fatalf("Got unexpected events.\nExpect: %v\nActual: %v", expect, events)
+		}
+	})
+}
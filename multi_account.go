@@ -0,0 +1,369 @@
+package oanda
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+/* Receivers */
+
+// MultiAccount fans queries out to several accounts of the same token in
+// parallel and consolidates the results. An account that fails is reported in
+// the Errors of the result and doesn't fail the others.
+type MultiAccount struct {
+	AccountIDs []AccountIDDefinition
+	Connection *Connection
+	// Maximum number of accounts queried at once, unlimited when zero.
+	Concurrency int
+}
+
+func (c *Connection) MultiAccount(ids ...AccountIDDefinition) *MultiAccount {
+	return &MultiAccount{
+		AccountIDs: ids,
+		Connection: c,
+	}
+}
+
+// Multi returns a MultiAccount over every account the token has access to.
+func (r *ReceiverAccounts) Multi(ctx context.Context) (*MultiAccount, error) {
+	data, err := r.Get(ctx)
+	if err != nil {
+		return nil, errors.Errorf("Get multi account failed: %v", err)
+	}
+
+	ids := make([]AccountIDDefinition, 0, len(data.Accounts))
+	for _, account := range data.Accounts {
+		ids = append(ids, account.ID)
+	}
+	return r.Connection.MultiAccount(ids...), nil
+}
+
+/* Schemas */
+
+type MultiAccountSummarySchema struct {
+	Accounts map[AccountIDDefinition]*GetAccountSummarySchema
+	Errors   map[AccountIDDefinition]error
+	// Totals of the accounts grouped by their currency.
+	Totals map[CurrencyDefinition]*MultiAccountTotals
+}
+
+type MultiAccountTotals struct {
+	Accounts        int
+	Balance         float64
+	NAV             float64
+	UnrealizedPL    float64
+	PL              float64
+	MarginUsed      float64
+	MarginAvailable float64
+	PositionValue   float64
+}
+
+type MultiAccountPositionsSchema struct {
+	Accounts map[AccountIDDefinition]*GetOpenPositionsSchema
+	Errors   map[AccountIDDefinition]error
+	// Net exposure per instrument across the accounts.
+	Positions map[InstrumentNameDefinition]*MultiAccountPosition
+}
+
+type MultiAccountPosition struct {
+	Instrument InstrumentNameDefinition
+	LongUnits  float64
+	ShortUnits float64
+	NetUnits   float64
+	AccountIDs []AccountIDDefinition
+}
+
+type MultiAccountTradesSchema struct {
+	Accounts map[AccountIDDefinition]*GetOpenTradesSchema
+	Errors   map[AccountIDDefinition]error
+	// Open trades of every account ordered by account.
+	Trades []*MultiAccountTrade
+}
+
+type MultiAccountTrade struct {
+	AccountID AccountIDDefinition
+	Trade     *TradeDefinition
+}
+
+type MultiAccountPricingSchema struct {
+	Accounts map[AccountIDDefinition]*GetPricingSchema
+	Errors   map[AccountIDDefinition]error
+}
+
+/* API */
+
+// GET /v3/accounts/{accountID}/summary for every account
+func (m *MultiAccount) Summary(ctx context.Context) (*MultiAccountSummarySchema, error) {
+	var mu sync.Mutex
+	data := &MultiAccountSummarySchema{Accounts: make(map[AccountIDDefinition]*GetAccountSummarySchema)}
+
+	data.Errors = m.each(ctx, func(ctx context.Context, r *ReceiverAccountID) error {
+		summary, err := r.Summary().Get(ctx)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		data.Accounts[r.AccountID] = summary
+		mu.Unlock()
+		return nil
+	})
+
+	data.Totals = aggregateAccountSummaries(data.Accounts, data.Errors)
+
+	return data, m.err("Get multi account summary failed", data.Errors)
+}
+
+// GET /v3/accounts/{accountID}/openPositions for every account
+func (m *MultiAccount) OpenPositions(ctx context.Context) (*MultiAccountPositionsSchema, error) {
+	var mu sync.Mutex
+	data := &MultiAccountPositionsSchema{Accounts: make(map[AccountIDDefinition]*GetOpenPositionsSchema)}
+
+	data.Errors = m.each(ctx, func(ctx context.Context, r *ReceiverAccountID) error {
+		positions, err := r.OpenPositions().Get(ctx)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		data.Accounts[r.AccountID] = positions
+		mu.Unlock()
+		return nil
+	})
+
+	data.Positions = aggregateAccountPositions(data.Accounts, data.Errors)
+
+	return data, m.err("Get multi account open positions failed", data.Errors)
+}
+
+// GET /v3/accounts/{accountID}/openTrades for every account
+func (m *MultiAccount) OpenTrades(ctx context.Context) (*MultiAccountTradesSchema, error) {
+	var mu sync.Mutex
+	data := &MultiAccountTradesSchema{Accounts: make(map[AccountIDDefinition]*GetOpenTradesSchema)}
+
+	data.Errors = m.each(ctx, func(ctx context.Context, r *ReceiverAccountID) error {
+		trades, err := r.OpenTrades().Get(ctx)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		data.Accounts[r.AccountID] = trades
+		mu.Unlock()
+		return nil
+	})
+
+	data.Trades = aggregateAccountTrades(data.Accounts)
+
+	return data, m.err("Get multi account open trades failed", data.Errors)
+}
+
+// GET /v3/accounts/{accountID}/pricing for every account
+func (m *MultiAccount) Pricing(ctx context.Context, params *GetPricingParams) (*MultiAccountPricingSchema, error) {
+	var mu sync.Mutex
+	data := &MultiAccountPricingSchema{Accounts: make(map[AccountIDDefinition]*GetPricingSchema)}
+
+	data.Errors = m.each(ctx, func(ctx context.Context, r *ReceiverAccountID) error {
+		pricing, err := r.Pricing().Get(ctx, params)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		data.Accounts[r.AccountID] = pricing
+		mu.Unlock()
+		return nil
+	})
+
+	return data, m.err("Get multi account pricing failed", data.Errors)
+}
+
+/* Utils */
+
+// each calls fn for every account in parallel and returns the errors by
+// account.
+func (m *MultiAccount) each(ctx context.Context, fn func(ctx context.Context, r *ReceiverAccountID) error) map[AccountIDDefinition]error {
+	var (
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+		errs = make(map[AccountIDDefinition]error)
+	)
+
+	var sem chan struct{}
+	if m.Concurrency > 0 {
+		sem = make(chan struct{}, m.Concurrency)
+	}
+
+	accounts := m.Connection.Accounts()
+	for _, id := range m.AccountIDs {
+		wg.Add(1)
+		go func(r *ReceiverAccountID) {
+			defer wg.Done()
+
+			if sem != nil {
+				select {
+				case sem <- struct{}{}:
+					defer func() { <-sem }()
+				case <-ctx.Done():
+					mu.Lock()
+					errs[r.AccountID] = ctx.Err()
+					mu.Unlock()
+					return
+				}
+			}
+
+			if err := fn(ctx, r); err != nil {
+				mu.Lock()
+				errs[r.AccountID] = err
+				mu.Unlock()
+			}
+		}(accounts.AccountID(id))
+	}
+	wg.Wait()
+
+	return errs
+}
+
+// err returns a MultiAccountError when every account failed.
+func (m *MultiAccount) err(message string, errs map[AccountIDDefinition]error) error {
+	if len(errs) == 0 || len(errs) < len(m.AccountIDs) {
+		return nil
+	}
+
+	ids := make([]string, 0, len(errs))
+	for id := range errs {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	causes := make([]string, 0, len(ids))
+	for _, id := range ids {
+		causes = append(causes, fmt.Sprintf("%s: %v", id, errs[id]))
+	}
+
+	return &MultiAccountError{
+		ErrorMessage: fmt.Sprintf("%s: %s", message, strings.Join(causes, "; ")),
+		Errors:       errs,
+	}
+}
+
+// aggregateAccountSummaries totals the accounts by currency. Accounts whose
+// values don't parse are moved from accounts to errs.
+func aggregateAccountSummaries(accounts map[AccountIDDefinition]*GetAccountSummarySchema, errs map[AccountIDDefinition]error) map[CurrencyDefinition]*MultiAccountTotals {
+	totals := make(map[CurrencyDefinition]*MultiAccountTotals)
+	for id, summary := range accounts {
+		if summary.Account == nil {
+			continue
+		}
+		account := summary.Account
+
+		values := new(MultiAccountTotals)
+		var err error
+		for _, f := range []struct {
+			value string
+			total *float64
+		}{
+			{account.Balance, &values.Balance},
+			{account.NAV, &values.NAV},
+			{account.UnrealizedPL, &values.UnrealizedPL},
+			{account.PL, &values.PL},
+			{account.MarginUsed, &values.MarginUsed},
+			{account.MarginAvailable, &values.MarginAvailable},
+			{account.PositionValue, &values.PositionValue},
+		} {
+			if f.value == "" {
+				continue
+			}
+			if *f.total, err = parseDecimal(f.value); err != nil {
+				break
+			}
+		}
+		if err != nil {
+			errs[id] = errors.Errorf("Aggregate account %s failed: %v", id, err)
+			delete(accounts, id)
+			continue
+		}
+
+		t, ok := totals[account.Currency]
+		if !ok {
+			t = new(MultiAccountTotals)
+			totals[account.Currency] = t
+		}
+		t.Accounts++
+		t.Balance += values.Balance
+		t.NAV += values.NAV
+		t.UnrealizedPL += values.UnrealizedPL
+		t.PL += values.PL
+		t.MarginUsed += values.MarginUsed
+		t.MarginAvailable += values.MarginAvailable
+		t.PositionValue += values.PositionValue
+	}
+	return totals
+}
+
+// aggregateAccountPositions nets the positions of the accounts by instrument.
+// Accounts whose units don't parse are moved from accounts to errs.
+func aggregateAccountPositions(accounts map[AccountIDDefinition]*GetOpenPositionsSchema, errs map[AccountIDDefinition]error) map[InstrumentNameDefinition]*MultiAccountPosition {
+	ids := make([]string, 0, len(accounts))
+	for id := range accounts {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	positions := make(map[InstrumentNameDefinition]*MultiAccountPosition)
+	for _, id := range ids {
+		units, err := parsePositionUnits(accounts[id].Positions)
+		if err != nil {
+			errs[id] = errors.Errorf("Aggregate account %s failed: %v", id, err)
+			delete(accounts, id)
+			continue
+		}
+		for i, position := range accounts[id].Positions {
+			p, ok := positions[position.Instrument]
+			if !ok {
+				p = &MultiAccountPosition{Instrument: position.Instrument}
+				positions[position.Instrument] = p
+			}
+			p.AccountIDs = append(p.AccountIDs, id)
+			p.LongUnits += units[i][0]
+			p.ShortUnits += units[i][1]
+			p.NetUnits = p.LongUnits + p.ShortUnits
+		}
+	}
+	return positions
+}
+
+// parsePositionUnits parses the long and short units of positions.
+func parsePositionUnits(positions []*PositionDefinition) ([][2]float64, error) {
+	units := make([][2]float64, len(positions))
+	for i, position := range positions {
+		for j, side := range []*PositionSideDefinition{position.Long, position.Short} {
+			if side == nil || side.Units == "" {
+				continue
+			}
+			v, err := parseDecimal(side.Units)
+			if err != nil {
+				return nil, err
+			}
+			units[i][j] = v
+		}
+	}
+	return units, nil
+}
+
+func aggregateAccountTrades(accounts map[AccountIDDefinition]*GetOpenTradesSchema) []*MultiAccountTrade {
+	ids := make([]string, 0, len(accounts))
+	for id := range accounts {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	var trades []*MultiAccountTrade
+	for _, id := range ids {
+		for _, trade := range accounts[id].Trades {
+			trades = append(trades, &MultiAccountTrade{AccountID: id, Trade: trade})
+		}
+	}
+	return trades
+}
@@ -0,0 +1,110 @@
+package oanda
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func Test_MultiAccount(t *testing.T) {
+	m := (&Connection{Environemnt: oandaDummy}).MultiAccount("001", "002", "003")
+	m.Concurrency = 2
+
+	t.Run("Each", func(t *testing.T) {
+		var running, peak int32
+		errs := m.each(context.Background(), func(ctx context.Context, r *ReceiverAccountID) error {
+			n := atomic.AddInt32(&running, 1)
+			defer atomic.AddInt32(&running, -1)
+			for {
+				p := atomic.LoadInt32(&peak)
+				if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+
+			if r.AccountID == "002" {
+				return errors.New("forbidden")
+			}
+			return nil
+		})
+
+		if len(errs) != 1 || errs["002"] == nil {
+			t.Fatalf("Got unexpected errors.\n%v", errs)
+		}
+		if peak > 2 {
+			t.Fatalf("Got %d concurrent requests, expect at most 2.", peak)
+		}
+		if err := m.err("Failed", errs); err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+	})
+
+	t.Run("AllFailed", func(t *testing.T) {
+		errs := m.each(context.Background(), func(ctx context.Context, r *ReceiverAccountID) error {
+			return errors.New("unauthorized")
+		})
+		if _, ok := m.err("Failed", errs).(*MultiAccountError); !ok {
+			t.Fatalf("Got unexpected error.\n%v", m.err("Failed", errs))
+		}
+	})
+
+	t.Run("Summary", func(t *testing.T) {
+		accounts := map[AccountIDDefinition]*GetAccountSummarySchema{
+			"001": {Account: &AccountSummaryDefinition{Currency: "USD", Balance: "1000", NAV: "1010", UnrealizedPL: "10"}},
+			"002": {Account: &AccountSummaryDefinition{Currency: "USD", Balance: "500.5", NAV: "495.5", UnrealizedPL: "-5"}},
+			"003": {Account: &AccountSummaryDefinition{Currency: "EUR", Balance: "200", NAV: "200"}},
+			// An account whose values don't parse is reported and skipped.
+			"004": {Account: &AccountSummaryDefinition{Currency: "USD", Balance: "300", NAV: "n/a"}},
+		}
+		errs := make(map[AccountIDDefinition]error)
+		totals := aggregateAccountSummaries(accounts, errs)
+		if len(errs) != 1 || errs["004"] == nil || accounts["004"] != nil || len(accounts) != 3 {
+			t.Fatalf("Got unexpected errors.\n%v", errs)
+		}
+		if usd := totals["USD"]; usd.Accounts != 2 || usd.Balance != 1500.5 || usd.NAV != 1505.5 || usd.UnrealizedPL != 5 {
+			t.Fatalf("Got unexpected totals.\n%#v", usd)
+		}
+		if eur := totals["EUR"]; eur.Accounts != 1 || eur.Balance != 200 {
+			t.Fatalf("Got unexpected totals.\n%#v", eur)
+		}
+	})
+
+	t.Run("Positions", func(t *testing.T) {
+		accounts := map[AccountIDDefinition]*GetOpenPositionsSchema{
+			"001": {Positions: []*PositionDefinition{
+				{Instrument: "EUR_USD", Long: &PositionSideDefinition{Units: "100"}, Short: &PositionSideDefinition{Units: "0"}},
+			}},
+			"002": {Positions: []*PositionDefinition{
+				{Instrument: "EUR_USD", Long: &PositionSideDefinition{Units: "0"}, Short: &PositionSideDefinition{Units: "-250"}},
+			}},
+			// An account whose units don't parse is reported and skipped.
+			"003": {Positions: []*PositionDefinition{
+				{Instrument: "EUR_USD", Long: &PositionSideDefinition{Units: "50"}, Short: &PositionSideDefinition{Units: "0"}},
+				{Instrument: "GBP_USD", Long: &PositionSideDefinition{Units: "n/a"}},
+			}},
+		}
+		errs := make(map[AccountIDDefinition]error)
+		positions := aggregateAccountPositions(accounts, errs)
+		if len(errs) != 1 || errs["003"] == nil || accounts["003"] != nil || positions["GBP_USD"] != nil {
+			t.Fatalf("Got unexpected errors.\n%v", errs)
+		}
+		p := positions["EUR_USD"]
+		if p.LongUnits != 100 || p.ShortUnits != -250 || p.NetUnits != -150 || len(p.AccountIDs) != 2 {
+			t.Fatalf("Got unexpected position.\n%#v", p)
+		}
+	})
+
+	t.Run("Trades", func(t *testing.T) {
+		trades := aggregateAccountTrades(map[AccountIDDefinition]*GetOpenTradesSchema{
+			"002": {Trades: []*TradeDefinition{{ID: "5"}}},
+			"001": {Trades: []*TradeDefinition{{ID: "7"}, {ID: "8"}}},
+		})
+		if len(trades) != 3 || trades[0].AccountID != "001" || trades[2].AccountID != "002" {
+			t.Fatalf("Got unexpected trades.\n%#v", trades)
+		}
+	})
+}
@@ -3,44 +3,34 @@ package oanda
 import (
 	"net/url"
 	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
-	dot "github.com/MakeNowJust/heredoc/dot"
 	"github.com/joho/godotenv"
 	"github.com/pkg/errors"
 )
 
+var testConfig *Config
+
+// init loads the test profile from .env or the environment. Tests that need
+// the API are skipped when no profile is configured.
 func init() {
-	if _, err := os.Stat("./.env"); err != nil {
-		file, fErr := os.OpenFile("./.env", os.O_WRONLY|os.O_CREATE, 0644)
-		if fErr != nil {
-			panic(errors.Errorf("Create .env failed: %v", err))
-		}
-		_, fErr = file.WriteString(dot.D(`
-			TOKEN=
-			ACCOUNT_ID=
-		`))
-		if fErr != nil {
-			panic(errors.Errorf("Write .env failed: %v", err))
+	if _, err := os.Stat("./.env"); err == nil {
+		if err := godotenv.Load(); err != nil {
+			panic(errors.Errorf("Error loading .env file: %v", err))
 		}
-
-		panic(errors.Errorf(".env file not exist: %v", err))
 	}
 
-	err := godotenv.Load()
+	config, err := LoadConfigEnv("")
 	if err != nil {
-		panic(errors.Errorf("Error loading .env file: %v", err))
-	}
-
-	if os.Getenv("TOKEN") == "" {
-		panic(errors.Errorf("Env 'TOKEN' is empty"))
-	}
-
-	if os.Getenv("ACCOUNT_ID") == "" {
-		panic(errors.Errorf("Env 'ACCOUNT_ID' is empty"))
+		panic(errors.Errorf("Load test config failed: %v", err))
 	}
+	// Tests must never run against a live account.
+	config.AllowLive = false
+	testConfig = config
 }
 
 func Test_oandaBaseURL(t *testing.T) {
@@ -71,16 +61,65 @@ func Test_oandaBaseURL(t *testing.T) {
 	}
 }
 
+// testReplayAccountID replaces the account ID of the test profile in
+// cassettes.
+const testReplayAccountID = "101-001-0000000-001"
+
+// newConnection returns a connection to the test profile. With OANDA_RECORD
+// set, its exchanges are recorded to the cassette of the test. Without a test
+// profile, the cassette of the test is replayed.
 func newConnection(t *testing.T, env OandaEnvironment) *Connection {
 	if env == OandaLive {
 		t.Fatal("Live environment for testing is prohibited.")
 	}
 
-	connection := &Connection{
-		Token:       Getenv("TOKEN"),
-		Environemnt: env,
-		Timeout:     time.Second * 30,
-		Strict:      true,
+	path := filepath.Join("testdata", "cassettes", strings.ReplaceAll(t.Name(), "/", "_")+".json")
+
+	if testConfig.Default == "" {
+		if _, err := os.Stat(path); err != nil {
+			t.Skip("No test profile configured, set TOKEN and ACCOUNT_ID in .env, and no cassette recorded.")
+		}
+		replayer, err := LoadReplayer(path)
+		if err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+		t.Setenv("ACCOUNT_ID", testReplayAccountID)
+
+		return &Connection{
+			Token:       "replay",
+			Environemnt: env,
+			Timeout:     time.Second * 30,
+			Strict:      true,
+			Transport:   replayer,
+		}
+	}
+
+	connection, err := testConfig.Connection("")
+	if err != nil {
+		t.Fatalf("Error occurred.\n%+v", err)
+	}
+	if connection.Environemnt != env {
+		t.Skipf("Test profile environment is %d, test needs %d.", connection.Environemnt, env)
+	}
+	connection.Timeout = time.Second * 30
+	connection.Strict = true
+
+	if os.Getenv("OANDA_RECORD") != "" {
+		profile, err := testConfig.Profile("")
+		if err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+		recorder := &Recorder{
+			Redact: func(s string) string {
+				return strings.ReplaceAll(s, profile.AccountID, testReplayAccountID)
+			},
+		}
+		connection.Transport = recorder
+		t.Cleanup(func() {
+			if err := recorder.Save(path); err != nil {
+				t.Errorf("Error occurred.\n%+v", err)
+			}
+		})
 	}
 
 	return connection
@@ -2,6 +2,7 @@ package oanda
 
 import (
 	"context"
+	"net/http"
 	"strconv"
 	"strings"
 
@@ -111,9 +112,120 @@ type PutOrderSpecifierClientExtensionsParams struct {
 	Body *PutOrderSpecifierClientExtensionsBodyParams
 }
 
+/* Headers */
+
+type PostOrdersHeaders struct {
+	RequestID string
+	// Location is the URL of the created order.
+	Location string
+}
+
+func (s *PostOrdersSchema) setHeaders(resp *http.Response) error {
+	s.Headers = new(PostOrdersHeaders)
+	if h, err := copyHeader(resp, "Requestid"); err == nil {
+		s.Headers.RequestID = h[0]
+	} else {
+		return errors.Errorf("Parse headers failed: %v", err)
+	}
+	if h, err := copyHeader(resp, "Location"); err == nil {
+		s.Headers.Location = h[0]
+	}
+	return nil
+}
+
+type GetOrdersHeaders struct {
+	RequestID string
+}
+
+func (s *GetOrdersSchema) setHeaders(resp *http.Response) error {
+	s.Headers = new(GetOrdersHeaders)
+	if h, err := copyHeader(resp, "Requestid"); err == nil {
+		s.Headers.RequestID = h[0]
+	} else {
+		return errors.Errorf("Parse headers failed: %v", err)
+	}
+	return nil
+}
+
+type GetPendingOrdersHeaders struct {
+	RequestID string
+}
+
+func (s *GetPendingOrdersSchema) setHeaders(resp *http.Response) error {
+	s.Headers = new(GetPendingOrdersHeaders)
+	if h, err := copyHeader(resp, "Requestid"); err == nil {
+		s.Headers.RequestID = h[0]
+	} else {
+		return errors.Errorf("Parse headers failed: %v", err)
+	}
+	return nil
+}
+
+type GetOrderSpecifierHeaders struct {
+	RequestID string
+}
+
+func (s *GetOrderSpecifierSchema) setHeaders(resp *http.Response) error {
+	s.Headers = new(GetOrderSpecifierHeaders)
+	if h, err := copyHeader(resp, "Requestid"); err == nil {
+		s.Headers.RequestID = h[0]
+	} else {
+		return errors.Errorf("Parse headers failed: %v", err)
+	}
+	return nil
+}
+
+type PutOrderSpecifierHeaders struct {
+	RequestID string
+	// Location is the URL of the created order.
+	Location string
+}
+
+func (s *PutOrderSpecifierSchema) setHeaders(resp *http.Response) error {
+	s.Headers = new(PutOrderSpecifierHeaders)
+	if h, err := copyHeader(resp, "Requestid"); err == nil {
+		s.Headers.RequestID = h[0]
+	} else {
+		return errors.Errorf("Parse headers failed: %v", err)
+	}
+	if h, err := copyHeader(resp, "Location"); err == nil {
+		s.Headers.Location = h[0]
+	}
+	return nil
+}
+
+type PutOrderSpecifierCancelHeaders struct {
+	RequestID string
+}
+
+func (s *PutOrderSpecifierCancelSchema) setHeaders(resp *http.Response) error {
+	s.Headers = new(PutOrderSpecifierCancelHeaders)
+	if h, err := copyHeader(resp, "Requestid"); err == nil {
+		s.Headers.RequestID = h[0]
+	} else {
+		return errors.Errorf("Parse headers failed: %v", err)
+	}
+	return nil
+}
+
+type PutOrderSpecifierClientExtensionsHeaders struct {
+	RequestID string
+}
+
+func (s *PutOrderSpecifierClientExtensionsSchema) setHeaders(resp *http.Response) error {
+	s.Headers = new(PutOrderSpecifierClientExtensionsHeaders)
+	if h, err := copyHeader(resp, "Requestid"); err == nil {
+		s.Headers.RequestID = h[0]
+	} else {
+		return errors.Errorf("Parse headers failed: %v", err)
+	}
+	return nil
+}
+
 /* Schemas */
 
 type PostOrdersSchema struct {
+	Headers                       *PostOrdersHeaders
 	OrderCreateTransaction        *TransactionDefinition    `json:"orderCreateTransaction,omitempty"`
 	OrderFillTransaction          *TransactionDefinition    `json:"orderFillTransaction,omitempty"`
 	OrderCancelTransaction        *TransactionDefinition    `json:"orderCancelTransaction,omitempty"`
@@ -131,21 +243,25 @@ func (p *PostOrdersSchema) ToTradeResult() *TradeResult {
 }
 
 type GetOrdersSchema struct {
+	Headers           *GetOrdersHeaders
 	Orders            []*OrderDefinition      `json:"orders,omitempty"`
 	LastTransactionID TransactionIDDefinition `json:"lastTransactionID,omitempty"`
 }
 
 type GetPendingOrdersSchema struct {
+	Headers           *GetPendingOrdersHeaders
 	Orders            []*OrderDefinition      `json:"orders,omitempty"`
 	LastTransactionID TransactionIDDefinition `json:"lastTransactionID,omitempty"`
 }
 
 type GetOrderSpecifierSchema struct {
+	Headers           *GetOrderSpecifierHeaders
 	Order             *OrderDefinition        `json:"order,omitempty"`
 	LastTransactionID TransactionIDDefinition `json:"lastTransactionID,omitempty"`
 }
 
 type PutOrderSpecifierSchema struct {
+	Headers                         *PutOrderSpecifierHeaders
 	OrderCancelTransaction          *TransactionDefinition    `json:"orderCancelTransaction,omitempty"`
 	OrderCreateTransaction          *TransactionDefinition    `json:"orderCreateTransaction,omitempty"`
 	OrderFillTransaction            *TransactionDefinition    `json:"orderFillTransaction,omitempty"`
@@ -157,12 +273,14 @@ type PutOrderSpecifierSchema struct {
 }
 
 type PutOrderSpecifierCancelSchema struct {
+	Headers                *PutOrderSpecifierCancelHeaders
 	OrderCancelTransaction *TransactionDefinition    `json:"orderCancelTransaction,omitempty"`
 	RelatedTransactionIDs  []TransactionIDDefinition `json:"relatedTransactionIDs,omitempty"`
 	LastTransactionID      TransactionIDDefinition   `json:"lastTransactionID,omitempty"`
 }
 
 type PutOrderSpecifierClientExtensionsSchema struct {
+	Headers                                *PutOrderSpecifierClientExtensionsHeaders
 	OrderClientExtensionsModifyTransaction *TransactionDefinition    `json:"orderClientExtensionsModifyTransaction,omitempty"`
 	LastTransactionID                      TransactionIDDefinition   `json:"lastTransactionID,omitempty"`
 	RelatedTransactionIDs                  []TransactionIDDefinition `json:"relatedTransactionIDs,omitempty"`
@@ -171,6 +289,8 @@ type PutOrderSpecifierClientExtensionsSchema struct {
 /* Errors */
 
 type PostOrdersBadRequestError struct {
+	errorHeaders
+
 	OrderRejectTransaction *TransactionDefinition    `json:"orderRejectTransaction,omitempty"`
 	RelatedTransactionIDs  []TransactionIDDefinition `json:"relatedTransactionIDs,omitempty"`
 	LastTransactionID      TransactionIDDefinition   `json:"lastTransactionID,omitempty"`
@@ -184,6 +304,8 @@ func (r *PostOrdersBadRequestError) Error() string {
 }
 
 type PostOrdersNotFoundError struct {
+	errorHeaders
+
 	OrderRejectTransaction *TransactionDefinition    `json:"orderRejectTransaction,omitempty"`
 	RelatedTransactionIDs  []TransactionIDDefinition `json:"relatedTransactionIDs,omitempty"`
 	LastTransactionID      TransactionIDDefinition   `json:"lastTransactionID,omitempty"`
@@ -197,6 +319,8 @@ func (r *PostOrdersNotFoundError) Error() string {
 }
 
 type PutOrderSpecifierBadRequestError struct {
+	errorHeaders
+
 	OrderRejectTransaction *TransactionDefinition    `json:"orderRejectTransaction"`
 	RelatedTransactionIDs  []TransactionIDDefinition `json:"relatedTransactionIDs"`
 	LastTransactionID      TransactionIDDefinition   `json:"lastTransactionID"`
@@ -210,6 +334,8 @@ func (r *PutOrderSpecifierBadRequestError) Error() string {
 }
 
 type PutOrderSpecifierNotFoundError struct {
+	errorHeaders
+
 	OrderCancelRejectTransaction *TransactionDefinition    `json:"orderCancelRejectTransaction"`
 	RelatedTransactionIDs        []TransactionIDDefinition `json:"relatedTransactionIDs"`
 	LastTransactionID            TransactionIDDefinition   `json:"lastTransactionID"`
@@ -223,6 +349,8 @@ func (r *PutOrderSpecifierNotFoundError) Error() string {
 }
 
 type PutOrderSpecifierCancelNotFoundError struct {
+	errorHeaders
+
 	OrderCancelRejectTransaction *TransactionDefinition    `json:"orderCancelRejectTransaction,omitempty"`
 	RelatedTransactionIDs        []TransactionIDDefinition `json:"relatedTransactionIDs,omitempty"`
 	LastTransactionID            TransactionIDDefinition   `json:"lastTransactionID,omitempty"`
@@ -236,6 +364,8 @@ func (r *PutOrderSpecifierCancelNotFoundError) Error() string {
 }
 
 type PutOrderSpecifierClientExtensionsBadRequestError struct {
+	errorHeaders
+
 	OrderClientExtensionsModifyRejectTransaction *TransactionDefinition    `json:"orderClientExtensionsModifyRejectTransaction,omitempty"`
 	LastTransactionID                            TransactionIDDefinition   `json:"lastTransactionID,omitempty"`
 	RelatedTransactionIDs                        []TransactionIDDefinition `json:"relatedTransactionIDs,omitempty"`
@@ -249,6 +379,8 @@ func (r *PutOrderSpecifierClientExtensionsBadRequestError) Error() string {
 }
 
 type PutOrderSpecifierClientExtensionsNotFoundError struct {
+	errorHeaders
+
 	OrderClientExtensionsModifyRejectTransaction *TransactionDefinition    `json:"orderClientExtensionsModifyRejectTransaction,omitempty"`
 	LastTransactionID                            TransactionIDDefinition   `json:"lastTransactionID,omitempty"`
 	RelatedTransactionIDs                        []TransactionIDDefinition `json:"relatedTransactionIDs,omitempty"`
@@ -265,10 +397,14 @@ func (r *PutOrderSpecifierClientExtensionsNotFoundError) Error() string {
 
 // POST /v3/accounts/{accountID}/orders
 func (r *ReceiverOrders) Post(ctx context.Context, params *PostOrdersParams) (*PostOrdersSchema, error) {
+	if err := r.Connection.checkOrderPolicy(ctx, r.AccountID, "", params.Body.Order); err != nil {
+		return nil, err
+	}
+
 	childCtx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	resp, err := r.Connection.request(
+	data, err := r.Connection.request(
 		childCtx,
 		&requestParams{
 			method:   "POST",
@@ -277,26 +413,20 @@ func (r *ReceiverOrders) Post(ctx context.Context, params *PostOrdersParams) (*P
 				{key: "Accept-Datetime-Format", value: "RFC3339"},
 			},
 			body: params.Body,
+			simulate: func() (int, interface{}) {
+				return 201, &PostOrdersSchema{
+					OrderCreateTransaction: dryRunOrderTransaction(r.AccountID, params.Body.Order),
+				}
+			},
+			results: map[int]interface{}{
+				201: new(PostOrdersSchema),
+				400: new(PostOrdersBadRequestError),
+				404: new(PostOrdersNotFoundError),
+			},
 		},
 	)
 	if err != nil {
-		return nil, errors.Errorf("Post orders canceled: %v", err)
-	}
-	defer resp.Body.Close()
-
-	var data interface{}
-	switch resp.StatusCode {
-	case 201:
-		data = new(PostOrdersSchema)
-	case 400:
-		data = new(PostOrdersBadRequestError)
-	case 404:
-		data = new(PostOrdersNotFoundError)
-	}
-
-	data, err = parseResponse(resp, data, r.Connection.Strict)
-	if err != nil {
-		return nil, errors.Errorf("Post orders failed: %v", err)
+		return nil, errors.Wrap(err, "Post orders failed")
 	}
 	return data.(*PostOrdersSchema), nil
 }
@@ -306,7 +436,7 @@ func (r *ReceiverOrders) Get(ctx context.Context, params *GetOrdersParams) (*Get
 	childCtx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	resp, err := r.Connection.request(
+	data, err := r.Connection.request(
 		childCtx,
 		&requestParams{
 			method:   "GET",
@@ -339,22 +469,13 @@ func (r *ReceiverOrders) Get(ctx context.Context, params *GetOrdersParams) (*Get
 
 				return q
 			}(),
+			results: map[int]interface{}{
+				200: new(GetOrdersSchema),
+			},
 		},
 	)
 	if err != nil {
-		return nil, errors.Errorf("Get orders canceled: %v", err)
-	}
-	defer resp.Body.Close()
-
-	var data interface{}
-	switch resp.StatusCode {
-	case 200:
-		data = new(GetOrdersSchema)
-	}
-
-	data, err = parseResponse(resp, data, r.Connection.Strict)
-	if err != nil {
-		return nil, errors.Errorf("Get orders failed: %v", err)
+		return nil, errors.Wrap(err, "Get orders failed")
 	}
 	return data.(*GetOrdersSchema), nil
 }
@@ -364,7 +485,7 @@ func (r *ReceiverPendingOrders) Get(ctx context.Context) (*GetPendingOrdersSchem
 	childCtx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	resp, err := r.Connection.request(
+	data, err := r.Connection.request(
 		childCtx,
 		&requestParams{
 			method:   "GET",
@@ -372,22 +493,13 @@ func (r *ReceiverPendingOrders) Get(ctx context.Context) (*GetPendingOrdersSchem
 			headers: []header{
 				{key: "Accept-Datetime-Format", value: "RFC3339"},
 			},
+			results: map[int]interface{}{
+				200: new(GetPendingOrdersSchema),
+			},
 		},
 	)
 	if err != nil {
-		return nil, errors.Errorf("Get pending orders canceled: %v", err)
-	}
-	defer resp.Body.Close()
-
-	var data interface{}
-	switch resp.StatusCode {
-	case 200:
-		data = new(GetPendingOrdersSchema)
-	}
-
-	data, err = parseResponse(resp, data, r.Connection.Strict)
-	if err != nil {
-		return nil, errors.Errorf("Get pending orders failed: %v", err)
+		return nil, errors.Wrap(err, "Get pending orders failed")
 	}
 	return data.(*GetPendingOrdersSchema), nil
 }
@@ -397,7 +509,7 @@ func (r *ReceiverOrderSpecifier) Get(ctx context.Context) (*GetOrderSpecifierSch
 	childCtx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	resp, err := r.Connection.request(
+	data, err := r.Connection.request(
 		childCtx,
 		&requestParams{
 			method:   "GET",
@@ -405,32 +517,27 @@ func (r *ReceiverOrderSpecifier) Get(ctx context.Context) (*GetOrderSpecifierSch
 			headers: []header{
 				{key: "Accept-Datetime-Format", value: "RFC3339"},
 			},
+			results: map[int]interface{}{
+				200: new(GetOrderSpecifierSchema),
+			},
 		},
 	)
 	if err != nil {
-		return nil, errors.Errorf("Get order specifier canceled: %v", err)
-	}
-	defer resp.Body.Close()
-
-	var data interface{}
-	switch resp.StatusCode {
-	case 200:
-		data = new(GetOrderSpecifierSchema)
-	}
-
-	data, err = parseResponse(resp, data, r.Connection.Strict)
-	if err != nil {
-		return nil, errors.Errorf("Get order specifier failed: %v", err)
+		return nil, errors.Wrap(err, "Get order specifier failed")
 	}
 	return data.(*GetOrderSpecifierSchema), nil
 }
 
 // PUT /v3/accounts/{accountID}/orders/{orderSpecifier}
 func (r *ReceiverOrderSpecifier) Put(ctx context.Context, params *PutOrderSpecifierParams) (*PutOrderSpecifierSchema, error) {
+	if err := r.Connection.checkOrderPolicy(ctx, r.AccountID, r.OrderSpecifier, params.Body.Order); err != nil {
+		return nil, err
+	}
+
 	childCtx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	resp, err := r.Connection.request(
+	data, err := r.Connection.request(
 		childCtx,
 		&requestParams{
 			method:   "PUT",
@@ -439,26 +546,26 @@ func (r *ReceiverOrderSpecifier) Put(ctx context.Context, params *PutOrderSpecif
 				{key: "Accept-Datetime-Format", value: "RFC3339"},
 			},
 			body: params.Body,
+			simulate: func() (int, interface{}) {
+				cancelTx := dryRunTransaction(r.AccountID, OrderCancelTransaction, "CLIENT_REQUEST_REPLACED")
+				cancelTx.OrderID = r.OrderSpecifier
+				createTx := dryRunOrderTransaction(r.AccountID, params.Body.Order)
+				createTx.Reason = "REPLACEMENT"
+				createTx.ReplacesOrderID = r.OrderSpecifier
+				return 201, &PutOrderSpecifierSchema{
+					OrderCancelTransaction: cancelTx,
+					OrderCreateTransaction: createTx,
+				}
+			},
+			results: map[int]interface{}{
+				201: new(PutOrderSpecifierSchema),
+				400: new(PutOrderSpecifierBadRequestError),
+				404: new(PutOrderSpecifierNotFoundError),
+			},
 		},
 	)
 	if err != nil {
-		return nil, errors.Errorf("Put order specifier canceled: %v", err)
-	}
-	defer resp.Body.Close()
-
-	var data interface{}
-	switch resp.StatusCode {
-	case 201:
-		data = new(PutOrderSpecifierSchema)
-	case 400:
-		data = new(PutOrderSpecifierBadRequestError)
-	case 404:
-		data = new(PutOrderSpecifierNotFoundError)
-	}
-
-	data, err = parseResponse(resp, data, r.Connection.Strict)
-	if err != nil {
-		return nil, errors.Errorf("Put order specifier failed: %v", err)
+		return nil, errors.Wrap(err, "Put order specifier failed")
 	}
 	return data.(*PutOrderSpecifierSchema), nil
 }
@@ -468,7 +575,7 @@ func (r *ReceiverOrderSpecifierCancel) Put(ctx context.Context) (*PutOrderSpecif
 	childCtx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	resp, err := r.Connection.request(
+	data, err := r.Connection.request(
 		childCtx,
 		&requestParams{
 			method:   "PUT",
@@ -476,24 +583,19 @@ func (r *ReceiverOrderSpecifierCancel) Put(ctx context.Context) (*PutOrderSpecif
 			headers: []header{
 				{key: "Accept-Datetime-Format", value: "RFC3339"},
 			},
+			simulate: func() (int, interface{}) {
+				tx := dryRunTransaction(r.AccountID, OrderCancelTransaction, "CLIENT_REQUEST")
+				tx.OrderID = r.OrderSpecifier
+				return 200, &PutOrderSpecifierCancelSchema{OrderCancelTransaction: tx}
+			},
+			results: map[int]interface{}{
+				200: new(PutOrderSpecifierCancelSchema),
+				404: new(PutOrderSpecifierCancelNotFoundError),
+			},
 		},
 	)
 	if err != nil {
-		return nil, errors.Errorf("Put order specifier cancel canceled: %v", err)
-	}
-	defer resp.Body.Close()
-
-	var data interface{}
-	switch resp.StatusCode {
-	case 200:
-		data = new(PutOrderSpecifierCancelSchema)
-	case 404:
-		data = new(PutOrderSpecifierCancelNotFoundError)
-	}
-
-	data, err = parseResponse(resp, data, r.Connection.Strict)
-	if err != nil {
-		return nil, errors.Errorf("Put order specifier cancel failed: %v", err)
+		return nil, errors.Wrap(err, "Put order specifier cancel failed")
 	}
 	return data.(*PutOrderSpecifierCancelSchema), nil
 }
@@ -503,7 +605,7 @@ func (r *ReceiverOrderSpecifierClientExtensions) Put(ctx context.Context, params
 	childCtx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	resp, err := r.Connection.request(
+	data, err := r.Connection.request(
 		childCtx,
 		&requestParams{
 			method:   "PUT",
@@ -512,26 +614,22 @@ func (r *ReceiverOrderSpecifierClientExtensions) Put(ctx context.Context, params
 				{key: "Accept-Datetime-Format", value: "RFC3339"},
 			},
 			body: params.Body,
+			simulate: func() (int, interface{}) {
+				tx := dryRunTransaction(r.AccountID, OrderClientExtensionsModifyTransaction, "")
+				tx.OrderID = r.OrderSpecifier
+				tx.ClientExtensionsModify = params.Body.ClientExtensions
+				tx.TradeClientExtensionsModify = params.Body.TradeClientExtensions
+				return 200, &PutOrderSpecifierClientExtensionsSchema{OrderClientExtensionsModifyTransaction: tx}
+			},
+			results: map[int]interface{}{
+				200: new(PutOrderSpecifierClientExtensionsSchema),
+				400: new(PutOrderSpecifierClientExtensionsBadRequestError),
+				404: new(PutOrderSpecifierClientExtensionsNotFoundError),
+			},
 		},
 	)
 	if err != nil {
-		return nil, errors.Errorf("Put order specifier client extensions canceled: %v", err)
-	}
-	defer resp.Body.Close()
-
-	var data interface{}
-	switch resp.StatusCode {
-	case 200:
-		data = new(PutOrderSpecifierClientExtensionsSchema)
-	case 400:
-		data = new(PutOrderSpecifierClientExtensionsBadRequestError)
-	case 404:
-		data = new(PutOrderSpecifierClientExtensionsNotFoundError)
-	}
-
-	data, err = parseResponse(resp, data, r.Connection.Strict)
-	if err != nil {
-		return nil, errors.Errorf("Put order specifier client extensions failed: %v", err)
+		return nil, errors.Wrap(err, "Put order specifier client extensions failed")
 	}
 	return data.(*PutOrderSpecifierClientExtensionsSchema), nil
 }
@@ -2,6 +2,7 @@ package oanda
 
 import (
 	"context"
+	"net/http"
 
 	"github.com/pkg/errors"
 )
@@ -90,24 +91,86 @@ type PutPositionsInstrumentCloseParams struct {
 	Body *PutPositionsInstrumentCloseBodyParams
 }
 
+/* Headers */
+
+type GetPositionsHeaders struct {
+	RequestID string
+}
+
+func (s *GetPositionsSchema) setHeaders(resp *http.Response) error {
+	s.Headers = new(GetPositionsHeaders)
+	if h, err := copyHeader(resp, "Requestid"); err == nil {
+		s.Headers.RequestID = h[0]
+	} else {
+		return errors.Errorf("Parse headers failed: %v", err)
+	}
+	return nil
+}
+
+type GetOpenPositionsHeaders struct {
+	RequestID string
+}
+
+func (s *GetOpenPositionsSchema) setHeaders(resp *http.Response) error {
+	s.Headers = new(GetOpenPositionsHeaders)
+	if h, err := copyHeader(resp, "Requestid"); err == nil {
+		s.Headers.RequestID = h[0]
+	} else {
+		return errors.Errorf("Parse headers failed: %v", err)
+	}
+	return nil
+}
+
+type GetPositionsInstrumentHeaders struct {
+	RequestID string
+}
+
+func (s *GetPositionsInstrumentSchema) setHeaders(resp *http.Response) error {
+	s.Headers = new(GetPositionsInstrumentHeaders)
+	if h, err := copyHeader(resp, "Requestid"); err == nil {
+		s.Headers.RequestID = h[0]
+	} else {
+		return errors.Errorf("Parse headers failed: %v", err)
+	}
+	return nil
+}
+
+type PutPositionsInstrumentCloseHeaders struct {
+	RequestID string
+}
+
+func (s *PutPositionsInstrumentCloseSchema) setHeaders(resp *http.Response) error {
+	s.Headers = new(PutPositionsInstrumentCloseHeaders)
+	if h, err := copyHeader(resp, "Requestid"); err == nil {
+		s.Headers.RequestID = h[0]
+	} else {
+		return errors.Errorf("Parse headers failed: %v", err)
+	}
+	return nil
+}
+
 /* Schemas */
 
 type GetPositionsSchema struct {
+	Headers           *GetPositionsHeaders
 	Positions         []*PositionDefinition   `json:"positions,omitempty"`
 	LastTransactionID TransactionIDDefinition `json:"lastTransactionID,omitempty"`
 }
 
 type GetOpenPositionsSchema struct {
+	Headers           *GetOpenPositionsHeaders
 	Positions         []*PositionDefinition   `json:"positions,omitempty"`
 	LastTransactionID TransactionIDDefinition `json:"lastTransactionID,omitempty"`
 }
 
 type GetPositionsInstrumentSchema struct {
+	Headers           *GetPositionsInstrumentHeaders
 	Position          *PositionDefinition     `json:"position,omitempty"`
 	LastTransactionID TransactionIDDefinition `json:"lastTransactionID,omitempty"`
 }
 
 type PutPositionsInstrumentCloseSchema struct {
+	Headers                     *PutPositionsInstrumentCloseHeaders
 	LongOrderCreateTransaction  *TransactionDefinition    `json:"longOrderCreateTransaction,omitempty"`
 	LongOrderFillTransaction    *TransactionDefinition    `json:"longOrderFillTransaction,omitempty"`
 	LongOrderCancelTransaction  *TransactionDefinition    `json:"longOrderCancelTransaction,omitempty"`
@@ -143,6 +206,8 @@ func (p *PutPositionsInstrumentCloseSchema) ToTradeResult() *TradeResult {
 /* Errors */
 
 type PutPositionsInstrumentCloseBadRequestError struct {
+	errorHeaders
+
 	LongOrderRejectTransaction  *TransactionDefinition    `json:"longOrderRejectTransaction,omitempty"`
 	ShortOrderRejectTransaction *TransactionDefinition    `json:"shortOrderRejectTransaction,omitempty"`
 	RelatedTransactionIDs       []TransactionIDDefinition `json:"relatedTransactionIDs,omitempty"`
@@ -157,6 +222,8 @@ func (r *PutPositionsInstrumentCloseBadRequestError) Error() string {
 }
 
 type PutPositionsInstrumentCloseNotFoundError struct {
+	errorHeaders
+
 	LongOrderRejectTransaction  *TransactionDefinition    `json:"longOrderRejectTransaction,omitempty"`
 	ShortOrderRejectTransaction *TransactionDefinition    `json:"shortOrderRejectTransaction,omitempty"`
 	RelatedTransactionIDs       []TransactionIDDefinition `json:"relatedTransactionIDs,omitempty"`
@@ -177,7 +244,7 @@ func (r *ReceiverPositions) Get(ctx context.Context) (*GetPositionsSchema, error
 	childCtx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	resp, err := r.Connection.request(
+	data, err := r.Connection.request(
 		childCtx,
 		&requestParams{
 			method:   "GET",
@@ -185,22 +252,13 @@ func (r *ReceiverPositions) Get(ctx context.Context) (*GetPositionsSchema, error
 			headers: []header{
 				{key: "Accept-Datetime-Format", value: "RFC3339"},
 			},
+			results: map[int]interface{}{
+				200: new(GetPositionsSchema),
+			},
 		},
 	)
 	if err != nil {
-		return nil, errors.Errorf("Get positions canceled: %v", err)
-	}
-	defer resp.Body.Close()
-
-	var data interface{}
-	switch resp.StatusCode {
-	case 200:
-		data = new(GetPositionsSchema)
-	}
-
-	data, err = parseResponse(resp, data, r.Connection.Strict)
-	if err != nil {
-		return nil, errors.Errorf("Get positions failed: %v", err)
+		return nil, errors.Wrap(err, "Get positions failed")
 	}
 	return data.(*GetPositionsSchema), nil
 }
@@ -210,7 +268,7 @@ func (r *ReceiverOpenPositions) Get(ctx context.Context) (*GetOpenPositionsSchem
 	childCtx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	resp, err := r.Connection.request(
+	data, err := r.Connection.request(
 		childCtx,
 		&requestParams{
 			method:   "GET",
@@ -218,22 +276,13 @@ func (r *ReceiverOpenPositions) Get(ctx context.Context) (*GetOpenPositionsSchem
 			headers: []header{
 				{key: "Accept-Datetime-Format", value: "RFC3339"},
 			},
+			results: map[int]interface{}{
+				200: new(GetOpenPositionsSchema),
+			},
 		},
 	)
 	if err != nil {
-		return nil, errors.Errorf("Get open positions canceled: %v", err)
-	}
-	defer resp.Body.Close()
-
-	var data interface{}
-	switch resp.StatusCode {
-	case 200:
-		data = new(GetOpenPositionsSchema)
-	}
-
-	data, err = parseResponse(resp, data, r.Connection.Strict)
-	if err != nil {
-		return nil, errors.Errorf("Get open positions failed: %v", err)
+		return nil, errors.Wrap(err, "Get open positions failed")
 	}
 	return data.(*GetOpenPositionsSchema), nil
 }
@@ -243,7 +292,7 @@ func (r *ReceiverPositionsInstrument) Get(ctx context.Context) (*GetPositionsIns
 	childCtx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	resp, err := r.Connection.request(
+	data, err := r.Connection.request(
 		childCtx,
 		&requestParams{
 			method:   "GET",
@@ -251,22 +300,13 @@ func (r *ReceiverPositionsInstrument) Get(ctx context.Context) (*GetPositionsIns
 			headers: []header{
 				{key: "Accept-Datetime-Format", value: "RFC3339"},
 			},
+			results: map[int]interface{}{
+				200: new(GetPositionsInstrumentSchema),
+			},
 		},
 	)
 	if err != nil {
-		return nil, errors.Errorf("Get positions instrument canceled: %v", err)
-	}
-	defer resp.Body.Close()
-
-	var data interface{}
-	switch resp.StatusCode {
-	case 200:
-		data = new(GetPositionsInstrumentSchema)
-	}
-
-	data, err = parseResponse(resp, data, r.Connection.Strict)
-	if err != nil {
-		return nil, errors.Errorf("Get positions instrument failed: %v", err)
+		return nil, errors.Wrap(err, "Get positions instrument failed")
 	}
 	return data.(*GetPositionsInstrumentSchema), nil
 }
@@ -276,7 +316,7 @@ func (r *ReceiverPositionsInstrumentClose) Put(ctx context.Context, params *PutP
 	childCtx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	resp, err := r.Connection.request(
+	data, err := r.Connection.request(
 		childCtx,
 		&requestParams{
 			method:   "PUT",
@@ -285,26 +325,41 @@ func (r *ReceiverPositionsInstrumentClose) Put(ctx context.Context, params *PutP
 				{key: "Accept-Datetime-Format", value: "RFC3339"},
 			},
 			body: params.Body,
+			simulate: func() (int, interface{}) {
+				longUnits, shortUnits := "ALL", "ALL"
+				if params.Body != nil {
+					if params.Body.LongUnits != "" {
+						longUnits = params.Body.LongUnits
+					}
+					if params.Body.ShortUnits != "" {
+						shortUnits = params.Body.ShortUnits
+					}
+				}
+
+				data := new(PutPositionsInstrumentCloseSchema)
+				if longUnits != "NONE" {
+					tx := dryRunTransaction(r.AccountID, MarketOrderTransaction, "POSITION_CLOSEOUT")
+					tx.Instrument = r.Instrument
+					tx.LongPositionCloseout = &MarketOrderPositionCloseoutDefinition{Instrument: r.Instrument, Units: longUnits}
+					data.LongOrderCreateTransaction = tx
+				}
+				if shortUnits != "NONE" {
+					tx := dryRunTransaction(r.AccountID, MarketOrderTransaction, "POSITION_CLOSEOUT")
+					tx.Instrument = r.Instrument
+					tx.ShortPositionCloseout = &MarketOrderPositionCloseoutDefinition{Instrument: r.Instrument, Units: shortUnits}
+					data.ShortOrderCreateTransaction = tx
+				}
+				return 200, data
+			},
+			results: map[int]interface{}{
+				200: new(PutPositionsInstrumentCloseSchema),
+				400: new(PutPositionsInstrumentCloseBadRequestError),
+				404: new(PutPositionsInstrumentCloseNotFoundError),
+			},
 		},
 	)
 	if err != nil {
-		return nil, errors.Errorf("Put positions instrument close canceled: %v", err)
-	}
-	defer resp.Body.Close()
-
-	var data interface{}
-	switch resp.StatusCode {
-	case 200:
-		data = new(PutPositionsInstrumentCloseSchema)
-	case 400:
-		data = new(PutPositionsInstrumentCloseBadRequestError)
-	case 404:
-		data = new(PutPositionsInstrumentCloseNotFoundError)
-	}
-
-	data, err = parseResponse(resp, data, r.Connection.Strict)
-	if err != nil {
-		return nil, errors.Errorf("Put positions instrument close failed: %v", err)
+		return nil, errors.Wrap(err, "Put positions instrument close failed")
 	}
 	return data.(*PutPositionsInstrumentCloseSchema), nil
 }
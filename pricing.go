@@ -1,9 +1,9 @@
 package oanda
 
 import (
-	"bufio"
 	"context"
 	"encoding/json"
+	"net/http"
 	"strconv"
 	"strings"
 	"sync"
@@ -50,11 +50,29 @@ type GetPricingParams struct {
 type GetPricingStreamParams struct {
 	BufferSize  int
 	Instruments []string
+	Snapshot    *bool
+}
+
+/* Headers */
+
+type GetPricingHeaders struct {
+	RequestID string
+}
+
+func (s *GetPricingSchema) setHeaders(resp *http.Response) error {
+	s.Headers = new(GetPricingHeaders)
+	if h, err := copyHeader(resp, "Requestid"); err == nil {
+		s.Headers.RequestID = h[0]
+	} else {
+		return errors.Errorf("Parse headers failed: %v", err)
+	}
+	return nil
 }
 
 /* Schemas */
 
 type GetPricingSchema struct {
+	Headers *GetPricingHeaders
 	// The list of Price objects requested.
 	Prices []*PriceDefinition `json:"prices,omitempty"`
 
@@ -84,7 +102,7 @@ func (r *ReceiverPricing) Get(ctx context.Context, params *GetPricingParams) (*G
 	childCtx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	resp, err := r.Connection.request(
+	data, err := r.Connection.request(
 		childCtx,
 		&requestParams{
 			method:   "GET",
@@ -106,22 +124,13 @@ func (r *ReceiverPricing) Get(ctx context.Context, params *GetPricingParams) (*G
 				}
 				return q
 			}(),
+			results: map[int]interface{}{
+				200: new(GetPricingSchema),
+			},
 		},
 	)
 	if err != nil {
-		return nil, errors.Errorf("Get pricing canceled: %v", err)
-	}
-	defer resp.Body.Close()
-
-	var data interface{}
-	switch resp.StatusCode {
-	case 200:
-		data = new(GetPricingSchema)
-	}
-
-	data, err = parseResponse(resp, data, r.Connection.Strict)
-	if err != nil {
-		return nil, errors.Errorf("Get pricing failed: %v", err)
+		return nil, errors.Wrap(err, "Get pricing failed")
 	}
 	return data.(*GetPricingSchema), nil
 }
@@ -130,7 +139,7 @@ func (r *ReceiverPricing) Get(ctx context.Context, params *GetPricingParams) (*G
 func (r *ReceiverPricingStream) Get(ctx context.Context, params *GetPricingStreamParams) (*PriceChannels, error) {
 	childCtx, cancel := context.WithCancel(ctx)
 
-	resp, err := r.Connection.stream(
+	resp, observer, err := r.Connection.stream(
 		childCtx,
 		&requestParams{
 			method:   "GET",
@@ -138,9 +147,14 @@ func (r *ReceiverPricingStream) Get(ctx context.Context, params *GetPricingStrea
 			headers: []header{
 				{key: "Accept-Datetime-Format", value: "RFC3339"},
 			},
-			queries: []query{
-				{key: "instruments", value: strings.Join(params.Instruments, ",")},
-			},
+			queries: func() []query {
+				q := make([]query, 0, 2)
+				q = append(q, query{key: "instruments", value: strings.Join(params.Instruments, ",")})
+				if params.Snapshot != nil {
+					q = append(q, query{key: "snapshot", value: strconv.FormatBool(*params.Snapshot)})
+				}
+				return q
+			}(),
 		},
 	)
 	if err != nil {
@@ -153,12 +167,13 @@ func (r *ReceiverPricingStream) Get(ctx context.Context, params *GetPricingStrea
 			resp.Body.Close()
 			cancel()
 		}()
-		var err interface{}
-		_, err = parseResponse(resp, err, r.Connection.Strict)
-		return nil, errors.Errorf("Get pricing stream failed: %v", err)
+		var err error
+		_, err = parseResponse(resp, nil, r.Connection.compare(childCtx, observer.req, resp))
+		return nil, errors.Wrap(err, "Get pricing stream failed")
 	}
 
 	closeWait := new(sync.WaitGroup)
+	closeWait.Add(3)
 
 	priceCh := make(chan *PriceDefinition, params.BufferSize)
 	errorCh := make(chan error, 3)
@@ -172,7 +187,6 @@ func (r *ReceiverPricingStream) Get(ctx context.Context, params *GetPricingStrea
 			cancel()
 			closeWait.Done()
 		}()
-		closeWait.Add(1)
 		<-childCtx.Done()
 	}()
 
@@ -184,28 +198,25 @@ func (r *ReceiverPricingStream) Get(ctx context.Context, params *GetPricingStrea
 			cancel()
 			closeWait.Done()
 		}()
-		closeWait.Add(1)
 
-		reader := bufio.NewReader(resp.Body)
+		decoder := json.NewDecoder(resp.Body)
 		for {
-			line, err := reader.ReadBytes('\n')
-			if err != nil {
+			data := new(PriceDefinition)
+			if err := decoder.Decode(data); err != nil {
 				select {
 				case <-childCtx.Done():
 				default:
-					errorCh <- errors.Errorf("Read response stream failed: %v", err)
+					errorCh <- observer.fail(decodeFailed(err, "response stream"))
 				}
 				return
 			}
+			observer.message(data)
 
-			data := new(PriceDefinition)
-			err = json.Unmarshal(line, data)
-			if err != nil {
-				errorCh <- errors.Errorf("Unmarshal response stream failed: %v", err)
+			select {
+			case readerCh <- data:
+			case <-childCtx.Done():
 				return
 			}
-
-			readerCh <- data
 		}
 	}()
 
@@ -215,9 +226,9 @@ func (r *ReceiverPricingStream) Get(ctx context.Context, params *GetPricingStrea
 		defer func() {
 			close(priceCh)
 			cancel()
+			observer.close()
 			closeWait.Done()
 		}()
-		closeWait.Add(1)
 
 		timeout := time.NewTimer(0)
 		received := true
@@ -226,14 +237,22 @@ func (r *ReceiverPricingStream) Get(ctx context.Context, params *GetPricingStrea
 			select {
 			case <-childCtx.Done():
 				return
-			case data := <-readerCh:
+			case data, ok := <-readerCh:
+				if !ok {
+					return
+				}
 				received = true
-				priceCh <- data
+				r.Connection.setStreamBuffer(StreamPricing, len(priceCh), cap(priceCh))
+				select {
+				case priceCh <- data:
+				case <-childCtx.Done():
+					return
+				}
 			case <-timeout.C:
 				timeout.Reset(r.Connection.Timeout)
 				if !received {
 					var err error = &StreamHeartbeatBroken{ErrorMessage: "Heartbeat was broken"}
-					errorCh <- errors.Errorf("Get pricing stream heartbeat was broken: %v", err)
+					errorCh <- observer.fail(errors.Errorf("Get pricing stream heartbeat was broken: %v", err))
 					return
 				}
 				received = false
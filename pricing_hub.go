@@ -0,0 +1,269 @@
+package oanda
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+/* Params */
+
+type SlowConsumerPolicy int
+
+const (
+	// Discard the oldest buffered price to make room for the newest one.
+	DropOldest SlowConsumerPolicy = iota + 1
+	// Wait until the subscriber has room. This stalls every subscriber of the hub.
+	Block
+	// Close the subscription with a SlowConsumerError.
+	Disconnect
+)
+
+type PriceSubscriptionParams struct {
+	BufferSize  int
+	Instruments []string // empty means every instrument of the hub
+	Policy      SlowConsumerPolicy
+}
+
+/* Streams */
+
+// PriceHub shares a single pricing stream of an account between any number of
+// in-process subscribers and remembers the latest price of every instrument.
+type PriceHub struct {
+	channels    *PriceChannels
+	instruments map[InstrumentNameDefinition]struct{}
+
+	mu          sync.RWMutex
+	latest      map[InstrumentNameDefinition]*PriceDefinition
+	subscribers map[*PriceSubscription]struct{}
+	closed      bool
+	lastError   error
+
+	done      chan struct{}
+	closeOnce sync.Once
+	closeWait *sync.WaitGroup
+}
+
+type PriceSubscription struct {
+	PriceCh <-chan *PriceDefinition
+
+	hub         *PriceHub
+	priceCh     chan *PriceDefinition
+	instruments map[InstrumentNameDefinition]struct{}
+	policy      SlowConsumerPolicy
+
+	// sendMu serializes deliveries with closing priceCh.
+	sendMu    sync.Mutex
+	done      chan struct{}
+	closeOnce sync.Once
+
+	errMu     sync.Mutex
+	lastError error
+}
+
+/* API */
+
+// Hub opens one pricing stream for params.Instruments and fans it out to the
+// subscribers registered with PriceHub.Subscribe.
+func (r *ReceiverPricingStream) Hub(ctx context.Context, params *GetPricingStreamParams) (*PriceHub, error) {
+	chs, err := r.Get(ctx, params)
+	if err != nil {
+		return nil, errors.Errorf("Open price hub failed: %v", err)
+	}
+
+	return newPriceHub(chs, params.Instruments), nil
+}
+
+func newPriceHub(chs *PriceChannels, instruments []string) *PriceHub {
+	h := &PriceHub{
+		channels:    chs,
+		instruments: make(map[InstrumentNameDefinition]struct{}, len(instruments)),
+		latest:      make(map[InstrumentNameDefinition]*PriceDefinition, len(instruments)),
+		subscribers: make(map[*PriceSubscription]struct{}),
+		done:        make(chan struct{}),
+		closeWait:   new(sync.WaitGroup),
+	}
+	for _, i := range instruments {
+		h.instruments[i] = struct{}{}
+	}
+
+	h.closeWait.Add(1)
+	go func() {
+		defer h.closeWait.Done()
+
+		for data := range chs.PriceCh {
+			if data.Type != "PRICE" {
+				continue
+			}
+
+			h.mu.Lock()
+			h.latest[data.Instrument] = data
+			subscribers := make([]*PriceSubscription, 0, len(h.subscribers))
+			for sub := range h.subscribers {
+				subscribers = append(subscribers, sub)
+			}
+			h.mu.Unlock()
+
+			for _, sub := range subscribers {
+				if !sub.deliver(data) {
+					sub.close(errors.Wrap(&SlowConsumerError{ErrorMessage: "Subscriber buffer is full"}, "Price subscription disconnected"))
+				}
+			}
+		}
+
+		h.shutdown(chs.Err())
+	}()
+
+	return h
+}
+
+// Subscribe registers a new subscriber for a subset of the hub's instruments.
+func (h *PriceHub) Subscribe(params *PriceSubscriptionParams) (*PriceSubscription, error) {
+	policy := params.Policy
+	if policy == 0 {
+		policy = DropOldest
+	}
+	bufferSize := params.BufferSize
+	if bufferSize < 1 {
+		bufferSize = 1
+	}
+
+	sub := &PriceSubscription{
+		hub:         h,
+		priceCh:     make(chan *PriceDefinition, bufferSize),
+		instruments: make(map[InstrumentNameDefinition]struct{}, len(params.Instruments)),
+		policy:      policy,
+		done:        make(chan struct{}),
+	}
+	sub.PriceCh = sub.priceCh
+
+	for _, i := range params.Instruments {
+		if _, ok := h.instruments[i]; !ok {
+			return nil, errors.Errorf("Instrument %s is not streamed by the price hub", i)
+		}
+		sub.instruments[i] = struct{}{}
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return nil, errors.Errorf("Price hub is closed: %v", h.lastError)
+	}
+	h.subscribers[sub] = struct{}{}
+
+	return sub, nil
+}
+
+// Latest returns the most recent price received for the instrument.
+func (h *PriceHub) Latest(instrument InstrumentNameDefinition) (*PriceDefinition, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	data, ok := h.latest[instrument]
+	return data, ok
+}
+
+/* Utils */
+
+func (h *PriceHub) Close() {
+	h.closeOnce.Do(func() { close(h.done) })
+	h.channels.Close()
+	h.closeWait.Wait()
+}
+
+func (h *PriceHub) Err() error {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.lastError
+}
+
+func (h *PriceHub) shutdown(err error) {
+	h.mu.Lock()
+	h.closed = true
+	h.lastError = err
+	subscribers := h.subscribers
+	h.subscribers = make(map[*PriceSubscription]struct{})
+	h.mu.Unlock()
+
+	for sub := range subscribers {
+		sub.close(err)
+	}
+}
+
+// deliver hands the price to the subscriber according to its policy. It
+// returns false if the subscriber has to be disconnected.
+func (s *PriceSubscription) deliver(data *PriceDefinition) bool {
+	if len(s.instruments) > 0 {
+		if _, ok := s.instruments[data.Instrument]; !ok {
+			return true
+		}
+	}
+
+	s.sendMu.Lock()
+	defer s.sendMu.Unlock()
+
+	select {
+	case <-s.done:
+		return true
+	default:
+	}
+
+	switch s.policy {
+	case Block:
+		select {
+		case s.priceCh <- data:
+		case <-s.done:
+		case <-s.hub.done:
+		}
+	case Disconnect:
+		select {
+		case s.priceCh <- data:
+		default:
+			return false
+		}
+	default:
+		for {
+			select {
+			case s.priceCh <- data:
+				return true
+			default:
+			}
+			select {
+			case <-s.priceCh:
+			default:
+			}
+		}
+	}
+
+	return true
+}
+
+// Close unregisters the subscription and closes PriceCh.
+func (s *PriceSubscription) Close() {
+	s.close(nil)
+}
+
+func (s *PriceSubscription) close(err error) {
+	s.closeOnce.Do(func() {
+		s.hub.mu.Lock()
+		delete(s.hub.subscribers, s)
+		s.hub.mu.Unlock()
+
+		s.errMu.Lock()
+		s.lastError = err
+		s.errMu.Unlock()
+
+		close(s.done)
+
+		s.sendMu.Lock()
+		close(s.priceCh)
+		s.sendMu.Unlock()
+	})
+}
+
+func (s *PriceSubscription) Err() error {
+	s.errMu.Lock()
+	defer s.errMu.Unlock()
+	return s.lastError
+}
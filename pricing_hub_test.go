@@ -0,0 +1,154 @@
+package oanda
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+// newFakePriceChannels returns PriceChannels fed by the returned channel
+// instead of an HTTP stream.
+func newFakePriceChannels() (*PriceChannels, chan<- *PriceDefinition) {
+	ctx, cancel := context.WithCancel(context.Background())
+	closeWait := new(sync.WaitGroup)
+	srcCh := make(chan *PriceDefinition)
+	priceCh := make(chan *PriceDefinition)
+
+	closeWait.Add(1)
+	go func() {
+		defer func() {
+			close(priceCh)
+			closeWait.Done()
+		}()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case data := <-srcCh:
+				select {
+				case priceCh <- data:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return &PriceChannels{
+		PriceCh:   priceCh,
+		errorCh:   make(chan error, 1),
+		close:     cancel,
+		closeWait: closeWait,
+	}, srcCh
+}
+
+func Test_PriceHub(t *testing.T) {
+	t.Run("FanOut", func(t *testing.T) {
+		chs, srcCh := newFakePriceChannels()
+		hub := newPriceHub(chs, []string{"EUR_USD", "USD_JPY"})
+		defer hub.Close()
+
+		all, err := hub.Subscribe(&PriceSubscriptionParams{BufferSize: 10})
+		if err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+		jpy, err := hub.Subscribe(&PriceSubscriptionParams{BufferSize: 10, Instruments: []string{"USD_JPY"}})
+		if err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+
+		srcCh <- &PriceDefinition{Type: "HEARTBEAT"}
+		srcCh <- &PriceDefinition{Type: "PRICE", Instrument: "EUR_USD", Time: "1"}
+		srcCh <- &PriceDefinition{Type: "PRICE", Instrument: "USD_JPY", Time: "2"}
+
+		if data := <-all.PriceCh; data.Instrument != "EUR_USD" {
+			t.Fatalf("Got unexpected price.\n%#v", data)
+		}
+		if data := <-all.PriceCh; data.Instrument != "USD_JPY" {
+			t.Fatalf("Got unexpected price.\n%#v", data)
+		}
+		if data := <-jpy.PriceCh; data.Instrument != "USD_JPY" {
+			t.Fatalf("Got unexpected price.\n%#v", data)
+		}
+
+		if data, ok := hub.Latest("USD_JPY"); !ok || data.Time != "2" {
+			t.Fatalf("Got unexpected latest price.\n%#v", data)
+		}
+		if _, ok := hub.Latest("GBP_USD"); ok {
+			t.Fatal("Got latest price of an instrument that was never received.")
+		}
+	})
+
+	t.Run("UnknownInstrument", func(t *testing.T) {
+		chs, _ := newFakePriceChannels()
+		hub := newPriceHub(chs, []string{"EUR_USD"})
+		defer hub.Close()
+
+		if _, err := hub.Subscribe(&PriceSubscriptionParams{Instruments: []string{"USD_JPY"}}); err == nil {
+			t.Fatal("Subscribed to an instrument that is not streamed.")
+		}
+	})
+
+	t.Run("DropOldest", func(t *testing.T) {
+		chs, srcCh := newFakePriceChannels()
+		hub := newPriceHub(chs, []string{"EUR_USD"})
+		defer hub.Close()
+
+		sub, _ := hub.Subscribe(&PriceSubscriptionParams{BufferSize: 2, Policy: DropOldest})
+		for _, tm := range []string{"1", "2", "3"} {
+			srcCh <- &PriceDefinition{Type: "PRICE", Instrument: "EUR_USD", Time: tm}
+		}
+		flushFakePriceChannels(srcCh)
+
+		for _, expect := range []string{"2", "3"} {
+			if data := <-sub.PriceCh; data.Time != expect {
+				t.Fatalf("Got unexpected price.\nExpect: %s\nActual: %s", expect, data.Time)
+			}
+		}
+	})
+
+	t.Run("Disconnect", func(t *testing.T) {
+		chs, srcCh := newFakePriceChannels()
+		hub := newPriceHub(chs, []string{"EUR_USD"})
+		defer hub.Close()
+
+		sub, _ := hub.Subscribe(&PriceSubscriptionParams{BufferSize: 1, Policy: Disconnect})
+		srcCh <- &PriceDefinition{Type: "PRICE", Instrument: "EUR_USD", Time: "1"}
+		srcCh <- &PriceDefinition{Type: "PRICE", Instrument: "EUR_USD", Time: "2"}
+		flushFakePriceChannels(srcCh)
+
+		for range sub.PriceCh {
+		}
+		var slow *SlowConsumerError
+		if !errors.As(sub.Err(), &slow) {
+			t.Fatalf("Slow subscriber was not disconnected with a SlowConsumerError.\n%v", sub.Err())
+		}
+	})
+
+	t.Run("Close", func(t *testing.T) {
+		chs, srcCh := newFakePriceChannels()
+		hub := newPriceHub(chs, []string{"EUR_USD"})
+
+		sub, _ := hub.Subscribe(&PriceSubscriptionParams{BufferSize: 1, Policy: Block})
+		srcCh <- &PriceDefinition{Type: "PRICE", Instrument: "EUR_USD", Time: "1"}
+		srcCh <- &PriceDefinition{Type: "PRICE", Instrument: "EUR_USD", Time: "2"}
+
+		hub.Close()
+
+		for range sub.PriceCh {
+		}
+		if err := hub.Err(); err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+	})
+}
+
+// flushFakePriceChannels returns once every price sent before has been
+// handed to the subscribers. Heartbeats are not delivered, and the second one
+// is only accepted after the hub took the first.
+func flushFakePriceChannels(srcCh chan<- *PriceDefinition) {
+	srcCh <- &PriceDefinition{Type: "HEARTBEAT"}
+	srcCh <- &PriceDefinition{Type: "HEARTBEAT"}
+}
@@ -0,0 +1,257 @@
+package oanda
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+/* Streams */
+
+// ManagedPriceStream is a pricing stream whose instrument set can be changed
+// while it is running. Every change reconnects the upstream stream, PriceCh
+// stays open across reconnects.
+type ManagedPriceStream struct {
+	PriceCh <-chan *PriceDefinition
+
+	open   func(ctx context.Context, params *GetPricingStreamParams) (*PriceChannels, error)
+	params GetPricingStreamParams
+
+	mu          sync.Mutex
+	instruments []string
+	lastError   error
+	changeCh    chan struct{}
+
+	close     context.CancelFunc
+	closeWait *sync.WaitGroup
+}
+
+/* API */
+
+// Managed starts a pricing stream that can be resubscribed with
+// ManagedPriceStream.Subscribe and ManagedPriceStream.Unsubscribe. Snapshot
+// defaults to true so that every reconnect starts with the current prices.
+func (r *ReceiverPricingStream) Managed(ctx context.Context, params *GetPricingStreamParams) (*ManagedPriceStream, error) {
+	opened := false
+	open := func(ctx context.Context, params *GetPricingStreamParams) (*PriceChannels, error) {
+		if opened {
+			r.Connection.incStreamReconnect(StreamPricing)
+		}
+		opened = true
+		return r.Get(ctx, params)
+	}
+
+	m, err := newManagedPriceStream(ctx, open, params)
+	if err != nil {
+		return nil, errors.Errorf("Get managed pricing stream failed: %v", err)
+	}
+	return m, nil
+}
+
+func newManagedPriceStream(ctx context.Context, open func(context.Context, *GetPricingStreamParams) (*PriceChannels, error), params *GetPricingStreamParams) (*ManagedPriceStream, error) {
+	childCtx, cancel := context.WithCancel(ctx)
+
+	m := &ManagedPriceStream{
+		open:      open,
+		params:    *params,
+		changeCh:  make(chan struct{}, 1),
+		close:     cancel,
+		closeWait: new(sync.WaitGroup),
+	}
+	if m.params.Snapshot == nil {
+		m.params.Snapshot = Bool(true)
+	}
+	m.instruments = appendInstruments(nil, params.Instruments...)
+
+	// The first connection is made synchronously so that errors like an
+	// invalid token are returned to the caller.
+	var chs *PriceChannels
+	if len(m.instruments) > 0 {
+		var err error
+		if chs, err = m.connect(childCtx, m.instruments); err != nil {
+			cancel()
+			return nil, err
+		}
+	}
+
+	priceCh := make(chan *PriceDefinition, params.BufferSize)
+	m.PriceCh = priceCh
+
+	m.closeWait.Add(1)
+	go func() {
+		defer func() {
+			close(priceCh)
+			cancel()
+			m.closeWait.Done()
+		}()
+
+		for {
+			if chs == nil {
+				instruments := m.Instruments()
+				if len(instruments) == 0 {
+					select {
+					case <-childCtx.Done():
+						return
+					case <-m.changeCh:
+						continue
+					}
+				}
+
+				var err error
+				if chs, err = m.connect(childCtx, instruments); err != nil {
+					m.setErr(errors.Errorf("Reconnect managed pricing stream failed: %v", err))
+					return
+				}
+			}
+
+			if !m.forward(childCtx, chs, priceCh) {
+				return
+			}
+			chs = nil
+		}
+	}()
+
+	return m, nil
+}
+
+func (m *ManagedPriceStream) connect(ctx context.Context, instruments []string) (*PriceChannels, error) {
+	params := m.params
+	params.Instruments = instruments
+	return m.open(ctx, &params)
+}
+
+// forward passes prices from chs to priceCh until the instrument set changes.
+// It returns false when the managed stream has to stop.
+func (m *ManagedPriceStream) forward(ctx context.Context, chs *PriceChannels, priceCh chan<- *PriceDefinition) bool {
+	defer chs.Close()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-m.changeCh:
+			return true
+		case data, ok := <-chs.PriceCh:
+			if !ok {
+				select {
+				case <-ctx.Done():
+				default:
+					m.setErr(errors.Errorf("Managed pricing stream was broken: %v", chs.Err()))
+				}
+				return false
+			}
+
+			// Prices of unsubscribed instruments may still arrive until
+			// the stream has been reconnected.
+			if data.Type == "PRICE" && !m.subscribed(data.Instrument) {
+				continue
+			}
+
+			select {
+			case priceCh <- data:
+			case <-ctx.Done():
+				return false
+			}
+		}
+	}
+}
+
+// Subscribe adds instruments to the stream and reconnects if the set changed.
+func (m *ManagedPriceStream) Subscribe(instruments ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	n := len(m.instruments)
+	m.instruments = appendInstruments(m.instruments, instruments...)
+	if len(m.instruments) != n {
+		m.notifyChange()
+	}
+}
+
+// Unsubscribe removes instruments from the stream and reconnects if the set
+// changed.
+func (m *ManagedPriceStream) Unsubscribe(instruments ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	removed := make(map[string]struct{}, len(instruments))
+	for _, i := range instruments {
+		removed[i] = struct{}{}
+	}
+
+	kept := make([]string, 0, len(m.instruments))
+	for _, i := range m.instruments {
+		if _, ok := removed[i]; !ok {
+			kept = append(kept, i)
+		}
+	}
+	if len(kept) != len(m.instruments) {
+		m.instruments = kept
+		m.notifyChange()
+	}
+}
+
+// Instruments returns the instruments the stream is currently subscribed to.
+func (m *ManagedPriceStream) Instruments() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	instruments := make([]string, len(m.instruments))
+	copy(instruments, m.instruments)
+	return instruments
+}
+
+/* Utils */
+
+func (m *ManagedPriceStream) Close() {
+	m.close()
+	m.closeWait.Wait()
+}
+
+func (m *ManagedPriceStream) Err() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.lastError
+}
+
+func (m *ManagedPriceStream) setErr(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.lastError = err
+}
+
+func (m *ManagedPriceStream) subscribed(instrument InstrumentNameDefinition) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, i := range m.instruments {
+		if i == instrument {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *ManagedPriceStream) notifyChange() {
+	select {
+	case m.changeCh <- struct{}{}:
+	default:
+	}
+}
+
+func appendInstruments(dst []string, instruments ...string) []string {
+	for _, i := range instruments {
+		exists := false
+		for _, d := range dst {
+			if d == i {
+				exists = true
+				break
+			}
+		}
+		if !exists {
+			dst = append(dst, i)
+		}
+	}
+	return dst
+}
@@ -0,0 +1,67 @@
+package oanda
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func Test_ManagedPriceStream(t *testing.T) {
+	type connection struct {
+		params *GetPricingStreamParams
+		srcCh  chan<- *PriceDefinition
+	}
+	connectionCh := make(chan *connection, 10)
+
+	open := func(ctx context.Context, params *GetPricingStreamParams) (*PriceChannels, error) {
+		chs, srcCh := newFakePriceChannels()
+		connectionCh <- &connection{params: params, srcCh: srcCh}
+		return chs, nil
+	}
+
+	m, err := newManagedPriceStream(context.Background(), open, &GetPricingStreamParams{
+		BufferSize:  10,
+		Instruments: []string{"EUR_USD"},
+	})
+	if err != nil {
+		t.Fatalf("Error occurred.\n%+v", err)
+	}
+	defer m.Close()
+
+	first := <-connectionCh
+	if first.params.Snapshot == nil || !*first.params.Snapshot {
+		t.Fatal("Snapshot was not requested.")
+	}
+	first.srcCh <- &PriceDefinition{Type: "PRICE", Instrument: "EUR_USD", Time: "1"}
+	if data := <-m.PriceCh; data.Time != "1" {
+		t.Fatalf("Got unexpected price.\n%#v", data)
+	}
+
+	m.Subscribe("USD_JPY", "EUR_USD")
+	second := <-connectionCh
+	if expect := []string{"EUR_USD", "USD_JPY"}; !reflect.DeepEqual(second.params.Instruments, expect) {
+		t.Fatalf("Reconnected with unexpected instruments.\nExpect: %v\nActual: %v", expect, second.params.Instruments)
+	}
+	second.srcCh <- &PriceDefinition{Type: "PRICE", Instrument: "USD_JPY", Time: "2"}
+	if data := <-m.PriceCh; data.Time != "2" {
+		t.Fatalf("Got unexpected price.\n%#v", data)
+	}
+
+	m.Unsubscribe("EUR_USD")
+	third := <-connectionCh
+	if expect := []string{"USD_JPY"}; !reflect.DeepEqual(third.params.Instruments, expect) {
+		t.Fatalf("Reconnected with unexpected instruments.\nExpect: %v\nActual: %v", expect, third.params.Instruments)
+	}
+	third.srcCh <- &PriceDefinition{Type: "PRICE", Instrument: "EUR_USD", Time: "3"}
+	third.srcCh <- &PriceDefinition{Type: "PRICE", Instrument: "USD_JPY", Time: "4"}
+	if data := <-m.PriceCh; data.Time != "4" {
+		t.Fatalf("Got price of an unsubscribed instrument.\n%#v", data)
+	}
+
+	m.Close()
+	for range m.PriceCh {
+	}
+	if err := m.Err(); err != nil {
+		t.Fatalf("Error occurred.\n%+v", err)
+	}
+}
@@ -0,0 +1,227 @@
+package oanda
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+/* Params */
+
+type GetPricingPollParams struct {
+	BufferSize  int
+	Instruments []string
+	Interval    time.Duration // defaults to one second
+}
+
+type GetPricingFeedParams struct {
+	BufferSize   int
+	Instruments  []string
+	PollInterval time.Duration // defaults to one second
+}
+
+/* API */
+
+// Poll emulates the pricing stream by polling GET /v3/accounts/{accountID}/pricing.
+// The time of every response is used as "since" of the next request, so only
+// prices that changed are sent to PriceCh. A heartbeat is sent for polls
+// without changes.
+func (r *ReceiverPricing) Poll(ctx context.Context, params *GetPricingPollParams) (*PriceChannels, error) {
+	chs, err := newPricePoller(ctx, r.Get, params)
+	if err != nil {
+		return nil, errors.Errorf("Poll pricing failed: %v", err)
+	}
+	return chs, nil
+}
+
+// Feed opens the pricing stream and falls back to polling when the stream
+// can't be opened or breaks, e.g. behind proxies that kill long-lived
+// connections.
+func (r *ReceiverPricing) Feed(ctx context.Context, params *GetPricingFeedParams) (*PriceChannels, error) {
+	stream := func(ctx context.Context) (*PriceChannels, error) {
+		return r.Stream().Get(ctx, &GetPricingStreamParams{
+			BufferSize:  params.BufferSize,
+			Instruments: params.Instruments,
+		})
+	}
+	poll := func(ctx context.Context) (*PriceChannels, error) {
+		return r.Poll(ctx, &GetPricingPollParams{
+			BufferSize:  params.BufferSize,
+			Instruments: params.Instruments,
+			Interval:    params.PollInterval,
+		})
+	}
+
+	chs, err := newPriceFeed(ctx, stream, poll, params.BufferSize)
+	if err != nil {
+		return nil, errors.Errorf("Get pricing feed failed: %v", err)
+	}
+	return chs, nil
+}
+
+func newPricePoller(ctx context.Context, get func(context.Context, *GetPricingParams) (*GetPricingSchema, error), params *GetPricingPollParams) (*PriceChannels, error) {
+	interval := params.Interval
+	if interval <= 0 {
+		interval = time.Second
+	}
+
+	childCtx, cancel := context.WithCancel(ctx)
+
+	// The first poll is made synchronously so that errors like an invalid
+	// token are returned to the caller.
+	data, err := get(childCtx, &GetPricingParams{Instruments: params.Instruments})
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	closeWait := new(sync.WaitGroup)
+	priceCh := make(chan *PriceDefinition, params.BufferSize)
+	errorCh := make(chan error, 1)
+
+	closeWait.Add(1)
+	go func() {
+		defer func() {
+			close(priceCh)
+			cancel()
+			closeWait.Done()
+		}()
+
+		// The last price time of every instrument, to drop unchanged prices.
+		times := make(map[InstrumentNameDefinition]DateTimeDefinition, len(params.Instruments))
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			sent := false
+			for _, price := range data.Prices {
+				if times[price.Instrument] == price.Time {
+					continue
+				}
+				times[price.Instrument] = price.Time
+				if price.Type == "" {
+					price.Type = "PRICE"
+				}
+
+				select {
+				case priceCh <- price:
+					sent = true
+				case <-childCtx.Done():
+					return
+				}
+			}
+			if !sent {
+				select {
+				case priceCh <- &PriceDefinition{Type: "HEARTBEAT", Time: data.Time}:
+				case <-childCtx.Done():
+					return
+				}
+			}
+
+			since, err := time.Parse(time.RFC3339Nano, data.Time)
+			if err != nil {
+				errorCh <- errors.Errorf("Parse pricing time failed: %v", err)
+				return
+			}
+
+			select {
+			case <-childCtx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			data, err = get(childCtx, &GetPricingParams{Instruments: params.Instruments, Since: since})
+			if err != nil {
+				select {
+				case <-childCtx.Done():
+				default:
+					errorCh <- errors.Errorf("Poll pricing failed: %v", err)
+				}
+				return
+			}
+		}
+	}()
+
+	return &PriceChannels{
+		PriceCh:   priceCh,
+		lastError: nil,
+		errorCh:   errorCh,
+		close:     cancel,
+		closeWait: closeWait,
+	}, nil
+}
+
+func newPriceFeed(ctx context.Context, stream, poll func(context.Context) (*PriceChannels, error), bufferSize int) (*PriceChannels, error) {
+	childCtx, cancel := context.WithCancel(ctx)
+
+	upstream, err := stream(childCtx)
+	polling := false
+	if err != nil {
+		if upstream, err = poll(childCtx); err != nil {
+			cancel()
+			return nil, err
+		}
+		polling = true
+	}
+
+	closeWait := new(sync.WaitGroup)
+	priceCh := make(chan *PriceDefinition, bufferSize)
+	errorCh := make(chan error, 1)
+
+	closeWait.Add(1)
+	go func() {
+		defer func() {
+			upstream.Close()
+			close(priceCh)
+			cancel()
+			closeWait.Done()
+		}()
+
+		for {
+			select {
+			case <-childCtx.Done():
+				return
+			case data, ok := <-upstream.PriceCh:
+				if ok {
+					select {
+					case priceCh <- data:
+					case <-childCtx.Done():
+						return
+					}
+					continue
+				}
+
+				select {
+				case <-childCtx.Done():
+					return
+				default:
+				}
+
+				upstream.Close()
+				if polling {
+					errorCh <- errors.Errorf("Pricing poll was broken: %v", upstream.Err())
+					return
+				}
+
+				// The stream was broken, continue by polling.
+				next, err := poll(childCtx)
+				if err != nil {
+					errorCh <- errors.Errorf("Fall back to pricing poll failed after stream was broken(%v): %v", upstream.Err(), err)
+					return
+				}
+				upstream, polling = next, true
+			}
+		}
+	}()
+
+	return &PriceChannels{
+		PriceCh:   priceCh,
+		lastError: nil,
+		errorCh:   errorCh,
+		close:     cancel,
+		closeWait: closeWait,
+	}, nil
+}
@@ -0,0 +1,134 @@
+package oanda
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func Test_PricePoller(t *testing.T) {
+	responses := []*GetPricingSchema{
+		{
+			Prices: []*PriceDefinition{
+				{Instrument: "EUR_USD", Time: "2021-01-01T00:00:00.000000000Z"},
+				{Instrument: "USD_JPY", Time: "2021-01-01T00:00:00.000000000Z"},
+			},
+			Time: "2021-01-01T00:00:01.000000000Z",
+		},
+		{
+			Prices: []*PriceDefinition{
+				{Instrument: "EUR_USD", Time: "2021-01-01T00:00:00.000000000Z"},
+				{Instrument: "USD_JPY", Time: "2021-01-01T00:00:01.500000000Z"},
+			},
+			Time: "2021-01-01T00:00:02.000000000Z",
+		},
+		{
+			Time: "2021-01-01T00:00:03.000000000Z",
+		},
+	}
+
+	var sinces []time.Time
+	get := func(ctx context.Context, params *GetPricingParams) (*GetPricingSchema, error) {
+		sinces = append(sinces, params.Since)
+		if len(sinces) > len(responses) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		return responses[len(sinces)-1], nil
+	}
+
+	chs, err := newPricePoller(context.Background(), get, &GetPricingPollParams{
+		Instruments: []string{"EUR_USD", "USD_JPY"},
+		Interval:    time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("Error occurred.\n%+v", err)
+	}
+
+	expects := []struct {
+		typ        string
+		instrument string
+	}{
+		{"PRICE", "EUR_USD"},
+		{"PRICE", "USD_JPY"},
+		{"PRICE", "USD_JPY"},
+		{"HEARTBEAT", ""},
+	}
+	for _, expect := range expects {
+		data := <-chs.PriceCh
+		if data.Type != expect.typ || data.Instrument != expect.instrument {
+			t.Fatalf("Got unexpected price.\nExpect: %+v\nActual: %#v", expect, data)
+		}
+	}
+
+	chs.Close()
+	if err := chs.Err(); err != nil {
+		t.Fatalf("Error occurred.\n%+v", err)
+	}
+
+	if !sinces[0].IsZero() {
+		t.Fatalf("First poll was sent with since %s", sinces[0])
+	}
+	if expect := "2021-01-01T00:00:01Z"; sinces[1].Format(time.RFC3339Nano) != expect {
+		t.Fatalf("Got unexpected since.\nExpect: %s\nActual: %s", expect, sinces[1].Format(time.RFC3339Nano))
+	}
+}
+
+func Test_PriceFeed(t *testing.T) {
+	t.Run("FallbackOnOpen", func(t *testing.T) {
+		stream := func(ctx context.Context) (*PriceChannels, error) {
+			return nil, errors.New("Proxy refused")
+		}
+		var pollSrcCh chan<- *PriceDefinition
+		poll := func(ctx context.Context) (*PriceChannels, error) {
+			var chs *PriceChannels
+			chs, pollSrcCh = newFakePriceChannels()
+			return chs, nil
+		}
+
+		chs, err := newPriceFeed(context.Background(), stream, poll, 1)
+		if err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+		defer chs.Close()
+
+		pollSrcCh <- &PriceDefinition{Type: "PRICE", Instrument: "EUR_USD"}
+		if data := <-chs.PriceCh; data.Instrument != "EUR_USD" {
+			t.Fatalf("Got unexpected price.\n%#v", data)
+		}
+	})
+
+	t.Run("FallbackOnBrokenStream", func(t *testing.T) {
+		streamChs, streamSrcCh := newFakePriceChannels()
+		stream := func(ctx context.Context) (*PriceChannels, error) {
+			return streamChs, nil
+		}
+		pollSrcCh := make(chan chan<- *PriceDefinition, 1)
+		poll := func(ctx context.Context) (*PriceChannels, error) {
+			chs, srcCh := newFakePriceChannels()
+			pollSrcCh <- srcCh
+			return chs, nil
+		}
+
+		chs, err := newPriceFeed(context.Background(), stream, poll, 1)
+		if err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+		defer chs.Close()
+
+		streamSrcCh <- &PriceDefinition{Type: "PRICE", Instrument: "EUR_USD"}
+		if data := <-chs.PriceCh; data.Instrument != "EUR_USD" {
+			t.Fatalf("Got unexpected price.\n%#v", data)
+		}
+
+		// Break the stream.
+		streamChs.close()
+
+		(<-pollSrcCh) <- &PriceDefinition{Type: "PRICE", Instrument: "USD_JPY"}
+		if data := <-chs.PriceCh; data.Instrument != "USD_JPY" {
+			t.Fatalf("Got unexpected price.\n%#v", data)
+		}
+	})
+}
@@ -1,7 +1,12 @@
 package oanda
 
 import (
+	"bufio"
 	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
 	"testing"
 	"time"
 
@@ -51,6 +56,27 @@ func Test_PricingStream(t *testing.T) {
 		}
 	})
 
+	// Close waits for the goroutines of the stream even when it is called
+	// right after opening.
+	t.Run("Close", func(t *testing.T) {
+		connection := newIdleStreamConnection()
+		for i := 0; i < 50; i++ {
+			chs, err := connection.Accounts().AccountID(testReplayAccountID).Pricing().Stream().Get(context.Background(), &GetPricingStreamParams{Instruments: []string{"EUR_USD"}})
+			if err != nil {
+				t.Fatalf("Error occurred.\n%+v", err)
+			}
+			chs.Close()
+			select {
+			case _, ok := <-chs.PriceCh:
+				if ok {
+					t.Fatalf("Got data after close.")
+				}
+			default:
+				t.Fatalf("Stream is still running after close.")
+			}
+		}
+	})
+
 	t.Run("Unauthorized", func(t *testing.T) {
 		connection := newConnection(t, OandaPractice)
 		connection.Token = "foo"
@@ -68,10 +94,65 @@ func Test_PricingStream(t *testing.T) {
 			t.Fatal("Got 200 OK but unauthorized.")
 		}
 
-		if _, ok := errors.Unwrap(err).(*UnauthorizedError); !ok {
+		var unauthorized *UnauthorizedError
+		if !errors.As(err, &unauthorized) {
 			t.Fatalf("Got unexpected error.\n%+v", err)
 		}
 
 		t.Logf("Error occurred as expected.\n%+v", err)
 	})
 }
+
+const benchmarkPriceLine = `{"type":"PRICE","time":"2016-09-20T15:05:47.960449532Z","bids":[{"price":"1.11590","liquidity":10000000},{"price":"1.11589","liquidity":10000000}],"asks":[{"price":"1.11601","liquidity":10000000},{"price":"1.11602","liquidity":10000000}],"closeoutBid":"1.11590","closeoutAsk":"1.11601","status":"tradeable","tradeable":true,"instrument":"EUR_USD"}` + "\n"
+
+// BenchmarkPriceStreamDecoder measures the stream path of Get, one
+// PriceDefinition per tick.
+func BenchmarkPriceStreamDecoder(b *testing.B) {
+	body := strings.NewReader(strings.Repeat(benchmarkPriceLine, b.N))
+	decoder := json.NewDecoder(body)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		data := new(PriceDefinition)
+		if err := decoder.Decode(data); err != nil {
+			b.Fatalf("Error occurred.\n%+v", err)
+		}
+	}
+}
+
+// BenchmarkTickDecoder measures the stream path of Ticks.
+func BenchmarkTickDecoder(b *testing.B) {
+	reader := bufio.NewReaderSize(strings.NewReader(strings.Repeat(benchmarkPriceLine, b.N)), tickLineSize)
+	instruments := map[string]InstrumentNameDefinition{"EUR_USD": "EUR_USD"}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		line, err := reader.ReadSlice('\n')
+		if err != nil {
+			b.Fatalf("Error occurred.\n%+v", err)
+		}
+		var tick Tick
+		if _, err := decodeTick(line, &tick, instruments); err != nil {
+			b.Fatalf("Error occurred.\n%+v", err)
+		}
+	}
+}
+
+// newIdleStreamConnection returns a connection to streams that send nothing
+// until they are closed.
+func newIdleStreamConnection() *Connection {
+	return &Connection{
+		Environemnt: OandaPractice,
+		Timeout:     10 * time.Second,
+		Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			body, w := io.Pipe()
+			go func() {
+				<-req.Context().Done()
+				w.Close()
+			}()
+			return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: body, Request: req}, nil
+		}),
+	}
+}
@@ -0,0 +1,541 @@
+package oanda
+
+import (
+	"bufio"
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+/* Streams */
+
+// Tick is the top of the book of an instrument. Ticks are decoded from the
+// pricing stream without allocating.
+type Tick struct {
+	Instrument InstrumentNameDefinition
+	Time       time.Time
+	Bid        float64
+	Ask        float64
+	Tradeable  bool
+}
+
+type TickChannels struct {
+	// TickCh receives the prices of the stream, heartbeats are not passed on.
+	TickCh    <-chan Tick
+	lastError error
+	errorCh   <-chan error
+	close     context.CancelFunc
+	closeWait *sync.WaitGroup
+}
+
+// The longest line of the pricing stream that can be decoded.
+const tickLineSize = 64 << 10
+
+/* API */
+
+// GET /v3/accounts/{accountID}/pricing/stream
+//
+// Ticks is a variant of Get for high tick rates. It decodes the stream into
+// ticks passed by value instead of allocating a PriceDefinition per price.
+func (r *ReceiverPricingStream) Ticks(ctx context.Context, params *GetPricingStreamParams) (*TickChannels, error) {
+	childCtx, cancel := context.WithCancel(ctx)
+
+	resp, observer, err := r.Connection.stream(
+		childCtx,
+		&requestParams{
+			method:   "GET",
+			endPoint: "/v3/accounts/" + r.AccountID + "/pricing/stream",
+			headers: []header{
+				{key: "Accept-Datetime-Format", value: "RFC3339"},
+			},
+			queries: func() []query {
+				q := make([]query, 0, 2)
+				q = append(q, query{key: "instruments", value: strings.Join(params.Instruments, ",")})
+				if params.Snapshot != nil {
+					q = append(q, query{key: "snapshot", value: strconv.FormatBool(*params.Snapshot)})
+				}
+				return q
+			}(),
+		},
+	)
+	if err != nil {
+		cancel()
+		return nil, errors.Errorf("Get pricing ticks canceled: %v", err)
+	}
+
+	if resp.StatusCode != 200 {
+		defer func() {
+			resp.Body.Close()
+			cancel()
+		}()
+		var err error
+		_, err = parseResponse(resp, nil, r.Connection.compare(childCtx, observer.req, resp))
+		return nil, errors.Wrap(err, "Get pricing ticks failed")
+	}
+
+	closeWait := new(sync.WaitGroup)
+	closeWait.Add(3)
+
+	tickCh := make(chan Tick, params.BufferSize)
+	errorCh := make(chan error, 2)
+
+	// Instrument names are shared by all ticks instead of being allocated.
+	instruments := make(map[string]InstrumentNameDefinition, len(params.Instruments))
+	for _, i := range params.Instruments {
+		instruments[i] = i
+	}
+
+	// Reading blocks until data arrives, so the body is closed when the
+	// stream ends.
+	go func() {
+		defer func() {
+			resp.Body.Close()
+			cancel()
+			closeWait.Done()
+		}()
+		<-childCtx.Done()
+	}()
+
+	// received is set by the reader and reset by the heartbeat watchdog.
+	received := int32(1)
+
+	go func() {
+		defer func() {
+			close(tickCh)
+			cancel()
+			observer.close()
+			closeWait.Done()
+		}()
+
+		reader := bufio.NewReaderSize(resp.Body, tickLineSize)
+		for {
+			line, err := reader.ReadSlice('\n')
+			if err != nil {
+				select {
+				case <-childCtx.Done():
+				default:
+					errorCh <- observer.fail(errors.Errorf("Read response stream failed: %v", err))
+				}
+				return
+			}
+			atomic.StoreInt32(&received, 1)
+
+			var tick Tick
+			heartbeat, err := decodeTick(line, &tick, instruments)
+			if err != nil {
+				errorCh <- observer.fail(errors.Errorf("Unmarshal response stream failed: %v", err))
+				return
+			}
+
+			if heartbeat {
+				if observer.observesMessages() {
+					observer.message(&PriceDefinition{Type: "HEARTBEAT", Time: tick.Time.Format(time.RFC3339Nano)})
+				}
+				continue
+			}
+			if observer.observesMessages() {
+				observer.message(tick)
+			}
+
+			r.Connection.setStreamBuffer(StreamPricing, len(tickCh), cap(tickCh))
+			select {
+			case tickCh <- tick:
+			case <-childCtx.Done():
+				return
+			}
+		}
+	}()
+
+	// Heartbeats arrive every 5 seconds, the stream is broken when nothing
+	// was received for Timeout.
+	go func() {
+		defer closeWait.Done()
+		if r.Connection.Timeout <= 0 {
+			<-childCtx.Done()
+			return
+		}
+
+		ticker := time.NewTicker(r.Connection.Timeout)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-childCtx.Done():
+				return
+			case <-ticker.C:
+				if atomic.SwapInt32(&received, 0) == 0 {
+					var err error = &StreamHeartbeatBroken{ErrorMessage: "Heartbeat was broken"}
+					errorCh <- observer.fail(errors.Errorf("Get pricing ticks heartbeat was broken: %v", err))
+					cancel()
+					return
+				}
+			}
+		}
+	}()
+
+	return &TickChannels{
+		TickCh:    tickCh,
+		lastError: nil,
+		errorCh:   errorCh,
+		close:     cancel,
+		closeWait: closeWait,
+	}, nil
+}
+
+/* Utils */
+
+func (ch *TickChannels) Close() {
+	ch.close()
+	ch.closeWait.Wait()
+}
+
+func (ch *TickChannels) Err() error {
+	if ch.lastError == nil {
+		select {
+		case ch.lastError = <-ch.errorCh:
+		default:
+		}
+	}
+	return ch.lastError
+}
+
+// decodeTick decodes a line of the pricing stream into tick and reports
+// whether it is a heartbeat. Names of instruments missing in instruments are
+// allocated.
+func decodeTick(line []byte, tick *Tick, instruments map[string]InstrumentNameDefinition) (bool, error) {
+	s := &tickScanner{b: line}
+	heartbeat := false
+	var closeoutBid, closeoutAsk []byte
+
+	if !s.consume('{') {
+		return false, s.errorf("expected object")
+	}
+	for first := true; ; first = false {
+		if s.consume('}') {
+			break
+		}
+		if !first && !s.consume(',') {
+			return false, s.errorf("expected ',' or '}'")
+		}
+		key, ok := s.string()
+		if !ok || !s.consume(':') {
+			return false, s.errorf("expected key")
+		}
+
+		var err error
+		switch string(key) {
+		case "type":
+			v, ok := s.string()
+			if !ok {
+				return false, s.errorf("expected type")
+			}
+			heartbeat = string(v) == "HEARTBEAT"
+		case "time":
+			v, ok := s.string()
+			if !ok {
+				return false, s.errorf("expected time")
+			}
+			if tick.Time, err = parseTickTime(v); err != nil {
+				return false, err
+			}
+		case "instrument":
+			v, ok := s.string()
+			if !ok {
+				return false, s.errorf("expected instrument")
+			}
+			if name, ok := instruments[string(v)]; ok {
+				tick.Instrument = name
+			} else {
+				tick.Instrument = string(v)
+			}
+		case "tradeable":
+			tick.Tradeable = s.literal("true")
+			if !tick.Tradeable && !s.literal("false") {
+				return false, s.errorf("expected tradeable")
+			}
+		case "bids":
+			if tick.Bid, err = s.bestPrice(); err != nil {
+				return false, err
+			}
+		case "asks":
+			if tick.Ask, err = s.bestPrice(); err != nil {
+				return false, err
+			}
+		case "closeoutBid":
+			if closeoutBid, ok = s.string(); !ok {
+				return false, s.errorf("expected closeoutBid")
+			}
+		case "closeoutAsk":
+			if closeoutAsk, ok = s.string(); !ok {
+				return false, s.errorf("expected closeoutAsk")
+			}
+		default:
+			if !s.skip() {
+				return false, s.errorf("malformed value")
+			}
+		}
+	}
+
+	// Prices without liquidity only have closeout prices.
+	var err error
+	if tick.Bid == 0 && closeoutBid != nil {
+		if tick.Bid, err = parseTickPrice(closeoutBid); err != nil {
+			return false, err
+		}
+	}
+	if tick.Ask == 0 && closeoutAsk != nil {
+		if tick.Ask, err = parseTickPrice(closeoutAsk); err != nil {
+			return false, err
+		}
+	}
+	return heartbeat, nil
+}
+
+// tickScanner scans the JSON of a price without allocating.
+type tickScanner struct {
+	b []byte
+	i int
+}
+
+func (s *tickScanner) errorf(format string) error {
+	return errors.Errorf("decode tick at offset %d: %s", s.i, format)
+}
+
+func (s *tickScanner) space() {
+	for s.i < len(s.b) {
+		switch s.b[s.i] {
+		case ' ', '\t', '\r', '\n':
+			s.i++
+		default:
+			return
+		}
+	}
+}
+
+func (s *tickScanner) consume(c byte) bool {
+	s.space()
+	if s.i < len(s.b) && s.b[s.i] == c {
+		s.i++
+		return true
+	}
+	return false
+}
+
+func (s *tickScanner) literal(lit string) bool {
+	s.space()
+	if len(s.b)-s.i >= len(lit) && string(s.b[s.i:s.i+len(lit)]) == lit {
+		s.i += len(lit)
+		return true
+	}
+	return false
+}
+
+// string returns the raw contents of a string, escapes are not decoded.
+func (s *tickScanner) string() ([]byte, bool) {
+	if !s.consume('"') {
+		return nil, false
+	}
+	start := s.i
+	for s.i < len(s.b) {
+		switch s.b[s.i] {
+		case '\\':
+			s.i += 2
+		case '"':
+			s.i++
+			return s.b[start : s.i-1], true
+		default:
+			s.i++
+		}
+	}
+	return nil, false
+}
+
+// skip skips a value of any type.
+func (s *tickScanner) skip() bool {
+	s.space()
+	if s.i >= len(s.b) {
+		return false
+	}
+	switch s.b[s.i] {
+	case '"':
+		_, ok := s.string()
+		return ok
+	case '{', '[':
+		depth := 0
+		for s.i < len(s.b) {
+			switch s.b[s.i] {
+			case '"':
+				if _, ok := s.string(); !ok {
+					return false
+				}
+				continue
+			case '{', '[':
+				depth++
+			case '}', ']':
+				depth--
+			}
+			s.i++
+			if depth == 0 {
+				return true
+			}
+		}
+		return false
+	default:
+		start := s.i
+		for s.i < len(s.b) {
+			switch s.b[s.i] {
+			case ',', '}', ']', ' ', '\t', '\r', '\n':
+				return s.i > start
+			}
+			s.i++
+		}
+		return false
+	}
+}
+
+// bestPrice returns the price of the first bucket of a list of price
+// buckets, 0 when the list is empty.
+func (s *tickScanner) bestPrice() (float64, error) {
+	if !s.consume('[') {
+		return 0, s.errorf("expected price buckets")
+	}
+	price := 0.0
+	for first := true; ; first = false {
+		if s.consume(']') {
+			return price, nil
+		}
+		if !first && !s.consume(',') {
+			return 0, s.errorf("expected ',' or ']'")
+		}
+		if !first {
+			if !s.skip() {
+				return 0, s.errorf("malformed price bucket")
+			}
+			continue
+		}
+
+		if !s.consume('{') {
+			return 0, s.errorf("expected price bucket")
+		}
+		for firstKey := true; ; firstKey = false {
+			if s.consume('}') {
+				break
+			}
+			if !firstKey && !s.consume(',') {
+				return 0, s.errorf("expected ',' or '}'")
+			}
+			key, ok := s.string()
+			if !ok || !s.consume(':') {
+				return 0, s.errorf("expected key")
+			}
+			if string(key) != "price" {
+				if !s.skip() {
+					return 0, s.errorf("malformed value")
+				}
+				continue
+			}
+			v, ok := s.string()
+			if !ok {
+				return 0, s.errorf("expected price")
+			}
+			var err error
+			if price, err = parseTickPrice(v); err != nil {
+				return 0, err
+			}
+		}
+	}
+}
+
+var tickPow10 = [...]float64{1, 1e1, 1e2, 1e3, 1e4, 1e5, 1e6, 1e7, 1e8, 1e9, 1e10, 1e11, 1e12, 1e13, 1e14, 1e15}
+
+// parseTickPrice parses a decimal like "1.10512". Both the digits and the
+// power of ten are exact floats, so their quotient is rounded like
+// strconv.ParseFloat.
+func parseTickPrice(b []byte) (float64, error) {
+	var mantissa uint64
+	digits, decimals := 0, -1
+	for _, c := range b {
+		switch {
+		case c >= '0' && c <= '9':
+			mantissa = mantissa*10 + uint64(c-'0')
+			digits++
+			if decimals >= 0 {
+				decimals++
+			}
+		case c == '.' && decimals < 0:
+			decimals = 0
+		default:
+			digits = len(tickPow10)
+		}
+	}
+
+	if digits == 0 || digits >= len(tickPow10) {
+		f, err := strconv.ParseFloat(string(b), 64)
+		if err != nil {
+			return 0, errors.Errorf("Parse price failed: %v", err)
+		}
+		return f, nil
+	}
+	if decimals <= 0 {
+		return float64(mantissa), nil
+	}
+	return float64(mantissa) / tickPow10[decimals], nil
+}
+
+// parseTickTime parses an RFC 3339 UTC time like
+// "2016-09-20T15:05:47.960449532Z".
+func parseTickTime(b []byte) (time.Time, error) {
+	if len(b) < 20 || b[4] != '-' || b[7] != '-' || b[10] != 'T' || b[13] != ':' || b[16] != ':' || b[len(b)-1] != 'Z' {
+		return parseTickTimeSlow(b)
+	}
+
+	num := func(b []byte) (int, bool) {
+		n := 0
+		for _, c := range b {
+			if c < '0' || c > '9' {
+				return 0, false
+			}
+			n = n*10 + int(c-'0')
+		}
+		return n, true
+	}
+
+	year, ok1 := num(b[0:4])
+	month, ok2 := num(b[5:7])
+	day, ok3 := num(b[8:10])
+	hour, ok4 := num(b[11:13])
+	minute, ok5 := num(b[14:16])
+	second, ok6 := num(b[17:19])
+	if !(ok1 && ok2 && ok3 && ok4 && ok5 && ok6) {
+		return parseTickTimeSlow(b)
+	}
+
+	nsec := 0
+	if frac := b[19 : len(b)-1]; len(frac) > 0 {
+		if frac[0] != '.' || len(frac) > 10 {
+			return parseTickTimeSlow(b)
+		}
+		n, ok := num(frac[1:])
+		if !ok {
+			return parseTickTimeSlow(b)
+		}
+		for i := len(frac) - 1; i < 9; i++ {
+			n *= 10
+		}
+		nsec = n
+	}
+
+	return time.Date(year, time.Month(month), day, hour, minute, second, nsec, time.UTC), nil
+}
+
+func parseTickTimeSlow(b []byte) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339Nano, string(b))
+	if err != nil {
+		return time.Time{}, errors.Errorf("Parse tick time failed: %v", err)
+	}
+	return t, nil
+}
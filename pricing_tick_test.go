@@ -0,0 +1,107 @@
+package oanda
+
+import (
+	"encoding/json"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func Test_decodeTick(t *testing.T) {
+	instruments := map[string]InstrumentNameDefinition{"EUR_USD": "EUR_USD"}
+
+	t.Run("Price", func(t *testing.T) {
+		var tick Tick
+		heartbeat, err := decodeTick([]byte(benchmarkPriceLine), &tick, instruments)
+		if err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+
+		expect := Tick{
+			Instrument: "EUR_USD",
+			Time:       time.Date(2016, 9, 20, 15, 5, 47, 960449532, time.UTC),
+			Bid:        1.11590,
+			Ask:        1.11601,
+			Tradeable:  true,
+		}
+		if heartbeat || tick != expect {
+			t.Fatalf("Got unexpected tick.\nExpect: %+v\nActual: %+v", expect, tick)
+		}
+	})
+
+	t.Run("Heartbeat", func(t *testing.T) {
+		var tick Tick
+		heartbeat, err := decodeTick([]byte(`{"type":"HEARTBEAT","time":"2016-09-20T15:05:50Z"}`), &tick, instruments)
+		if err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+		if !heartbeat || !tick.Time.Equal(time.Date(2016, 9, 20, 15, 5, 50, 0, time.UTC)) {
+			t.Fatalf("Got unexpected heartbeat.\n%+v", tick)
+		}
+	})
+
+	t.Run("Closeout", func(t *testing.T) {
+		line := `{"type":"PRICE","time":"2016-09-20T15:05:47.96+09:00","bids":[],"asks":[],"closeoutBid":"156.123","closeoutAsk":"156.321","tradeable":false,"quoteHomeConversionFactors":{"positiveUnits":"1.0"},"instrument":"USD_JPY"}`
+		var tick Tick
+		if _, err := decodeTick([]byte(line), &tick, instruments); err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+		if tick.Instrument != "USD_JPY" || tick.Bid != 156.123 || tick.Ask != 156.321 || tick.Tradeable {
+			t.Fatalf("Got unexpected tick.\n%+v", tick)
+		}
+		if !tick.Time.Equal(time.Date(2016, 9, 20, 6, 5, 47, 960000000, time.UTC)) {
+			t.Fatalf("Got unexpected time.\n%v", tick.Time)
+		}
+	})
+
+	t.Run("Malformed", func(t *testing.T) {
+		for _, line := range []string{``, `[]`, `{"type":"PRICE"`, `{"bids":[{"price":1.1}]}`, `{"tradeable":maybe}`} {
+			var tick Tick
+			if _, err := decodeTick([]byte(line), &tick, instruments); err == nil {
+				t.Fatalf("Error did not occur for %s", line)
+			}
+		}
+	})
+
+	t.Run("Allocations", func(t *testing.T) {
+		line := []byte(benchmarkPriceLine)
+		allocs := testing.AllocsPerRun(100, func() {
+			var tick Tick
+			if _, err := decodeTick(line, &tick, instruments); err != nil {
+				t.Fatalf("Error occurred.\n%+v", err)
+			}
+		})
+		if allocs != 0 {
+			t.Fatalf("Decoding a tick allocated %v times", allocs)
+		}
+	})
+
+	t.Run("MatchesDecoder", func(t *testing.T) {
+		var price PriceDefinition
+		if err := json.Unmarshal([]byte(benchmarkPriceLine), &price); err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+		var tick Tick
+		if _, err := decodeTick([]byte(benchmarkPriceLine), &tick, instruments); err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+		bid, _ := strconv.ParseFloat(price.Bids[0].Price, 64)
+		ask, _ := strconv.ParseFloat(price.Asks[0].Price, 64)
+		if tick.Bid != bid || tick.Ask != ask || tick.Instrument != price.Instrument {
+			t.Fatalf("Got unexpected tick.\n%+v", tick)
+		}
+	})
+}
+
+func Test_parseTickPrice(t *testing.T) {
+	for _, v := range []string{"0", "1", "1.1", "1.10512", "0.00001", "156.123", "12345.6789", "99999999999999.9", "1234567890.1234567", "1e5"} {
+		expect, _ := strconv.ParseFloat(v, 64)
+		actual, err := parseTickPrice([]byte(v))
+		if err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+		if actual != expect {
+			t.Fatalf("Got unexpected price of %s.\nExpect: %v\nActual: %v", v, expect, actual)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+// Package prometheus exports the metrics of an oanda.Connection to the
+// Prometheus client.
+package prometheus
+
+import (
+	"strconv"
+	"time"
+
+	oanda "github.com/denkhaus/oanda-client"
+	prom "github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics implements oanda.Metrics with Prometheus collectors.
+type Metrics struct {
+	requests        *prom.CounterVec
+	requestDuration *prom.HistogramVec
+	messages        *prom.CounterVec
+	messageGap      *prom.HistogramVec
+	reconnects      *prom.CounterVec
+	bufferUsed      *prom.GaugeVec
+	bufferCapacity  *prom.GaugeVec
+}
+
+var _ oanda.Metrics = (*Metrics)(nil)
+
+// New creates the collectors with the namespace, e.g. "oanda", and registers
+// them with registerer.
+func New(registerer prom.Registerer, namespace string) (*Metrics, error) {
+	m := &Metrics{
+		requests: prom.NewCounterVec(prom.CounterOpts{
+			Namespace: namespace,
+			Name:      "requests_total",
+			Help:      "REST calls by method, endpoint and status code.",
+		}, []string{"method", "endpoint", "status"}),
+		requestDuration: prom.NewHistogramVec(prom.HistogramOpts{
+			Namespace: namespace,
+			Name:      "request_duration_seconds",
+			Help:      "Latency of REST calls by method and endpoint.",
+			Buckets:   prom.DefBuckets,
+		}, []string{"method", "endpoint"}),
+		messages: prom.NewCounterVec(prom.CounterOpts{
+			Namespace: namespace,
+			Name:      "stream_messages_total",
+			Help:      "Messages read from streams by stream and message type.",
+		}, []string{"stream", "type"}),
+		messageGap: prom.NewHistogramVec(prom.HistogramOpts{
+			Namespace: namespace,
+			Name:      "stream_message_gap_seconds",
+			Help:      "Time between messages of the same type read from streams, e.g. between heartbeats.",
+			Buckets:   []float64{0.01, 0.05, 0.1, 0.5, 1, 2.5, 5, 6, 7.5, 10, 30},
+		}, []string{"stream", "type"}),
+		reconnects: prom.NewCounterVec(prom.CounterOpts{
+			Namespace: namespace,
+			Name:      "stream_reconnects_total",
+			Help:      "Reopened streams by stream.",
+		}, []string{"stream"}),
+		bufferUsed: prom.NewGaugeVec(prom.GaugeOpts{
+			Namespace: namespace,
+			Name:      "stream_buffer_used",
+			Help:      "Messages waiting in the channel of a stream.",
+		}, []string{"stream"}),
+		bufferCapacity: prom.NewGaugeVec(prom.GaugeOpts{
+			Namespace: namespace,
+			Name:      "stream_buffer_capacity",
+			Help:      "Capacity of the channel of a stream.",
+		}, []string{"stream"}),
+	}
+
+	for _, c := range []prom.Collector{
+		m.requests, m.requestDuration, m.messages, m.messageGap,
+		m.reconnects, m.bufferUsed, m.bufferCapacity,
+	} {
+		if err := registerer.Register(c); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+func (m *Metrics) ObserveRequest(method, endpoint string, status int, latency time.Duration) {
+	m.requests.WithLabelValues(method, endpoint, strconv.Itoa(status)).Inc()
+	m.requestDuration.WithLabelValues(method, endpoint).Observe(latency.Seconds())
+}
+
+func (m *Metrics) ObserveStreamMessage(stream, msgType string, gap time.Duration) {
+	m.messages.WithLabelValues(stream, msgType).Inc()
+	if gap > 0 {
+		m.messageGap.WithLabelValues(stream, msgType).Observe(gap.Seconds())
+	}
+}
+
+func (m *Metrics) IncStreamReconnect(stream string) {
+	m.reconnects.WithLabelValues(stream).Inc()
+}
+
+func (m *Metrics) SetStreamBuffer(stream string, used, capacity int) {
+	m.bufferUsed.WithLabelValues(stream).Set(float64(used))
+	m.bufferCapacity.WithLabelValues(stream).Set(float64(capacity))
+}
@@ -0,0 +1,47 @@
+package prometheus
+
+import (
+	"testing"
+	"time"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func Test_Metrics(t *testing.T) {
+	registry := prom.NewRegistry()
+	m, err := New(registry, "oanda")
+	if err != nil {
+		t.Fatalf("Error occurred.\n%+v", err)
+	}
+
+	m.ObserveRequest("GET", "/v3/accounts/{accountID}/summary", 200, 10*time.Millisecond)
+	m.ObserveRequest("GET", "/v3/accounts/{accountID}/summary", 200, 20*time.Millisecond)
+	m.ObserveStreamMessage("pricing", "HEARTBEAT", 0)
+	m.ObserveStreamMessage("pricing", "HEARTBEAT", 5*time.Second)
+	m.IncStreamReconnect("pricing")
+	m.SetStreamBuffer("pricing", 3, 10)
+
+	for _, c := range []struct {
+		name   string
+		expect float64
+		actual float64
+	}{
+		{"requests", 2, testutil.ToFloat64(m.requests.WithLabelValues("GET", "/v3/accounts/{accountID}/summary", "200"))},
+		{"messages", 2, testutil.ToFloat64(m.messages.WithLabelValues("pricing", "HEARTBEAT"))},
+		{"reconnects", 1, testutil.ToFloat64(m.reconnects.WithLabelValues("pricing"))},
+		{"bufferUsed", 3, testutil.ToFloat64(m.bufferUsed.WithLabelValues("pricing"))},
+	} {
+		if c.expect != c.actual {
+			t.Fatalf("Got unexpected %s.\nExpect: %v\nActual: %v", c.name, c.expect, c.actual)
+		}
+	}
+
+	if n := testutil.CollectAndCount(m.messageGap); n != 1 {
+		t.Fatalf("Got unexpected number of gap series: %d", n)
+	}
+
+	if _, err := New(registry, "oanda"); err == nil {
+		t.Fatalf("Error did not occur.")
+	}
+}
@@ -0,0 +1,279 @@
+package oanda
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/pkg/errors"
+)
+
+/* Rules */
+
+type RiskRule string
+
+const (
+	RiskRuleInstrumentWhitelist RiskRule = "INSTRUMENT_WHITELIST"
+	RiskRuleMaxUnits            RiskRule = "MAX_UNITS"
+	RiskRuleMaxOpenPositions    RiskRule = "MAX_OPEN_POSITIONS"
+	RiskRuleMaxNotional         RiskRule = "MAX_NOTIONAL"
+	RiskRuleMinMarginAvailable  RiskRule = "MIN_MARGIN_AVAILABLE"
+	RiskRulePriceDistance       RiskRule = "PRICE_DISTANCE"
+)
+
+// RiskRules configures the pre-trade checks of a RiskManager. A zero value
+// disables its rule.
+type RiskRules struct {
+	// Instruments that may be traded, any instrument when empty.
+	Instruments []InstrumentNameDefinition
+	// Maximum net units of the position in an instrument after the order,
+	// by instrument. DefaultMaxUnits applies to instruments not listed.
+	MaxUnits        map[InstrumentNameDefinition]float64
+	DefaultMaxUnits float64
+	// Maximum number of open positions after the order.
+	MaxOpenPositions int
+	// Maximum notional value of the order in the account currency.
+	MaxNotional float64
+	// Minimum margin available on the account before the order.
+	MinMarginAvailable float64
+	// Maximum distance of the order price from the last price as a fraction
+	// of the last price, e.g. 0.01 for 1%.
+	MaxPriceDistance float64
+	// Check live orders only.
+	LiveOnly bool
+}
+
+// RiskState is the account state and market data the rules are checked
+// against.
+type RiskState struct {
+	Summary *AccountSummaryDefinition
+	// Position in the instrument of the order, nil when there is none.
+	Position *PositionDefinition
+	// Last price of the instrument of the order.
+	Price           *PriceDefinition
+	HomeConversions []*HomeConversionsDefinition
+}
+
+/* Manager */
+
+// RiskManager runs pre-trade risk checks on orders. Install its Policy as the
+// OrderPolicy of a Connection to check every order before it is sent.
+type RiskManager struct {
+	Rules      *RiskRules
+	Connection *Connection
+	// State provides the state an order is checked against, defaults to
+	// querying the account summary, position and price through Connection.
+	State func(ctx context.Context, req *OrderPolicyRequest) (*RiskState, error)
+}
+
+func NewRiskManager(connection *Connection, rules *RiskRules) *RiskManager {
+	return &RiskManager{
+		Rules:      rules,
+		Connection: connection,
+	}
+}
+
+// Policy returns an OrderPolicy running the checks of the manager.
+func (m *RiskManager) Policy() OrderPolicy {
+	return m.Check
+}
+
+// Check returns a RiskViolation when the order breaks a rule.
+func (m *RiskManager) Check(ctx context.Context, req *OrderPolicyRequest) error {
+	rules := m.Rules
+	if rules.LiveOnly && !req.Live {
+		return nil
+	}
+	// Dependent orders like take profits only close trades.
+	if req.Instrument == "" {
+		return nil
+	}
+
+	if len(rules.Instruments) > 0 && !containsInstrument(rules.Instruments, req.Instrument) {
+		return newRiskViolation(RiskRuleInstrumentWhitelist, "%s is not a whitelisted instrument", req.Instrument)
+	}
+
+	units, err := parseDecimal(req.Units)
+	if err != nil {
+		return errors.Errorf("Check risk failed: %v", err)
+	}
+
+	state, err := m.state(ctx, req)
+	if err != nil {
+		return errors.Errorf("Check risk failed: %v", err)
+	}
+
+	position := 0.0
+	if state.Position != nil {
+		if position, err = netUnits(state.Position); err != nil {
+			return errors.Errorf("Check risk failed: %v", err)
+		}
+	}
+
+	if max := rules.maxUnits(req.Instrument); max > 0 {
+		if after := math.Abs(position + units); after > max && after > math.Abs(position) {
+			return newRiskViolation(RiskRuleMaxUnits, "%s position of %v units after the order exceeds the limit of %v units", req.Instrument, position+units, max)
+		}
+	}
+
+	if rules.MaxOpenPositions > 0 && position == 0 && state.Summary.OpenPositionCount != nil {
+		if after := *state.Summary.OpenPositionCount + 1; after > rules.MaxOpenPositions {
+			return newRiskViolation(RiskRuleMaxOpenPositions, "%d open positions after the order exceed the limit of %d", after, rules.MaxOpenPositions)
+		}
+	}
+
+	if rules.MinMarginAvailable > 0 {
+		available, err := parseDecimal(state.Summary.MarginAvailable)
+		if err != nil {
+			return errors.Errorf("Check risk failed: %v", err)
+		}
+		if available < rules.MinMarginAvailable {
+			return newRiskViolation(RiskRuleMinMarginAvailable, "Margin available of %v is below the minimum of %v", available, rules.MinMarginAvailable)
+		}
+	}
+
+	if rules.MaxNotional > 0 || rules.MaxPriceDistance > 0 {
+		last, err := lastPrice(state.Price, units)
+		if err != nil {
+			return errors.Errorf("Check risk failed: %v", err)
+		}
+
+		price := last
+		for _, p := range []PriceValueDefinition{req.Price, req.PriceBound} {
+			if p == "" {
+				continue
+			}
+			if price, err = parseDecimal(p); err != nil {
+				return errors.Errorf("Check risk failed: %v", err)
+			}
+			break
+		}
+
+		if rules.MaxPriceDistance > 0 {
+			if distance := math.Abs(price-last) / last; distance > rules.MaxPriceDistance {
+				return newRiskViolation(RiskRulePriceDistance, "Price %v of %s is %.2f%% away from the last price %v", price, req.Instrument, distance*100, last)
+			}
+		}
+
+		if rules.MaxNotional > 0 {
+			converter := NewHomeConverter(state.Summary.Currency)
+			if err := converter.Update(state.HomeConversions); err != nil {
+				return errors.Errorf("Check risk failed: %v", err)
+			}
+			if err := converter.UpdateFromPrice(state.Price); err != nil {
+				return errors.Errorf("Check risk failed: %v", err)
+			}
+			notional, err := converter.ConvertPositionValue(QuoteCurrency(req.Instrument), math.Abs(units)*price)
+			if err != nil {
+				return errors.Errorf("Check risk failed: %v", err)
+			}
+			if notional > rules.MaxNotional {
+				return newRiskViolation(RiskRuleMaxNotional, "Notional %v %s of the order exceeds the limit of %v", notional, state.Summary.Currency, rules.MaxNotional)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (m *RiskManager) state(ctx context.Context, req *OrderPolicyRequest) (*RiskState, error) {
+	if m.State != nil {
+		return m.State(ctx, req)
+	}
+
+	account := m.Connection.Accounts().AccountID(req.AccountID)
+	state := new(RiskState)
+
+	summary, err := account.Summary().Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	state.Summary = summary.Account
+
+	positions, err := account.OpenPositions().Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range positions.Positions {
+		if p.Instrument == req.Instrument {
+			state.Position = p
+		}
+	}
+
+	if m.Rules.MaxNotional > 0 || m.Rules.MaxPriceDistance > 0 {
+		pricing, err := account.Pricing().Get(ctx, &GetPricingParams{
+			Instruments:            []string{req.Instrument},
+			IncludeHomeConversions: Bool(true),
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(pricing.Prices) == 0 {
+			return nil, errors.Errorf("no price of %s", req.Instrument)
+		}
+		state.Price = pricing.Prices[0]
+		state.HomeConversions = pricing.HomeConversions
+	}
+
+	return state, nil
+}
+
+/* Utils */
+
+func (r *RiskRules) maxUnits(instrument InstrumentNameDefinition) float64 {
+	if max, ok := r.MaxUnits[instrument]; ok {
+		return max
+	}
+	return r.DefaultMaxUnits
+}
+
+func newRiskViolation(rule RiskRule, format string, args ...interface{}) *RiskViolation {
+	return &RiskViolation{
+		ErrorMessage: fmt.Sprintf(format, args...),
+		Rule:         rule,
+	}
+}
+
+func containsInstrument(instruments []InstrumentNameDefinition, instrument InstrumentNameDefinition) bool {
+	for _, i := range instruments {
+		if i == instrument {
+			return true
+		}
+	}
+	return false
+}
+
+func netUnits(p *PositionDefinition) (float64, error) {
+	net := 0.0
+	for _, side := range []*PositionSideDefinition{p.Long, p.Short} {
+		if side == nil || side.Units == "" {
+			continue
+		}
+		units, err := parseDecimal(side.Units)
+		if err != nil {
+			return 0, err
+		}
+		net += units
+	}
+	return net, nil
+}
+
+// lastPrice returns the price an order of units would be filled at, the ask
+// for buys and the bid for sells.
+func lastPrice(price *PriceDefinition, units float64) (float64, error) {
+	if price == nil {
+		return 0, errors.New("no last price")
+	}
+
+	buckets, closeout := price.Bids, price.CloseoutBid
+	if units > 0 {
+		buckets, closeout = price.Asks, price.CloseoutAsk
+	}
+	if len(buckets) > 0 {
+		return parseDecimal(buckets[0].Price)
+	}
+	if closeout != "" {
+		return parseDecimal(closeout)
+	}
+	return 0, errors.Errorf("no last price of %s", price.Instrument)
+}
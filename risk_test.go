@@ -0,0 +1,106 @@
+package oanda
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func Test_RiskManager(t *testing.T) {
+	state := &RiskState{
+		Summary: &AccountSummaryDefinition{
+			Currency:          "EUR",
+			OpenPositionCount: Int(2),
+			MarginAvailable:   "5000",
+		},
+		Position: &PositionDefinition{
+			Instrument: "EUR_USD",
+			Long:       &PositionSideDefinition{Units: "800"},
+			Short:      &PositionSideDefinition{Units: "0"},
+		},
+		Price: &PriceDefinition{
+			Instrument: "EUR_USD",
+			Bids:       []*PriceBucketDefinition{{Price: "1.0998"}},
+			Asks:       []*PriceBucketDefinition{{Price: "1.1000"}},
+		},
+		HomeConversions: []*HomeConversionsDefinition{
+			{Currency: "USD", AccountGain: "0.9", AccountLoss: "0.92", PositionValue: "0.91"},
+		},
+	}
+
+	manager := &RiskManager{
+		Rules: &RiskRules{
+			Instruments:        []InstrumentNameDefinition{"EUR_USD", "USD_JPY"},
+			MaxUnits:           map[InstrumentNameDefinition]float64{"EUR_USD": 1000},
+			MaxOpenPositions:   2,
+			MaxNotional:        10000,
+			MinMarginAvailable: 1000,
+			MaxPriceDistance:   0.01,
+		},
+		State: func(ctx context.Context, req *OrderPolicyRequest) (*RiskState, error) {
+			s := *state
+			if req.Instrument != "EUR_USD" {
+				s.Position = nil
+			}
+			return &s, nil
+		},
+	}
+
+	account := (&Connection{
+		Environemnt: OandaPractice,
+		DryRun:      true,
+		OrderPolicy: manager.Policy(),
+	}).Accounts().AccountID("101")
+
+	post := func(order OrderRequestDefinition) error {
+		_, err := account.Orders().Post(context.Background(), &PostOrdersParams{Body: PostOrdersBodyParams{Order: order}})
+		return err
+	}
+
+	patterns := []struct {
+		name   string
+		order  OrderRequestDefinition
+		expect RiskRule
+	}{
+		{"Passes", &MarketOrderRequestDefinition{Type: "MARKET", Instrument: "EUR_USD", Units: "200"}, ""},
+		{"ReducesPosition", &MarketOrderRequestDefinition{Type: "MARKET", Instrument: "EUR_USD", Units: "-1500"}, ""},
+		{"Whitelist", &MarketOrderRequestDefinition{Type: "MARKET", Instrument: "GBP_USD", Units: "1"}, RiskRuleInstrumentWhitelist},
+		{"MaxUnits", &MarketOrderRequestDefinition{Type: "MARKET", Instrument: "EUR_USD", Units: "201"}, RiskRuleMaxUnits},
+		{"MaxOpenPositions", &MarketOrderRequestDefinition{Type: "MARKET", Instrument: "USD_JPY", Units: "1"}, RiskRuleMaxOpenPositions},
+		{"PriceDistance", &LimitOrderRequestDefinition{Type: "LIMIT", Instrument: "EUR_USD", Units: "-100", Price: "1.2"}, RiskRulePriceDistance},
+	}
+
+	for _, pattern := range patterns {
+		t.Run(pattern.name, func(t *testing.T) {
+			err := post(pattern.order)
+			if pattern.expect == "" {
+				if err != nil {
+					t.Fatalf("Error occurred.\n%+v", err)
+				}
+				return
+			}
+
+			var violation *RiskViolation
+			if !errors.As(err, &violation) || violation.Rule != pattern.expect {
+				t.Fatalf("Got unexpected error.\nExpect: %s\nActual: %+v", pattern.expect, err)
+			}
+		})
+	}
+
+	t.Run("MaxNotional", func(t *testing.T) {
+		manager.Rules.MaxUnits = nil
+		// 10000 units at 1.1 USD are 10010 EUR.
+		err := manager.Check(context.Background(), &OrderPolicyRequest{Instrument: "EUR_USD", Units: "-10000", Price: "1.1"})
+		if violation, ok := err.(*RiskViolation); !ok || violation.Rule != RiskRuleMaxNotional {
+			t.Fatalf("Got unexpected error.\n%+v", err)
+		}
+	})
+
+	t.Run("MinMarginAvailable", func(t *testing.T) {
+		state.Summary.MarginAvailable = "999"
+		err := manager.Check(context.Background(), &OrderPolicyRequest{Instrument: "EUR_USD", Units: "1"})
+		if violation, ok := err.(*RiskViolation); !ok || violation.Rule != RiskRuleMinMarginAvailable {
+			t.Fatalf("Got unexpected error.\n%+v", err)
+		}
+	})
+}
@@ -0,0 +1,2040 @@
+package oanda
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+/* Params */
+
+type SimulatorParams struct {
+	// AccountID defaults to "101-001-0000000-001".
+	AccountID AccountIDDefinition
+	// Currency is the home currency, defaults to "USD".
+	Currency CurrencyDefinition
+	Balance  float64
+	// MarginRate defaults to 0.02, a leverage of 50:1.
+	MarginRate float64
+	// Financing holds the annual financing rates of instruments, which are
+	// charged or credited at the daily rollover. Positions of instruments
+	// without rates aren't financed.
+	Financing map[InstrumentNameDefinition]*SimulatorFinancing
+	// Slippage is the price distance by instrument that market, stop,
+	// market-if-touched, stop loss and trailing stop loss orders are filled
+	// worse than the current price. Limit and take profit orders don't slip.
+	Slippage map[InstrumentNameDefinition]float64
+}
+
+type SimulatorFinancing struct {
+	// Annual rates of the position value, e.g. -0.03 charges 3% a year.
+	LongRate  float64
+	ShortRate float64
+	// DaysCharged is the number of days financed at the rollover of a day
+	// of the week, e.g. 3 on Wednesdays and 0 on weekends. Every rollover
+	// finances a day when it is nil.
+	DaysCharged map[time.Weekday]int
+}
+
+// NewSimulatorFinancing converts the financing of an instrument as returned by
+// the instruments endpoint of an account.
+func NewSimulatorFinancing(def *InstrumentFinancingDefinition) (*SimulatorFinancing, error) {
+	f := new(SimulatorFinancing)
+	var err error
+	if f.LongRate, err = parseDecimal(def.LongRate); err != nil {
+		return nil, errors.Wrap(err, "Parse long rate failed")
+	}
+	if f.ShortRate, err = parseDecimal(def.ShortRate); err != nil {
+		return nil, errors.Wrap(err, "Parse short rate failed")
+	}
+	if len(def.FinancingDaysOfWeek) == 0 {
+		return f, nil
+	}
+	f.DaysCharged = make(map[time.Weekday]int)
+	for _, d := range def.FinancingDaysOfWeek {
+		day, ok := weekdays[d.DayOfWeek]
+		if !ok {
+			return nil, errors.Errorf("Unknown day of week %s", d.DayOfWeek)
+		}
+		if d.DaysCharged != nil {
+			f.DaysCharged[day] = *d.DaysCharged
+		}
+	}
+	return f, nil
+}
+
+var weekdays = map[DayOfWeekDefinition]time.Weekday{
+	"SUNDAY":    time.Sunday,
+	"MONDAY":    time.Monday,
+	"TUESDAY":   time.Tuesday,
+	"WEDNESDAY": time.Wednesday,
+	"THURSDAY":  time.Thursday,
+	"FRIDAY":    time.Friday,
+	"SATURDAY":  time.Saturday,
+}
+
+/* Simulator */
+
+// Simulator is a broker simulated behind a Connection. Set it as the
+// Transport of a Connection, feed it prices with UpdatePrice or Run and
+// trade through the API as usual:
+//
+//	sim := oanda.NewSimulator(&oanda.SimulatorParams{Balance: 100000})
+//	connection := &oanda.Connection{Environemnt: oanda.OandaPractice, Transport: sim}
+//	go sim.Run(ctx, replayedPrices)
+//
+// Market, limit, stop, market-if-touched, take profit, stop loss and trailing
+// stop loss orders are filled against the bid and ask of the latest prices
+// with unlimited liquidity. Positions aren't hedged, orders reduce opposite
+// trades first in FIFO order. Margin closeout happens when the NAV falls to
+// half of the margin used. Daily financing is booked at 17:00 New York time,
+// approximated as 21:00 UTC. Amounts in other currencies than the home
+// currency are converted with the latest prices, e.g. of USD_JPY for JPY
+// amounts of a USD account.
+//
+// The simulator serves the account, changes, instruments, order, trade,
+// position, pricing and transaction endpoints including the pricing and
+// transaction streams. Other requests get 404 Not Found.
+type Simulator struct {
+	accountID  AccountIDDefinition
+	currency   CurrencyDefinition
+	marginRate float64
+	financing  map[InstrumentNameDefinition]*SimulatorFinancing
+	slippage   map[InstrumentNameDefinition]float64
+
+	mu           sync.Mutex
+	now          time.Time
+	balance      float64
+	pl           float64
+	financed     float64
+	lastID       int
+	prices       map[InstrumentNameDefinition]*simPrice
+	orders       []*simOrder
+	trades       []*simTrade
+	transactions []*TransactionDefinition
+	priceSubs    map[*simSubscriber]bool
+	txSubs       map[*simSubscriber]bool
+}
+
+type simPrice struct {
+	bid, ask float64
+	def      *PriceDefinition
+	// The previous price while orders are triggered when the market moved
+	// continuously from it, e.g. within a candle. Orders crossed on the way
+	// are filled at their price.
+	prev *simPrice
+}
+
+type simOrder struct {
+	def        *OrderDefinition
+	units      float64
+	price      float64
+	priceBound float64
+	distance   float64
+	// The trade closed by the order, nil for entry orders.
+	trade *simTrade
+	// Reason of the fill, e.g. "MARKET_ORDER_TRADE_CLOSE".
+	reason Reason
+	// Whether the market was above the price of a market-if-touched order
+	// when it was created.
+	above bool
+}
+
+type simTrade struct {
+	def        *TradeDefinition
+	units      float64
+	price      float64
+	realizedPL float64
+	financing  float64
+	closed     float64
+	takeProfit *simOrder
+	stopLoss   *simOrder
+	trailing   *simOrder
+}
+
+// simBatch collects the transactions made by a request or a price.
+type simBatch struct {
+	id  TransactionIDDefinition
+	txs []*TransactionDefinition
+}
+
+type simSubscriber struct {
+	ch          chan interface{}
+	instruments map[InstrumentNameDefinition]bool
+}
+
+func NewSimulator(params *SimulatorParams) *Simulator {
+	s := &Simulator{
+		accountID:  params.AccountID,
+		currency:   params.Currency,
+		marginRate: params.MarginRate,
+		financing:  params.Financing,
+		slippage:   params.Slippage,
+		balance:    params.Balance,
+		prices:     make(map[InstrumentNameDefinition]*simPrice),
+		priceSubs:  make(map[*simSubscriber]bool),
+		txSubs:     make(map[*simSubscriber]bool),
+	}
+	if s.accountID == "" {
+		s.accountID = "101-001-0000000-001"
+	}
+	if s.currency == "" {
+		s.currency = "USD"
+	}
+	if s.marginRate <= 0 {
+		s.marginRate = 0.02
+	}
+	return s
+}
+
+/* Prices */
+
+// Run feeds the prices of chs to the simulator until chs is closed or ctx is
+// done. Heartbeats advance the clock of the simulator.
+func (s *Simulator) Run(ctx context.Context, chs *PriceChannels) error {
+	defer chs.Close()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case data, ok := <-chs.PriceCh:
+			if !ok {
+				return chs.Err()
+			}
+			if err := s.UpdatePrice(data); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// UpdatePrice makes price the current price of its instrument and fills the
+// orders it triggers. Heartbeats only advance the clock.
+func (s *Simulator) UpdatePrice(price *PriceDefinition) error {
+	return s.updatePrice(price, false)
+}
+
+// updatePrice updates the price, continuous tells whether the market moved
+// continuously from the previous price of the instrument.
+func (s *Simulator) updatePrice(price *PriceDefinition, continuous bool) error {
+	t, err := time.Parse(time.RFC3339Nano, price.Time)
+	if err != nil {
+		return errors.Errorf("Parse price time failed: %v", err)
+	}
+	var bid, ask float64
+	if price.Type != "HEARTBEAT" {
+		if len(price.Bids) == 0 || len(price.Asks) == 0 {
+			return errors.Errorf("Price of %s has no bids or asks", price.Instrument)
+		}
+		if bid, err = parseDecimal(price.Bids[0].Price); err != nil {
+			return err
+		}
+		if ask, err = parseDecimal(price.Asks[0].Price); err != nil {
+			return err
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b := new(simBatch)
+	s.advance(b, t)
+
+	if price.Type == "HEARTBEAT" {
+		s.commit(b)
+		return nil
+	}
+	p := &simPrice{bid: bid, ask: ask, def: price}
+	if continuous {
+		p.prev = s.prices[price.Instrument]
+	}
+	s.prices[price.Instrument] = p
+
+	s.trigger(b, price.Instrument)
+	// Orders placed later are filled at the price.
+	p.prev = nil
+	s.closeoutMargin(b)
+	s.commit(b)
+
+	for sub := range s.priceSubs {
+		if sub.instruments[price.Instrument] {
+			s.send(sub, price)
+		}
+	}
+	return nil
+}
+
+// advance moves the clock to t, expiring orders and booking the financing of
+// rollovers passed.
+func (s *Simulator) advance(b *simBatch, t time.Time) {
+	if !t.After(s.now) {
+		return
+	}
+	prev := s.now
+	s.now = t
+
+	if !prev.IsZero() {
+		rollover := time.Date(prev.Year(), prev.Month(), prev.Day(), 21, 0, 0, 0, time.UTC)
+		if !rollover.After(prev) {
+			rollover = rollover.AddDate(0, 0, 1)
+		}
+		for ; !rollover.After(t); rollover = rollover.AddDate(0, 0, 1) {
+			s.finance(b, rollover.Weekday())
+		}
+	}
+
+	for _, o := range s.pendingOrders() {
+		if o.def.TimeInForce != "GTD" {
+			continue
+		}
+		if gtd, err := time.Parse(time.RFC3339Nano, o.def.GtdTime); err == nil && !gtd.After(t) {
+			s.cancel(b, o, "TIME_IN_FORCE_EXPIRED")
+		}
+	}
+}
+
+// trigger fills the orders of instrument that the current price triggers.
+func (s *Simulator) trigger(b *simBatch, instrument InstrumentNameDefinition) {
+	p := s.prices[instrument]
+
+	for _, t := range s.openTrades() {
+		if t.def.Instrument != instrument {
+			continue
+		}
+		// The price closing the trade.
+		closing := p.bid
+		if t.units < 0 {
+			closing = p.ask
+		}
+		if o := t.trailing; o != nil {
+			if stop := closing - sign(t.units)*o.distance; sign(t.units)*(stop-o.price) > 0 {
+				o.price = stop
+				o.def.TrailingStopValue = formatPrice(stop)
+			}
+		}
+		for _, o := range []*simOrder{t.stopLoss, t.trailing, t.takeProfit} {
+			if o == nil || o.def.State != "PENDING" || t.units == 0 {
+				continue
+			}
+			// Stops trigger when the price moves against the trade, take
+			// profits when it moves in favor.
+			moved := sign(t.units) * (closing - o.price)
+			if (o.def.Type == "TAKE_PROFIT" && moved >= 0) || (o.def.Type != "TAKE_PROFIT" && moved <= 0) {
+				s.fill(b, o)
+			}
+		}
+	}
+
+	for _, o := range s.pendingOrders() {
+		if o.trade == nil && o.def.Instrument == instrument && s.triggered(o) {
+			s.fill(b, o)
+		}
+	}
+}
+
+// triggered returns whether the current price triggers the entry order o.
+func (s *Simulator) triggered(o *simOrder) bool {
+	p := s.prices[o.def.Instrument]
+	current := p.ask
+	if o.units < 0 {
+		current = p.bid
+	}
+	switch o.def.Type {
+	case "LIMIT":
+		return sign(o.units)*(current-o.price) <= 0
+	case "STOP":
+		return sign(o.units)*(current-o.price) >= 0
+	case "MARKET_IF_TOUCHED":
+		if o.above {
+			return current <= o.price
+		}
+		return current >= o.price
+	}
+	return false
+}
+
+// closeoutMargin closes all trades when the NAV fell to half of the margin
+// used.
+func (s *Simulator) closeoutMargin(b *simBatch) {
+	state := s.state()
+	if state.marginUsed == 0 || state.nav > state.marginUsed/2 {
+		return
+	}
+	for _, t := range s.openTrades() {
+		o := s.newOrder(b, &OrderDefinition{
+			Type:           "MARKET",
+			Instrument:     t.def.Instrument,
+			Units:          formatUnits(-t.units),
+			TimeInForce:    "FOK",
+			PositionFill:   "REDUCE_ONLY",
+			MarginCloseout: &MarketOrderMarginCloseoutDefinition{Reason: "MARGIN_CHECK_VIOLATION"},
+		}, "MARGIN_CLOSEOUT")
+		o.trade = t
+		o.reason = "MARKET_ORDER_MARGIN_CLOSEOUT"
+		s.fill(b, o)
+	}
+}
+
+// finance books the financing of the open trades at the rollover of day.
+func (s *Simulator) finance(b *simBatch, day time.Weekday) {
+	var total float64
+	var positions []*PositionFinancingDefinition
+	byInstrument := make(map[InstrumentNameDefinition]*PositionFinancingDefinition)
+
+	for _, t := range s.openTrades() {
+		rates := s.financing[t.def.Instrument]
+		p := s.prices[t.def.Instrument]
+		if rates == nil || p == nil {
+			continue
+		}
+		rate := rates.LongRate
+		if t.units < 0 {
+			rate = rates.ShortRate
+		}
+		days := 1
+		if rates.DaysCharged != nil {
+			if days = rates.DaysCharged[day]; days == 0 {
+				continue
+			}
+		}
+		factor, ok := s.homeFactor(QuoteCurrency(t.def.Instrument))
+		if !ok {
+			continue
+		}
+		amount := round(math.Abs(t.units) * (p.bid + p.ask) / 2 * factor * rate * float64(days) / 365)
+		t.financing += amount
+		total += amount
+
+		position := byInstrument[t.def.Instrument]
+		if position == nil {
+			position = &PositionFinancingDefinition{Instrument: t.def.Instrument}
+			byInstrument[t.def.Instrument] = position
+			positions = append(positions, position)
+		}
+		position.OpenTradeFinancings = append(position.OpenTradeFinancings, &OpenTradeFinancingDefinition{
+			TradeID:   t.def.ID,
+			Financing: formatAmount(amount),
+		})
+	}
+	if positions == nil {
+		return
+	}
+
+	for _, position := range positions {
+		var sum float64
+		for _, f := range position.OpenTradeFinancings {
+			amount, _ := parseDecimal(f.Financing)
+			sum += amount
+		}
+		position.Financing = formatAmount(sum)
+	}
+
+	s.balance += total
+	s.financed += total
+
+	tx := s.newTransaction(b, DailyFinancingTransaction)
+	tx.Financing = formatAmount(total)
+	tx.AccountBalance = formatAmount(s.balance)
+	tx.AccountFinancingMode = "DAILY"
+	tx.PositionFinancings = positions
+}
+
+/* Orders */
+
+// newOrder creates an order of def and its transaction.
+func (s *Simulator) newOrder(b *simBatch, def *OrderDefinition, reason Reason) *simOrder {
+	tx := s.newTransaction(b, def.Type+"_ORDER")
+	tx.Reason = reason
+	tx.Instrument = def.Instrument
+	tx.Units = def.Units
+	tx.PriceBound = def.PriceBound
+	tx.TimeInForce = def.TimeInForce
+	tx.GtdTime = def.GtdTime
+	tx.PositionFill = def.PositionFill
+	tx.TriggerCondition = def.TriggerCondition
+	tx.ClientExtensions = def.ClientExtensions
+	tx.TakeProfitOnFill = def.TakeProfitOnFill
+	tx.StopLossOnFill = def.StopLossOnFill
+	tx.TrailingStopLossOnFill = def.TrailingStopLossOnFill
+	tx.TradeClientExtensions = def.TradeClientExtensions
+	tx.TradeID = def.TradeID
+	tx.Distance = def.Distance
+	tx.TradeClose = def.TradeClose
+	tx.LongPositionCloseout = def.LongPositionCloseout
+	tx.ShortPositionCloseout = def.ShortPositionCloseout
+	tx.MarginCloseout = def.MarginCloseout
+	tx.ReplacesOrderID = def.ReplacesOrderID
+	if def.Price != "" {
+		tx.Price = def.Price
+	}
+
+	d := *def
+	d.ID = tx.ID
+	d.CreateTime = tx.Time
+	d.State = "PENDING"
+
+	o := &simOrder{def: &d, reason: Reason(def.Type + "_ORDER")}
+	o.units, _ = parseDecimal(def.Units)
+	o.price, _ = parseDecimal(def.Price)
+	o.priceBound, _ = parseDecimal(def.PriceBound)
+	o.distance, _ = parseDecimal(def.Distance)
+	s.orders = append(s.orders, o)
+	return o
+}
+
+// postOrder creates an order requested by a client, rejecting invalid
+// requests.
+func (s *Simulator) postOrder(b *simBatch, def *OrderDefinition) (*simOrder, *TransactionDefinition) {
+	if reason := s.checkOrder(def); reason != "" {
+		return nil, s.reject(b, def, reason)
+	}
+	return s.createOrder(b, def, "CLIENT_ORDER"), nil
+}
+
+// checkOrder sets the defaults of an order request and returns the reason to
+// reject it, empty when it is valid.
+func (s *Simulator) checkOrder(def *OrderDefinition) TransactionRejectReasonDefinition {
+	def.Type = strings.TrimSuffix(def.Type, "_ORDER")
+	switch def.Type {
+	case "MARKET":
+		setDefault(&def.TimeInForce, "FOK")
+	case "LIMIT", "STOP", "MARKET_IF_TOUCHED", "TAKE_PROFIT", "STOP_LOSS", "TRAILING_STOP_LOSS":
+		setDefault(&def.TimeInForce, "GTC")
+	default:
+		return "ORDER_TYPE_INVALID"
+	}
+	setDefault(&def.TriggerCondition, "DEFAULT")
+
+	if def.Type == "TAKE_PROFIT" || def.Type == "STOP_LOSS" || def.Type == "TRAILING_STOP_LOSS" {
+		t := s.findTrade(def.TradeID)
+		if t == nil || t.units == 0 {
+			return "TRADE_ID_UNSPECIFIED"
+		}
+		def.Instrument = t.def.Instrument
+
+		price, _ := parseDecimal(def.Price)
+		distance, _ := parseDecimal(def.Distance)
+		switch {
+		case def.Type == "TRAILING_STOP_LOSS" && distance <= 0:
+			return "TRAILING_STOP_LOSS_ORDER_DISTANCE_MISSING"
+		case def.Type == "STOP_LOSS" && price <= 0 && distance <= 0:
+			return "STOP_LOSS_ORDER_PRICE_MISSING"
+		case def.Type == "TAKE_PROFIT" && price <= 0:
+			return "TAKE_PROFIT_ORDER_PRICE_MISSING"
+		}
+		return ""
+	}
+
+	setDefault(&def.PositionFill, "DEFAULT")
+	if _, ok := s.prices[def.Instrument]; !ok {
+		return "INSTRUMENT_PRICE_UNKNOWN"
+	}
+	if units, err := parseDecimal(def.Units); err != nil || units == 0 {
+		return "UNITS_INVALID"
+	}
+	if def.Type != "MARKET" {
+		if price, err := parseDecimal(def.Price); err != nil || price <= 0 {
+			return "PRICE_INVALID"
+		}
+	}
+	if def.TimeInForce == "GTD" {
+		if _, err := time.Parse(time.RFC3339Nano, def.GtdTime); err != nil {
+			return "TIME_IN_FORCE_GTD_TIMESTAMP_MISSING"
+		}
+	}
+	return ""
+}
+
+// createOrder creates a valid order and fills it when it is triggered. A take
+// profit, stop loss or trailing stop loss order replaces the one its trade
+// has.
+func (s *Simulator) createOrder(b *simBatch, def *OrderDefinition, reason Reason) *simOrder {
+	if t := s.findTrade(def.TradeID); t != nil {
+		if current := t.dependent(def.Type); current != nil {
+			if *current != nil {
+				s.cancel(b, *current, "CLIENT_REQUEST_REPLACED")
+				reason = "REPLACEMENT"
+			}
+			return s.newDependentOrder(b, t, def, reason)
+		}
+	}
+
+	o := s.newOrder(b, def, reason)
+	if def.Type == "MARKET" {
+		s.fill(b, o)
+		return o
+	}
+	if def.Type == "MARKET_IF_TOUCHED" {
+		p := s.prices[def.Instrument]
+		o.above = p.ask > o.price
+		if o.units < 0 {
+			o.above = p.bid > o.price
+		}
+		o.def.InitialMarketPrice = formatPrice((p.bid + p.ask) / 2)
+	}
+	if s.triggered(o) {
+		s.fill(b, o)
+	}
+	return o
+}
+
+func (s *Simulator) newDependentOrder(b *simBatch, t *simTrade, def *OrderDefinition, reason Reason) *simOrder {
+	def.TradeID = t.def.ID
+	def.Instrument = t.def.Instrument
+	setDefault(&def.TimeInForce, "GTC")
+	setDefault(&def.TriggerCondition, "DEFAULT")
+
+	o := s.newOrder(b, def, reason)
+	o.trade = t
+	o.reason = Reason(def.Type + "_ORDER")
+	if o.price == 0 {
+		// Stops at a distance are placed from the price the trade closes at.
+		p := s.prices[t.def.Instrument]
+		closing := p.bid
+		if t.units < 0 {
+			closing = p.ask
+		}
+		o.price = closing - sign(t.units)*o.distance
+		if def.Type == "TRAILING_STOP_LOSS" {
+			o.def.TrailingStopValue = formatPrice(o.price)
+		} else {
+			o.def.Price = formatPrice(o.price)
+		}
+	}
+	*t.dependent(def.Type) = o
+	t.updateOrders()
+	return o
+}
+
+// reject records the rejection of an order request.
+func (s *Simulator) reject(b *simBatch, def *OrderDefinition, reason TransactionRejectReasonDefinition) *TransactionDefinition {
+	tx := s.newTransaction(b, strings.TrimSuffix(def.Type, "_ORDER")+"_ORDER_REJECT")
+	tx.Reason = "CLIENT_ORDER"
+	tx.RejectReason = reason
+	tx.Instrument = def.Instrument
+	tx.Units = def.Units
+	tx.TradeID = def.TradeID
+	tx.TimeInForce = def.TimeInForce
+	tx.ClientExtensions = def.ClientExtensions
+	return tx
+}
+
+// cancel cancels a pending order.
+func (s *Simulator) cancel(b *simBatch, o *simOrder, reason Reason) *TransactionDefinition {
+	tx := s.newTransaction(b, OrderCancelTransaction)
+	tx.OrderID = o.def.ID
+	tx.Reason = reason
+	if o.def.ClientExtensions != nil {
+		tx.ClientOrderID = o.def.ClientExtensions.ID
+	}
+
+	o.def.State = "CANCELLED"
+	o.def.CancellingTransactionID = tx.ID
+	o.def.CancelledTime = tx.Time
+	if t := o.trade; t != nil {
+		if current := t.dependent(o.def.Type); current != nil && *current == o {
+			*current = nil
+			t.updateOrders()
+		}
+	}
+	return tx
+}
+
+// fill fills an order at the current price, reducing opposite trades before
+// opening a trade. It returns the ORDER_FILL transaction, or the ORDER_CANCEL
+// transaction when the order can't be filled.
+func (s *Simulator) fill(b *simBatch, o *simOrder) *TransactionDefinition {
+	instrument := o.def.Instrument
+	p, ok := s.prices[instrument]
+	if !ok {
+		return s.cancel(b, o, "MARKET_HALTED")
+	}
+	factor, ok := s.homeFactor(QuoteCurrency(instrument))
+	if !ok {
+		return s.cancel(b, o, "MARKET_HALTED")
+	}
+
+	units := o.units
+	if o.trade != nil && units == 0 {
+		// Take profits and stops close the whole trade.
+		units = -o.trade.units
+	}
+	price := p.ask
+	if units < 0 {
+		price = p.bid
+	}
+	if o.price != 0 && p.crossed(units, o.price) {
+		price = o.price
+	}
+	if o.def.Type != "LIMIT" && o.def.Type != "TAKE_PROFIT" {
+		price += sign(units) * s.slippage[instrument]
+	}
+	if o.priceBound != 0 && sign(units)*(price-o.priceBound) > 0 {
+		return s.cancel(b, o, "BOUNDS_VIOLATION")
+	}
+
+	// Trades reduced by the order, in FIFO order.
+	var reduce []*simTrade
+	switch {
+	case o.trade != nil:
+		reduce = []*simTrade{o.trade}
+	case o.def.PositionFill != "OPEN_ONLY":
+		for _, t := range s.openTrades() {
+			if t.def.Instrument == instrument && sign(t.units) == -sign(units) {
+				reduce = append(reduce, t)
+			}
+		}
+	}
+	var reducible float64
+	for _, t := range reduce {
+		reducible += math.Abs(t.units)
+	}
+	if o.def.PositionFill == "OPEN_ONLY" && s.hasOpposite(instrument, units) {
+		return s.cancel(b, o, "FIFO_VIOLATION_SAFEGUARD_VIOLATION")
+	}
+
+	open := 0.0
+	if math.Abs(units) > reducible {
+		open = units + sign(-units)*reducible
+		if o.trade != nil || o.def.PositionFill == "REDUCE_ONLY" {
+			open = 0
+		}
+	}
+	if open == 0 && reducible == 0 {
+		return s.cancel(b, o, "POSITION_CLOSEOUT_FAILED")
+	}
+	margin := round(math.Abs(open) * (p.bid + p.ask) / 2 * factor * s.marginRate)
+	if open != 0 && margin > s.state().marginAvailable {
+		return s.cancel(b, o, "INSUFFICIENT_MARGIN")
+	}
+
+	tx := s.newTransaction(b, OrderFillTransaction)
+	tx.OrderID = o.def.ID
+	tx.Instrument = instrument
+	tx.Reason = o.reason
+	tx.FullPrice = &ClientPriceDefinition{
+		Bids:        p.def.Bids,
+		Asks:        p.def.Asks,
+		CloseoutBid: p.def.CloseoutBid,
+		CloseoutAsk: p.def.CloseoutAsk,
+		Timestamp:   p.def.Time,
+	}
+	tx.Price = formatPrice(price)
+	if o.def.ClientExtensions != nil {
+		tx.ClientOrderID = o.def.ClientExtensions.ID
+	}
+
+	var filled, pl float64
+	remaining := math.Abs(units)
+	for _, t := range reduce {
+		if remaining == 0 {
+			break
+		}
+		side := sign(t.units)
+		n := math.Min(remaining, math.Abs(t.units))
+		remaining -= n
+		filled -= side * n
+
+		tradePL := round(n * side * (price - t.price) * factor)
+		pl += tradePL
+		t.realizedPL += tradePL
+		t.closed += n * price
+		t.units -= side * n
+		t.def.CurrentUnits = formatUnits(t.units)
+		t.def.RealizedPL = formatAmount(t.realizedPL)
+		t.def.ClosingTransactionIDs = append(t.def.ClosingTransactionIDs, tx.ID)
+
+		reduced := &TradeReduceDefinition{
+			TradeID:        t.def.ID,
+			Units:          formatUnits(-side * n),
+			Price:          formatPrice(price),
+			RealizedPL:     formatAmount(tradePL),
+			Financing:      "0.0000",
+			HalfSpreadCost: formatAmount(n * (p.ask - p.bid) / 2 * factor),
+		}
+		if t.units == 0 {
+			initial, _ := parseDecimal(t.def.InitialUnits)
+			t.def.State = "CLOSED"
+			t.def.CloseTime = tx.Time
+			t.def.AverageClosePrice = formatPrice(t.closed / math.Abs(initial))
+			t.def.UnrealizedPL = ""
+			t.def.MarginUsed = ""
+			tx.TradesClosed = append(tx.TradesClosed, reduced)
+			o.def.TradeClosedIDs = append(o.def.TradeClosedIDs, t.def.ID)
+		} else {
+			tx.TradeReduced = reduced
+			o.def.TradeReducedID = t.def.ID
+		}
+	}
+
+	if open != 0 {
+		filled += open
+		t := &simTrade{
+			def: &TradeDefinition{
+				ID:                    tx.ID,
+				Instrument:            instrument,
+				Price:                 formatPrice(price),
+				OpenTime:              tx.Time,
+				State:                 "OPEN",
+				InitialUnits:          formatUnits(open),
+				InitialMarginRequired: formatAmount(margin),
+				CurrentUnits:          formatUnits(open),
+				RealizedPL:            "0.0000",
+				Financing:             "0.0000",
+				ClientExtensions:      o.def.TradeClientExtensions,
+			},
+			units: open,
+			price: price,
+		}
+		s.trades = append(s.trades, t)
+		tx.TradeOpened = &TradeOpenDefinition{
+			TradeID:               t.def.ID,
+			Units:                 formatUnits(open),
+			Price:                 formatPrice(price),
+			ClientExtensions:      o.def.TradeClientExtensions,
+			HalfSpreadCost:        formatAmount(math.Abs(open) * (p.ask - p.bid) / 2 * factor),
+			InitialMarginRequired: formatAmount(margin),
+		}
+		o.def.TradeOpenedID = t.def.ID
+	}
+
+	s.balance += pl
+	s.pl += pl
+	tx.Units = formatUnits(filled)
+	tx.PL = formatAmount(pl)
+	tx.Financing = "0.0000"
+	tx.Commission = "0.0000"
+	tx.HalfSpreadCost = formatAmount(math.Abs(filled) * (p.ask - p.bid) / 2 * factor)
+	tx.AccountBalance = formatAmount(s.balance)
+
+	o.def.State = "FILLED"
+	o.def.FillingTransactionID = tx.ID
+	o.def.FilledTime = tx.Time
+
+	// Orders of closed trades are cancelled, orders on fill are created for
+	// the opened trade.
+	for _, t := range reduce {
+		if t.units != 0 {
+			continue
+		}
+		for _, dependent := range []*simOrder{t.takeProfit, t.stopLoss, t.trailing} {
+			if dependent != nil && dependent != o {
+				s.cancel(b, dependent, "LINKED_TRADE_CLOSED")
+			}
+		}
+		t.takeProfit, t.stopLoss, t.trailing = nil, nil, nil
+		t.updateOrders()
+	}
+	if open != 0 {
+		t := s.trades[len(s.trades)-1]
+		if d := o.def.TakeProfitOnFill; d != nil && d.Price != "" {
+			s.newDependentOrder(b, t, &OrderDefinition{Type: "TAKE_PROFIT", Price: d.Price, TimeInForce: d.TimeInForce, GtdTime: d.GtdTime, ClientExtensions: d.ClientExtensions}, "ON_FILL")
+		}
+		if d := o.def.StopLossOnFill; d != nil && (d.Price != "" || d.Distance != "") {
+			s.newDependentOrder(b, t, &OrderDefinition{Type: "STOP_LOSS", Price: d.Price, Distance: d.Distance, TimeInForce: d.TimeInForce, GtdTime: d.GtdTime, ClientExtensions: d.ClientExtensions}, "ON_FILL")
+		}
+		if d := o.def.TrailingStopLossOnFill; d != nil && d.Distance != "" {
+			s.newDependentOrder(b, t, &OrderDefinition{Type: "TRAILING_STOP_LOSS", Distance: d.Distance, TimeInForce: d.TimeInForce, GtdTime: d.GtdTime, ClientExtensions: d.ClientExtensions}, "ON_FILL")
+		}
+	}
+	return tx
+}
+
+// crossed returns whether the market moved continuously through price on the
+// side filling units.
+func (p *simPrice) crossed(units, price float64) bool {
+	if p.prev == nil {
+		return false
+	}
+	from, to := p.prev.ask, p.ask
+	if units < 0 {
+		from, to = p.prev.bid, p.bid
+	}
+	return math.Min(from, to) <= price && price <= math.Max(from, to)
+}
+
+func (s *Simulator) hasOpposite(instrument InstrumentNameDefinition, units float64) bool {
+	for _, t := range s.openTrades() {
+		if t.def.Instrument == instrument && sign(t.units) == -sign(units) {
+			return true
+		}
+	}
+	return false
+}
+
+// closeTrade closes units of a trade, all of it when units is zero.
+func (s *Simulator) closeTrade(b *simBatch, t *simTrade, units float64) *TransactionDefinition {
+	if units == 0 {
+		units = math.Abs(t.units)
+	}
+	o := s.newOrder(b, &OrderDefinition{
+		Type:         "MARKET",
+		Instrument:   t.def.Instrument,
+		Units:        formatUnits(-sign(t.units) * units),
+		TimeInForce:  "FOK",
+		PositionFill: "REDUCE_ONLY",
+		TradeClose:   &MarketOrderTradeCloseDefinition{TradeID: t.def.ID, Units: formatUnits(units)},
+	}, "TRADE_CLOSE")
+	o.trade = t
+	o.reason = "MARKET_ORDER_TRADE_CLOSE"
+	return s.fill(b, o)
+}
+
+/* State */
+
+type simState struct {
+	unrealizedPL    float64
+	marginUsed      float64
+	positionValue   float64
+	nav             float64
+	marginAvailable float64
+}
+
+// state calculates the state of the account at the current prices and
+// updates the calculated fields of the open trades.
+func (s *Simulator) state() *simState {
+	state := new(simState)
+	for _, t := range s.openTrades() {
+		p := s.prices[t.def.Instrument]
+		factor, ok := s.homeFactor(QuoteCurrency(t.def.Instrument))
+		if p == nil || !ok {
+			continue
+		}
+		closing := p.bid
+		if t.units < 0 {
+			closing = p.ask
+		}
+		unrealizedPL := round(t.units * (closing - t.price) * factor)
+		value := math.Abs(t.units) * (p.bid + p.ask) / 2 * factor
+		marginUsed := round(value * s.marginRate)
+
+		t.def.UnrealizedPL = formatAmount(unrealizedPL)
+		t.def.MarginUsed = formatAmount(marginUsed)
+		state.unrealizedPL += unrealizedPL
+		state.marginUsed += marginUsed
+		state.positionValue += value
+	}
+	state.nav = s.balance + state.unrealizedPL
+	state.marginAvailable = math.Max(0, state.nav-state.marginUsed)
+	return state
+}
+
+// homeFactor returns the factor converting amounts in currency to the home
+// currency.
+func (s *Simulator) homeFactor(currency CurrencyDefinition) (float64, bool) {
+	if currency == s.currency {
+		return 1, true
+	}
+	if p, ok := s.prices[currency+"_"+s.currency]; ok {
+		return (p.bid + p.ask) / 2, true
+	}
+	if p, ok := s.prices[s.currency+"_"+currency]; ok {
+		return 2 / (p.bid + p.ask), true
+	}
+	return 0, false
+}
+
+func (s *Simulator) openTrades() []*simTrade {
+	var trades []*simTrade
+	for _, t := range s.trades {
+		if t.units != 0 {
+			trades = append(trades, t)
+		}
+	}
+	return trades
+}
+
+func (s *Simulator) pendingOrders() []*simOrder {
+	var orders []*simOrder
+	for _, o := range s.orders {
+		if o.def.State == "PENDING" {
+			orders = append(orders, o)
+		}
+	}
+	return orders
+}
+
+func (s *Simulator) findTrade(specifier TradeSpecifierDefinition) *simTrade {
+	for _, t := range s.trades {
+		if t.def.ID == specifier || (t.def.ClientExtensions != nil && "@"+t.def.ClientExtensions.ID == specifier) {
+			return t
+		}
+	}
+	return nil
+}
+
+func (s *Simulator) findOrder(specifier OrderSpecifierDefinition) *simOrder {
+	for _, o := range s.orders {
+		if o.def.ID == specifier || (o.def.ClientExtensions != nil && "@"+o.def.ClientExtensions.ID == specifier) {
+			return o
+		}
+	}
+	return nil
+}
+
+// dependent returns the field holding the dependent order of a type, nil for
+// other types.
+func (t *simTrade) dependent(typ OrderTypeDefinition) **simOrder {
+	switch typ {
+	case "TAKE_PROFIT":
+		return &t.takeProfit
+	case "STOP_LOSS":
+		return &t.stopLoss
+	case "TRAILING_STOP_LOSS":
+		return &t.trailing
+	}
+	return nil
+}
+
+func (t *simTrade) updateOrders() {
+	t.def.TakeProfitOrder, t.def.StopLossOrder, t.def.TrailingStopLossOrder = nil, nil, nil
+	if t.takeProfit != nil {
+		t.def.TakeProfitOrder = t.takeProfit.def
+	}
+	if t.stopLoss != nil {
+		t.def.StopLossOrder = t.stopLoss.def
+	}
+	if t.trailing != nil {
+		t.def.TrailingStopLossOrder = t.trailing.def
+	}
+}
+
+func (s *Simulator) tradeSummary(t *simTrade) *TradeSummaryDefinition {
+	d := t.def
+	summary := &TradeSummaryDefinition{
+		ID:                    d.ID,
+		Instrument:            d.Instrument,
+		Price:                 d.Price,
+		OpenTime:              d.OpenTime,
+		State:                 d.State,
+		InitialUnits:          d.InitialUnits,
+		InitialMarginRequired: d.InitialMarginRequired,
+		CurrentUnits:          d.CurrentUnits,
+		RealizedPL:            d.RealizedPL,
+		UnrealizedPL:          d.UnrealizedPL,
+		MarginUsed:            d.MarginUsed,
+		AverageClosePrice:     d.AverageClosePrice,
+		ClosingTransactionIDs: d.ClosingTransactionIDs,
+		Financing:             formatAmount(t.financing),
+		CloseTime:             d.CloseTime,
+		ClientExtensions:      d.ClientExtensions,
+	}
+	if t.takeProfit != nil {
+		summary.TakeProfitOrderID = t.takeProfit.def.ID
+	}
+	if t.stopLoss != nil {
+		summary.StopLossOrderID = t.stopLoss.def.ID
+	}
+	if t.trailing != nil {
+		summary.TrailingStopLossOrderID = t.trailing.def.ID
+	}
+	return summary
+}
+
+func (s *Simulator) trade(t *simTrade) *TradeDefinition {
+	d := *t.def
+	d.Financing = formatAmount(t.financing)
+	return &d
+}
+
+// positions returns the positions of all instruments traded, or of the
+// instruments with open trades.
+func (s *Simulator) positions(open bool) []*PositionDefinition {
+	var positions []*PositionDefinition
+	byInstrument := make(map[InstrumentNameDefinition]*PositionDefinition)
+	sums := make(map[*PositionSideDefinition]*[3]float64)
+
+	for _, t := range s.trades {
+		position := byInstrument[t.def.Instrument]
+		if position == nil {
+			position = &PositionDefinition{
+				Instrument: t.def.Instrument,
+				Long:       &PositionSideDefinition{},
+				Short:      &PositionSideDefinition{},
+			}
+			byInstrument[t.def.Instrument] = position
+			positions = append(positions, position)
+		}
+		initial, _ := parseDecimal(t.def.InitialUnits)
+		side := position.Long
+		if initial < 0 {
+			side = position.Short
+		}
+		sum := sums[side]
+		if sum == nil {
+			sum = new([3]float64)
+			sums[side] = sum
+		}
+		// Units, their cost and the unrealized P/L of the open trades.
+		if t.units != 0 {
+			unrealizedPL, _ := parseDecimal(t.def.UnrealizedPL)
+			sum[0] += t.units
+			sum[1] += t.units * t.price
+			sum[2] += unrealizedPL
+			side.TradeIDs = append(side.TradeIDs, t.def.ID)
+		}
+		pl, _ := parseDecimal(side.PL)
+		financing, _ := parseDecimal(side.Financing)
+		side.PL = formatAmount(pl + t.realizedPL)
+		side.Financing = formatAmount(financing + t.financing)
+	}
+
+	var result []*PositionDefinition
+	for _, position := range positions {
+		var pl, unrealizedPL, financing float64
+		for _, side := range []*PositionSideDefinition{position.Long, position.Short} {
+			sum := sums[side]
+			if sum == nil {
+				sum = new([3]float64)
+			}
+			side.Units = formatUnits(sum[0])
+			if sum[0] != 0 {
+				side.AveragePrice = formatPrice(sum[1] / sum[0])
+			}
+			setDefault(&side.PL, "0.0000")
+			setDefault(&side.Financing, "0.0000")
+			side.UnrealizedPL = formatAmount(sum[2])
+			side.ResettablePL = side.PL
+
+			sidePL, _ := parseDecimal(side.PL)
+			sideFinancing, _ := parseDecimal(side.Financing)
+			pl += sidePL
+			financing += sideFinancing
+			unrealizedPL += sum[2]
+		}
+		if open && len(position.Long.TradeIDs) == 0 && len(position.Short.TradeIDs) == 0 {
+			continue
+		}
+		position.PL = formatAmount(pl)
+		position.ResettablePL = position.PL
+		position.UnrealizedPL = formatAmount(unrealizedPL)
+		position.Financing = formatAmount(financing)
+		position.Commission = "0.0000"
+		var marginUsed float64
+		for _, id := range append(append([]TradeIDDefinition(nil), position.Long.TradeIDs...), position.Short.TradeIDs...) {
+			m, _ := parseDecimal(s.findTrade(id).def.MarginUsed)
+			marginUsed += m
+		}
+		position.MarginUsed = formatAmount(marginUsed)
+		result = append(result, position)
+	}
+	return result
+}
+
+func (s *Simulator) account() *AccountDefinition {
+	state := s.state()
+	hedging := false
+	openTrades := s.openTrades()
+	pending := s.pendingOrders()
+	positions := s.positions(true)
+	openTradeCount, openPositionCount, pendingOrderCount := len(openTrades), len(positions), len(pending)
+
+	account := &AccountDefinition{
+		ID:                          s.accountID,
+		Alias:                       "Simulator",
+		Currency:                    s.currency,
+		Balance:                     formatAmount(s.balance),
+		PL:                          formatAmount(s.pl),
+		ResettablePL:                formatAmount(s.pl),
+		Financing:                   formatAmount(s.financed),
+		Commission:                  "0.0000",
+		GuaranteedExecutionFees:     "0.0000",
+		MarginRate:                  formatPrice(s.marginRate),
+		OpenTradeCount:              &openTradeCount,
+		OpenPositionCount:           &openPositionCount,
+		PendingOrderCount:           &pendingOrderCount,
+		HedgingEnabled:              &hedging,
+		UnrealizedPL:                formatAmount(state.unrealizedPL),
+		NAV:                         formatAmount(state.nav),
+		MarginUsed:                  formatAmount(state.marginUsed),
+		MarginAvailable:             formatAmount(state.marginAvailable),
+		PositionValue:               formatAmount(state.positionValue),
+		MarginCloseoutUnrealizedPL:  formatAmount(state.unrealizedPL),
+		MarginCloseoutNAV:           formatAmount(state.nav),
+		MarginCloseoutMarginUsed:    formatAmount(state.marginUsed / 2),
+		MarginCloseoutPositionValue: formatAmount(state.positionValue),
+		MarginCloseoutPercent:       formatPercent(state.marginUsed/2, state.nav),
+		WithdrawalLimit:             formatAmount(state.marginAvailable),
+		MarginCallMarginUsed:        formatAmount(state.marginUsed),
+		MarginCallPercent:           formatPercent(state.marginUsed, state.nav),
+		LastTransactionID:           strconv.Itoa(s.lastID),
+		Positions:                   positions,
+	}
+	for _, t := range openTrades {
+		account.Trades = append(account.Trades, s.tradeSummary(t))
+	}
+	for _, o := range pending {
+		account.Orders = append(account.Orders, o.def)
+	}
+	return account
+}
+
+func (s *Simulator) summary() *AccountSummaryDefinition {
+	a := s.account()
+	return &AccountSummaryDefinition{
+		ID:                          a.ID,
+		Alias:                       a.Alias,
+		Currency:                    a.Currency,
+		Balance:                     a.Balance,
+		PL:                          a.PL,
+		ResettablePL:                a.ResettablePL,
+		Financing:                   a.Financing,
+		Commission:                  a.Commission,
+		GuaranteedExecutionFees:     a.GuaranteedExecutionFees,
+		MarginRate:                  a.MarginRate,
+		OpenTradeCount:              a.OpenTradeCount,
+		OpenPositionCount:           a.OpenPositionCount,
+		PendingOrderCount:           a.PendingOrderCount,
+		HedgingEnabled:              a.HedgingEnabled,
+		UnrealizedPL:                a.UnrealizedPL,
+		NAV:                         a.NAV,
+		MarginUsed:                  a.MarginUsed,
+		MarginAvailable:             a.MarginAvailable,
+		PositionValue:               a.PositionValue,
+		MarginCloseoutUnrealizedPL:  a.MarginCloseoutUnrealizedPL,
+		MarginCloseoutNAV:           a.MarginCloseoutNAV,
+		MarginCloseoutMarginUsed:    a.MarginCloseoutMarginUsed,
+		MarginCloseoutPercent:       a.MarginCloseoutPercent,
+		MarginCloseoutPositionValue: a.MarginCloseoutPositionValue,
+		WithdrawalLimit:             a.WithdrawalLimit,
+		MarginCallMarginUsed:        a.MarginCallMarginUsed,
+		MarginCallPercent:           a.MarginCallPercent,
+		LastTransactionID:           a.LastTransactionID,
+	}
+}
+
+// changes returns the changes of the account since a transaction and its
+// current state.
+func (s *Simulator) changes(since int) (*AccountChangesDefinition, *AccountChangesStateDefinition) {
+	after := func(id TransactionIDDefinition) bool {
+		n, err := strconv.Atoi(id)
+		return err == nil && n > since
+	}
+
+	changes := &AccountChangesDefinition{}
+	for _, tx := range s.transactions {
+		if after(tx.ID) {
+			changes.Transactions = append(changes.Transactions, tx)
+		}
+	}
+	for _, o := range s.orders {
+		if after(o.def.ID) {
+			changes.OrdersCreated = append(changes.OrdersCreated, o.def)
+		}
+		if after(o.def.CancellingTransactionID) {
+			changes.OrdersCancelled = append(changes.OrdersCancelled, o.def)
+		}
+		if after(o.def.FillingTransactionID) {
+			changes.OrdersFilled = append(changes.OrdersFilled, o.def)
+		}
+	}
+
+	account := s.account()
+	instruments := make(map[InstrumentNameDefinition]bool)
+	for _, t := range s.trades {
+		var reduced bool
+		for _, id := range t.def.ClosingTransactionIDs {
+			reduced = reduced || after(id)
+		}
+		switch {
+		case after(t.def.ID):
+			changes.TradesOpened = append(changes.TradesOpened, s.tradeSummary(t))
+		case reduced && t.units == 0:
+			changes.TradesClosed = append(changes.TradesClosed, s.tradeSummary(t))
+		case reduced:
+			changes.TradesReduced = append(changes.TradesReduced, s.tradeSummary(t))
+		default:
+			continue
+		}
+		instruments[t.def.Instrument] = true
+	}
+	for _, position := range s.positions(false) {
+		if instruments[position.Instrument] {
+			changes.Positions = append(changes.Positions, position)
+		}
+	}
+
+	state := &AccountChangesStateDefinition{
+		UnrealizedPL:                account.UnrealizedPL,
+		NAV:                         account.NAV,
+		MarginUsed:                  account.MarginUsed,
+		MarginAvailable:             account.MarginAvailable,
+		PositionValue:               account.PositionValue,
+		MarginCloseoutUnrealizedPL:  account.MarginCloseoutUnrealizedPL,
+		MarginCloseoutNAV:           account.MarginCloseoutNAV,
+		MarginCloseoutMarginUsed:    account.MarginCloseoutMarginUsed,
+		MarginCloseoutPercent:       account.MarginCloseoutPercent,
+		MarginCloseoutPositionValue: account.MarginCloseoutPositionValue,
+		WithdrawalLimit:             account.WithdrawalLimit,
+		MarginCallMarginUsed:        account.MarginCallMarginUsed,
+		MarginCallPercent:           account.MarginCallPercent,
+	}
+	for _, o := range account.Orders {
+		if o.Type == "TRAILING_STOP_LOSS" {
+			state.Orders = append(state.Orders, &DynamicOrderStateDefinition{ID: o.ID, TrailingStopValue: o.TrailingStopValue})
+		}
+	}
+	for _, t := range account.Trades {
+		state.Trades = append(state.Trades, &CalculatedTradeStateDefinition{ID: t.ID, UnrealizedPL: t.UnrealizedPL, MarginUsed: t.MarginUsed})
+	}
+	for _, p := range account.Positions {
+		state.Positions = append(state.Positions, &CalculatedPositionStateDefinition{
+			Instrument:        p.Instrument,
+			NetUnrealizedPL:   p.UnrealizedPL,
+			LongUnrealizedPL:  p.Long.UnrealizedPL,
+			ShortUnrealizedPL: p.Short.UnrealizedPL,
+			MarginUsed:        p.MarginUsed,
+		})
+	}
+	return changes, state
+}
+
+// instruments returns the instruments with a price, or those of names. Pip
+// locations are those of currency pairs, -2 for JPY quotes and -4 otherwise.
+func (s *Simulator) instruments(names []InstrumentNameDefinition) []*InstrumentDefinition {
+	if names == nil {
+		for name := range s.prices {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+
+	instruments := make([]*InstrumentDefinition, 0, len(names))
+	for _, name := range names {
+		if _, ok := s.prices[name]; !ok {
+			continue
+		}
+		pipLocation := -4
+		if QuoteCurrency(name) == "JPY" {
+			pipLocation = -2
+		}
+		displayPrecision, tradeUnitsPrecision := -pipLocation+1, 0
+		instruments = append(instruments, &InstrumentDefinition{
+			Name:                        name,
+			Type:                        "CURRENCY",
+			DisplayName:                 strings.Replace(name, "_", "/", 1),
+			PipLocation:                 &pipLocation,
+			DisplayPrecision:            &displayPrecision,
+			TradeUnitsPrecision:         &tradeUnitsPrecision,
+			MinimumTradeSize:            "1",
+			MaximumTrailingStopDistance: "1.00000",
+			MinimumTrailingStopDistance: "0.00050",
+			MaximumPositionSize:         "0",
+			MaximumOrderUnits:           "100000000",
+			MarginRate:                  formatPrice(s.marginRate),
+		})
+	}
+	return instruments
+}
+
+/* Transactions */
+
+func (s *Simulator) newTransaction(b *simBatch, typ TransactionTypeDefinition) *TransactionDefinition {
+	s.lastID++
+	tx := &TransactionDefinition{
+		ID:        strconv.Itoa(s.lastID),
+		AccountID: s.accountID,
+		Time:      s.timestamp(),
+		Type:      typ,
+	}
+	if b.id == "" {
+		b.id = tx.ID
+	}
+	tx.BatchID = b.id
+	b.txs = append(b.txs, tx)
+	return tx
+}
+
+// commit records the transactions of a batch and sends them to the
+// transaction streams.
+func (s *Simulator) commit(b *simBatch) {
+	s.transactions = append(s.transactions, b.txs...)
+	for _, tx := range b.txs {
+		for sub := range s.txSubs {
+			s.send(sub, tx)
+		}
+	}
+}
+
+// send sends a message to a stream, disconnecting streams that fall behind.
+func (s *Simulator) send(sub *simSubscriber, message interface{}) {
+	select {
+	case sub.ch <- message:
+	default:
+		delete(s.priceSubs, sub)
+		delete(s.txSubs, sub)
+		close(sub.ch)
+	}
+}
+
+func (b *simBatch) ids() []TransactionIDDefinition {
+	ids := make([]TransactionIDDefinition, 0, len(b.txs))
+	for _, tx := range b.txs {
+		ids = append(ids, tx.ID)
+	}
+	return ids
+}
+
+// timestamp returns the time of the latest price, the wall clock before the
+// first price.
+func (s *Simulator) timestamp() DateTimeDefinition {
+	if s.now.IsZero() {
+		return time.Now().UTC().Format(time.RFC3339Nano)
+	}
+	return s.now.UTC().Format(time.RFC3339Nano)
+}
+
+/* API */
+
+// RoundTrip serves a request to the API.
+func (s *Simulator) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = ioutil.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+	}
+
+	path := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+	if len(path) < 2 || path[0] != "v3" || path[1] != "accounts" {
+		return s.respond(req, 404, &NotFoundError{ErrorMessage: "The simulator doesn't serve " + req.URL.Path})
+	}
+	if len(path) == 2 && req.Method == "GET" {
+		return s.respond(req, 200, &GetAccountsSchema{Accounts: []*AccountPropertiesDefinition{{ID: s.accountID, Tags: []string{}}}})
+	}
+	if len(path) < 3 || path[2] != s.accountID {
+		return s.respond(req, 404, &NotFoundError{ErrorMessage: "The Account specified does not exist", ErrorCode: "ACCOUNT_NOT_FOUND"})
+	}
+
+	route := strings.Join(path[3:], "/")
+	switch {
+	case req.Method == "GET" && route == "pricing/stream":
+		return s.openStream(req, strings.Split(req.URL.Query().Get("instruments"), ","))
+	case req.Method == "GET" && route == "transactions/stream":
+		return s.openStream(req, nil)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status, data := s.serve(req.Method, path[3:], req.URL.Query(), body)
+	return s.respond(req, status, data)
+}
+
+func (s *Simulator) respond(req *http.Request, status int, data interface{}) (*http.Response, error) {
+	resp, err := jsonResponse(status, data, "simulator")
+	if err != nil {
+		return nil, err
+	}
+	resp.Request = req
+	return resp, nil
+}
+
+// serve serves the request to a route of the account.
+func (s *Simulator) serve(method string, route []string, query map[string][]string, body []byte) (int, interface{}) {
+	lastID := func() TransactionIDDefinition { return strconv.Itoa(s.lastID) }
+	notFound := func(msg string) (int, interface{}) {
+		return 404, &NotFoundError{ErrorMessage: msg, LastTransactionID: lastID()}
+	}
+	get := func(k string) string {
+		if v := query[k]; len(v) > 0 {
+			return v[0]
+		}
+		return ""
+	}
+
+	switch {
+	case method == "GET" && len(route) == 0:
+		return 200, &GetAccountIDSchema{Account: s.account(), LastTransactionID: lastID()}
+
+	case method == "GET" && match(route, "summary"):
+		return 200, &GetAccountSummarySchema{Account: s.summary(), LastTransactionID: lastID()}
+
+	case method == "GET" && match(route, "pricing"):
+		prices := make([]*PriceDefinition, 0)
+		for _, instrument := range strings.Split(get("instruments"), ",") {
+			if p, ok := s.prices[instrument]; ok {
+				prices = append(prices, p.def)
+			}
+		}
+		return 200, &GetPricingSchema{Prices: prices, Time: s.timestamp()}
+
+	case method == "POST" && match(route, "orders"):
+		var params struct {
+			Order *OrderDefinition `json:"order"`
+		}
+		if err := json.Unmarshal(body, &params); err != nil || params.Order == nil {
+			return 400, &PostOrdersBadRequestError{ErrorCode: "INVALID_ORDER", ErrorMessage: "Invalid order request", LastTransactionID: lastID()}
+		}
+		b := new(simBatch)
+		o, rejected := s.postOrder(b, params.Order)
+		s.commit(b)
+		if rejected != nil {
+			return 400, &PostOrdersBadRequestError{
+				OrderRejectTransaction: rejected,
+				RelatedTransactionIDs:  b.ids(),
+				LastTransactionID:      lastID(),
+				ErrorCode:              rejected.RejectReason,
+				ErrorMessage:           "The order request was rejected: " + rejected.RejectReason,
+			}
+		}
+		resp := &PostOrdersSchema{
+			RelatedTransactionIDs: b.ids(),
+			LastTransactionID:     lastID(),
+		}
+		// Replacing a dependent order cancels the replaced order first.
+		for _, tx := range b.txs {
+			switch {
+			case tx.ID == o.def.ID:
+				resp.OrderCreateTransaction = tx
+			case tx.OrderID != o.def.ID:
+			case tx.Type == OrderFillTransaction:
+				resp.OrderFillTransaction = tx
+			case tx.Type == OrderCancelTransaction:
+				resp.OrderCancelTransaction = tx
+			}
+		}
+		return 201, resp
+
+	case method == "GET" && (match(route, "orders") || match(route, "pendingOrders")):
+		state := get("state")
+		if route[0] == "pendingOrders" || state == "" {
+			state = "PENDING"
+		}
+		var ids map[string]bool
+		if v := get("ids"); v != "" {
+			ids = make(map[string]bool)
+			for _, id := range strings.Split(v, ",") {
+				ids[id] = true
+			}
+		}
+		count, _ := strconv.Atoi(get("count"))
+		if count <= 0 {
+			count = 50
+		}
+		orders := make([]*OrderDefinition, 0)
+		for i := len(s.orders) - 1; i >= 0 && len(orders) < count; i-- {
+			o := s.orders[i].def
+			if (state == "ALL" || o.State == state) &&
+				(get("instrument") == "" || o.Instrument == get("instrument")) &&
+				(ids == nil || ids[o.ID]) {
+				orders = append(orders, o)
+			}
+		}
+		if route[0] == "pendingOrders" {
+			return 200, &GetPendingOrdersSchema{Orders: orders, LastTransactionID: lastID()}
+		}
+		return 200, &GetOrdersSchema{Orders: orders, LastTransactionID: lastID()}
+
+	case method == "GET" && match(route, "orders", ""):
+		o := s.findOrder(route[1])
+		if o == nil {
+			return notFound("The Order specified does not exist")
+		}
+		return 200, &GetOrderSpecifierSchema{Order: o.def, LastTransactionID: lastID()}
+
+	case method == "PUT" && match(route, "orders", "", "cancel"):
+		o := s.findOrder(route[1])
+		if o == nil || o.def.State != "PENDING" {
+			return 404, &PutOrderSpecifierCancelNotFoundError{ErrorCode: "ORDER_DOESNT_EXIST", ErrorMessage: "The Order specified does not exist", LastTransactionID: lastID()}
+		}
+		b := new(simBatch)
+		tx := s.cancel(b, o, "CLIENT_REQUEST")
+		s.commit(b)
+		return 200, &PutOrderSpecifierCancelSchema{OrderCancelTransaction: tx, RelatedTransactionIDs: b.ids(), LastTransactionID: lastID()}
+
+	case method == "PUT" && match(route, "orders", ""):
+		replaced := s.findOrder(route[1])
+		if replaced == nil || replaced.def.State != "PENDING" {
+			return 404, &PutOrderSpecifierNotFoundError{ErrorCode: "ORDER_DOESNT_EXIST", ErrorMessage: "The Order specified does not exist", LastTransactionID: lastID()}
+		}
+		var params struct {
+			Order *OrderDefinition `json:"order"`
+		}
+		if err := json.Unmarshal(body, &params); err != nil || params.Order == nil {
+			return 400, &PutOrderSpecifierBadRequestError{ErrorCode: "INVALID_ORDER", ErrorMessage: "Invalid order request", LastTransactionID: lastID()}
+		}
+		def := params.Order
+		if replaced.trade != nil {
+			setDefault(&def.TradeID, replaced.trade.def.ID)
+		}
+		b := new(simBatch)
+		if reason := s.checkOrder(def); reason != "" {
+			tx := s.reject(b, def, reason)
+			tx.IntendedReplacesOrderID = replaced.def.ID
+			s.commit(b)
+			return 400, &PutOrderSpecifierBadRequestError{
+				OrderRejectTransaction: tx,
+				RelatedTransactionIDs:  b.ids(),
+				LastTransactionID:      lastID(),
+				ErrorCode:              reason,
+				ErrorMessage:           "The order request was rejected: " + reason,
+			}
+		}
+		resp := &PutOrderSpecifierSchema{OrderCancelTransaction: s.cancel(b, replaced, "CLIENT_REQUEST_REPLACED")}
+		def.ReplacesOrderID = replaced.def.ID
+		o := s.createOrder(b, def, "REPLACEMENT")
+		replaced.def.ReplacedByOrderID = o.def.ID
+		s.commit(b)
+		for _, tx := range b.txs {
+			switch {
+			case tx.ID == o.def.ID:
+				resp.OrderCreateTransaction = tx
+			case tx.OrderID != o.def.ID:
+			case tx.Type == OrderFillTransaction:
+				resp.OrderFillTransaction = tx
+			case tx.Type == OrderCancelTransaction:
+				resp.ReplacingOrderCancelTransaction = tx
+			}
+		}
+		resp.RelatedTransactionIDs = b.ids()
+		resp.LastTransactionID = lastID()
+		return 201, resp
+
+	case method == "GET" && (match(route, "trades") || match(route, "openTrades")):
+		s.state()
+		state := get("state")
+		if route[0] == "openTrades" || state == "" {
+			state = "OPEN"
+		}
+		trades := make([]*TradeDefinition, 0)
+		for i := len(s.trades) - 1; i >= 0; i-- {
+			t := s.trades[i]
+			if (state == "ALL" || t.def.State == state) && (get("instrument") == "" || t.def.Instrument == get("instrument")) {
+				trades = append(trades, s.trade(t))
+			}
+		}
+		if route[0] == "openTrades" {
+			return 200, &GetOpenTradesSchema{Trades: trades, LastTransactionID: lastID()}
+		}
+		return 200, &GetTradesSchema{Trades: trades, LastTransactionID: lastID()}
+
+	case method == "GET" && match(route, "trades", ""):
+		s.state()
+		t := s.findTrade(route[1])
+		if t == nil {
+			return notFound("The Trade specified does not exist")
+		}
+		return 200, &GetTradeSpecifierSchema{Trade: s.trade(t), LastTransactionID: lastID()}
+
+	case method == "PUT" && match(route, "trades", "", "close"):
+		t := s.findTrade(route[1])
+		if t == nil || t.units == 0 {
+			return 404, &PutTradeSpecifierCloseNotFoundError{ErrorCode: "TRADE_DOESNT_EXIST", ErrorMessage: "The Trade specified does not exist", LastTransactionID: lastID()}
+		}
+		var params PutTradeSpecifierCloseBodyParams
+		if len(body) > 0 {
+			if err := json.Unmarshal(body, &params); err != nil {
+				return 400, &PutTradeSpecifierCloseBadRequestError{ErrorCode: "INVALID_PARAMETER", ErrorMessage: "Invalid close request"}
+			}
+		}
+		var units float64
+		if params.Units != "" && params.Units != "ALL" {
+			var err error
+			if units, err = parseDecimal(params.Units); err != nil || units <= 0 || units > math.Abs(t.units) {
+				return 400, &PutTradeSpecifierCloseBadRequestError{ErrorCode: "CLOSE_TRADE_UNITS_EXCEED_TRADE_SIZE", ErrorMessage: "Invalid units to close"}
+			}
+		}
+		b := new(simBatch)
+		tx := s.closeTrade(b, t, units)
+		s.commit(b)
+		resp := &PutTradeSpecifierCloseSchema{OrderCreateTransaction: b.txs[0], RelatedTransactionIDs: b.ids(), LastTransactionID: lastID()}
+		if tx.Type == OrderFillTransaction {
+			resp.OrderFillTransaction = tx
+		} else {
+			resp.OrderCancelTransaction = tx
+		}
+		return 200, resp
+
+	case method == "PUT" && match(route, "trades", "", "orders"):
+		t := s.findTrade(route[1])
+		if t == nil || t.units == 0 {
+			return notFound("The Trade specified does not exist")
+		}
+		// An order set to null is cancelled, a missing one is kept.
+		var params map[string]*json.RawMessage
+		if err := json.Unmarshal(body, &params); err != nil {
+			return 400, &PutTradeSpecifierOrdersBadRequestError{ErrorCode: "INVALID_PARAMETER", ErrorMessage: "Invalid orders request", LastTransactionID: lastID()}
+		}
+		resp := &PutTradeSpecifierOrdersSchema{}
+		rejected := &PutTradeSpecifierOrdersBadRequestError{}
+		type dependent struct {
+			key    string
+			typ    OrderTypeDefinition
+			def    *OrderDefinition
+			cancel **TransactionDefinition
+			create **TransactionDefinition
+			reject **TransactionDefinition
+		}
+		var dependents []*dependent
+		for _, d := range []*dependent{
+			{key: "takeProfit", typ: "TAKE_PROFIT", cancel: &resp.TakeProfitOrderCancelTransaction, create: &resp.TakeProfitOrderTransaction, reject: &rejected.TakeProfitOrderRejectTransaction},
+			{key: "stopLoss", typ: "STOP_LOSS", cancel: &resp.StopLossOrderCancelTransaction, create: &resp.StopLossOrderTransaction, reject: &rejected.StopLossOrderRejectTransaction},
+			{key: "trailingStopLoss", typ: "TRAILING_STOP_LOSS", cancel: &resp.TrailingStopLossOrderCancelTransaction, create: &resp.TrailingStopLossOrderTransaction, reject: &rejected.TrailingStopLossOrderRejectTransaction},
+		} {
+			raw, ok := params[d.key]
+			if !ok {
+				continue
+			}
+			if raw != nil {
+				d.def = new(OrderDefinition)
+				if err := json.Unmarshal(*raw, d.def); err != nil {
+					return 400, &PutTradeSpecifierOrdersBadRequestError{ErrorCode: "INVALID_PARAMETER", ErrorMessage: "Invalid orders request", LastTransactionID: lastID()}
+				}
+				d.def.Type, d.def.TradeID = d.typ, t.def.ID
+			}
+			dependents = append(dependents, d)
+		}
+
+		// All orders are validated before any is replaced.
+		b := new(simBatch)
+		for _, d := range dependents {
+			if d.def == nil {
+				continue
+			}
+			if reason := s.checkOrder(d.def); reason != "" {
+				*d.reject = s.reject(b, d.def, reason)
+				if rejected.ErrorCode == "" {
+					rejected.ErrorCode = reason
+					rejected.ErrorMessage = "The order request was rejected: " + reason
+				}
+			}
+		}
+		if len(b.txs) > 0 {
+			s.commit(b)
+			rejected.RelatedTransactionIDs = b.ids()
+			rejected.LastTransactionID = lastID()
+			return 400, rejected
+		}
+
+		for _, d := range dependents {
+			reason := Reason("CLIENT_ORDER")
+			if current := t.dependent(d.typ); *current != nil {
+				cancelReason := Reason("CLIENT_REQUEST")
+				if d.def != nil {
+					cancelReason, reason = "CLIENT_REQUEST_REPLACED", "REPLACEMENT"
+				}
+				*d.cancel = s.cancel(b, *current, cancelReason)
+			}
+			if d.def != nil {
+				s.newDependentOrder(b, t, d.def, reason)
+				*d.create = b.txs[len(b.txs)-1]
+			}
+		}
+		s.commit(b)
+		resp.RelatedTransactionIDs = b.ids()
+		resp.LastTransactionID = lastID()
+		return 200, resp
+
+	case method == "PUT" && match(route, "trades", "", "clientExtensions"):
+		t := s.findTrade(route[1])
+		if t == nil {
+			return 404, &PutTradeSpecifierClientExtensionsNotFoundError{ErrorCode: "TRADE_DOESNT_EXIST", ErrorMessage: "The Trade specified does not exist", LastTransactionID: lastID()}
+		}
+		var params PutTradeSpecifierClientExtensionsBodyParams
+		if err := json.Unmarshal(body, &params); err != nil || params.ClientExtensions == nil {
+			return 400, &PutTradeSpecifierClientExtensionsBadRequestError{ErrorCode: "INVALID_PARAMETER", ErrorMessage: "Invalid client extensions request", LastTransactionID: lastID()}
+		}
+		extensions := new(ClientExtensionsDefinition)
+		if t.def.ClientExtensions != nil {
+			*extensions = *t.def.ClientExtensions
+		}
+		// Fields missing from the request keep their values.
+		setDefault(&params.ClientExtensions.ID, extensions.ID)
+		setDefault(&params.ClientExtensions.Tag, extensions.Tag)
+		setDefault(&params.ClientExtensions.Comment, extensions.Comment)
+
+		b := new(simBatch)
+		tx := s.newTransaction(b, TradeClientExtensionsModifyTransaction)
+		tx.TradeID = t.def.ID
+		tx.ClientTradeID = extensions.ID
+		tx.TradeClientExtensionsModify = params.ClientExtensions
+		t.def.ClientExtensions = params.ClientExtensions
+		s.commit(b)
+		return 200, &PutTradeSpecifierClientExtensionsSchema{TradeClientExtensionsModifyTransaction: tx, RelatedTransactionIDs: b.ids(), LastTransactionID: lastID()}
+
+	case method == "GET" && (match(route, "positions") || match(route, "openPositions")):
+		s.state()
+		positions := s.positions(route[0] == "openPositions")
+		if route[0] == "openPositions" {
+			return 200, &GetOpenPositionsSchema{Positions: positions, LastTransactionID: lastID()}
+		}
+		return 200, &GetPositionsSchema{Positions: positions, LastTransactionID: lastID()}
+
+	case method == "GET" && match(route, "positions", ""):
+		s.state()
+		for _, position := range s.positions(false) {
+			if position.Instrument == route[1] {
+				return 200, &GetPositionsInstrumentSchema{Position: position, LastTransactionID: lastID()}
+			}
+		}
+		return notFound("The Position specified does not exist")
+
+	case method == "PUT" && match(route, "positions", "", "close"):
+		params := PutPositionsInstrumentCloseBodyParams{LongUnits: "ALL", ShortUnits: "ALL"}
+		if len(body) > 0 {
+			params = PutPositionsInstrumentCloseBodyParams{}
+			if err := json.Unmarshal(body, &params); err != nil {
+				return 400, &PutPositionsInstrumentCloseBadRequestError{ErrorCode: "INVALID_PARAMETER", ErrorMessage: "Invalid close request", LastTransactionID: lastID()}
+			}
+		}
+		resp := &PutPositionsInstrumentCloseSchema{}
+		type closeSide struct {
+			units    float64
+			sign     float64
+			closeout *MarketOrderPositionCloseoutDefinition
+			create   **TransactionDefinition
+			fill     **TransactionDefinition
+			cancel   **TransactionDefinition
+		}
+		// Both sides are validated before any is closed. ALL skips a side
+		// without trades.
+		var sides []*closeSide
+		for _, side := range []struct {
+			param string
+			*closeSide
+		}{
+			{params.LongUnits, &closeSide{sign: 1, create: &resp.LongOrderCreateTransaction, fill: &resp.LongOrderFillTransaction, cancel: &resp.LongOrderCancelTransaction}},
+			{params.ShortUnits, &closeSide{sign: -1, create: &resp.ShortOrderCreateTransaction, fill: &resp.ShortOrderFillTransaction, cancel: &resp.ShortOrderCancelTransaction}},
+		} {
+			if side.param == "" || side.param == "NONE" {
+				continue
+			}
+			var open float64
+			for _, t := range s.openTrades() {
+				if t.def.Instrument == route[1] && sign(t.units) == side.sign {
+					open += math.Abs(t.units)
+				}
+			}
+			if side.param == "ALL" && open == 0 {
+				continue
+			}
+			side.units = open
+			if side.param != "ALL" {
+				var err error
+				if side.units, err = parseDecimal(side.param); err != nil {
+					side.units = 0
+				}
+			}
+			if side.units <= 0 || side.units > open {
+				return 400, &PutPositionsInstrumentCloseBadRequestError{ErrorCode: "CLOSEOUT_POSITION_DOESNT_EXIST", ErrorMessage: "The Position requested to be closed out does not exist", LastTransactionID: lastID()}
+			}
+			side.closeout = &MarketOrderPositionCloseoutDefinition{Instrument: route[1], Units: side.param}
+			sides = append(sides, side.closeSide)
+		}
+		if len(sides) == 0 {
+			return 400, &PutPositionsInstrumentCloseBadRequestError{ErrorCode: "CLOSEOUT_POSITION_DOESNT_EXIST", ErrorMessage: "The Position requested to be closed out does not exist", LastTransactionID: lastID()}
+		}
+
+		b := new(simBatch)
+		for _, side := range sides {
+			def := &OrderDefinition{
+				Type:         "MARKET",
+				Instrument:   route[1],
+				Units:        formatUnits(-side.sign * side.units),
+				TimeInForce:  "FOK",
+				PositionFill: "REDUCE_ONLY",
+			}
+			if side.sign > 0 {
+				def.LongPositionCloseout = side.closeout
+			} else {
+				def.ShortPositionCloseout = side.closeout
+			}
+			o := s.newOrder(b, def, "POSITION_CLOSEOUT")
+			o.reason = "MARKET_ORDER_POSITION_CLOSEOUT"
+			*side.create = b.txs[len(b.txs)-1]
+			if tx := s.fill(b, o); tx.Type == OrderFillTransaction {
+				*side.fill = tx
+			} else {
+				*side.cancel = tx
+			}
+		}
+		s.commit(b)
+		resp.RelatedTransactionIDs = b.ids()
+		resp.LastTransactionID = lastID()
+		return 200, resp
+
+	case method == "GET" && match(route, "changes"):
+		since, err := strconv.Atoi(get("sinceTransactionID"))
+		if err != nil || since < 0 || since > s.lastID {
+			return 400, &BadRequestError{ErrorMessage: "Invalid value specified for 'sinceTransactionID'"}
+		}
+		changes, state := s.changes(since)
+		return 200, &GetAccountChangesSchema{Changes: changes, State: state, LastTransactionID: lastID()}
+
+	case method == "GET" && match(route, "instruments"):
+		var names []InstrumentNameDefinition
+		if v := get("instruments"); v != "" {
+			names = strings.Split(v, ",")
+		}
+		return 200, &GetAccountInstrumentsSchema{Instruments: s.instruments(names), LastTransactionID: lastID()}
+
+	case method == "GET" && (match(route, "transactions", "idrange") || match(route, "transactions", "sinceid")):
+		from, _ := strconv.Atoi(get("from"))
+		to, _ := strconv.Atoi(get("to"))
+		if route[1] == "sinceid" {
+			id, _ := strconv.Atoi(get("id"))
+			from, to = id+1, s.lastID
+		}
+		transactions := make([]*TransactionDefinition, 0)
+		for _, tx := range s.transactions {
+			if id, _ := strconv.Atoi(tx.ID); id >= from && id <= to {
+				transactions = append(transactions, tx)
+			}
+		}
+		if route[1] == "sinceid" {
+			return 200, &GetTransactionsSinceIDSchema{Transactions: transactions, LastTransactionID: lastID()}
+		}
+		return 200, &GetTransactionsIdrangeSchema{Transactions: transactions, LastTransactionID: lastID()}
+
+	case method == "GET" && match(route, "transactions", ""):
+		for _, tx := range s.transactions {
+			if tx.ID == route[1] {
+				return 200, &GetTransactionIDSchema{Transaction: tx, LastTransactionID: lastID()}
+			}
+		}
+		return notFound("The Transaction specified does not exist")
+	}
+
+	return notFound("The simulator doesn't serve " + method + " /" + strings.Join(route, "/"))
+}
+
+// openStream opens a pricing stream of instruments, or the transaction
+// stream when instruments is nil. Heartbeats are sent every five seconds.
+func (s *Simulator) openStream(req *http.Request, instruments []string) (*http.Response, error) {
+	sub := &simSubscriber{ch: make(chan interface{}, 1024)}
+	s.mu.Lock()
+	if instruments != nil {
+		sub.instruments = make(map[InstrumentNameDefinition]bool)
+		for _, instrument := range instruments {
+			sub.instruments[instrument] = true
+			if p, ok := s.prices[instrument]; ok {
+				sub.ch <- p.def
+			}
+		}
+		s.priceSubs[sub] = true
+	} else {
+		s.txSubs[sub] = true
+	}
+	s.mu.Unlock()
+
+	reader, writer := io.Pipe()
+	go func() {
+		defer func() {
+			s.mu.Lock()
+			if s.priceSubs[sub] || s.txSubs[sub] {
+				delete(s.priceSubs, sub)
+				delete(s.txSubs, sub)
+			}
+			s.mu.Unlock()
+			writer.Close()
+		}()
+
+		heartbeat := time.NewTicker(5 * time.Second)
+		defer heartbeat.Stop()
+
+		encoder := json.NewEncoder(writer)
+		for {
+			var message interface{}
+			select {
+			case <-req.Context().Done():
+				return
+			case m, ok := <-sub.ch:
+				if !ok {
+					return
+				}
+				message = m
+			case <-heartbeat.C:
+				s.mu.Lock()
+				if instruments != nil {
+					message = &PricingHeartbeatDefinition{Type: "HEARTBEAT", Time: s.timestamp()}
+				} else {
+					message = &TransactionHeartbeatDefinition{Type: "HEARTBEAT", LastTransactionID: strconv.Itoa(s.lastID), Time: s.timestamp()}
+				}
+				s.mu.Unlock()
+			}
+			if err := encoder.Encode(message); err != nil {
+				return
+			}
+		}
+	}()
+
+	header := make(http.Header)
+	header.Set("Content-Type", "application/octet-stream")
+	header.Set("RequestID", "simulator")
+	return &http.Response{
+		Status:     http.StatusText(200),
+		StatusCode: 200,
+		Header:     header,
+		Body:       reader,
+		Request:    req,
+	}, nil
+}
+
+/* Utils */
+
+// match returns whether route has the segments, "" matches any segment.
+func match(route []string, segments ...string) bool {
+	if len(route) != len(segments) {
+		return false
+	}
+	for i, segment := range segments {
+		if segment != "" && route[i] != segment {
+			return false
+		}
+	}
+	return true
+}
+
+func setDefault(v *string, def string) {
+	if *v == "" {
+		*v = def
+	}
+}
+
+func sign(v float64) float64 {
+	switch {
+	case v > 0:
+		return 1
+	case v < 0:
+		return -1
+	}
+	return 0
+}
+
+func round(v float64) float64 {
+	return math.Round(v*10000) / 10000
+}
+
+func formatAmount(v float64) AccountUnitsDefinition {
+	// Adding zero turns a negative zero positive.
+	return strconv.FormatFloat(round(v)+0, 'f', 4, 64)
+}
+
+func formatUnits(v float64) DecimalNumberDefinition {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+func formatPrice(v float64) PriceValueDefinition {
+	return strconv.FormatFloat(math.Round(v*1e6)/1e6, 'f', -1, 64)
+}
+
+// formatPercent formats the ratio of v to of with the 5 decimals of the API.
+func formatPercent(v, of float64) DecimalNumberDefinition {
+	if of <= 0 {
+		return "0.00000"
+	}
+	return strconv.FormatFloat(math.Round(v/of*1e5)/1e5+0, 'f', 5, 64)
+}
@@ -0,0 +1,557 @@
+package oanda
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func newSimPrice(instrument InstrumentNameDefinition, t string, bid, ask string) *PriceDefinition {
+	return &PriceDefinition{
+		Type:       "PRICE",
+		Instrument: instrument,
+		Time:       t,
+		Bids:       []*PriceBucketDefinition{{Price: bid, Liquidity: "1000000"}},
+		Asks:       []*PriceBucketDefinition{{Price: ask, Liquidity: "1000000"}},
+	}
+}
+
+// newTestSimulator returns a simulator with a balance of 10000 USD and a
+// EUR_USD price of 1.1000/1.1002, and a connection to it.
+func newTestSimulator(t *testing.T, params *SimulatorParams) (*Simulator, *ReceiverAccountID) {
+	params.Balance = 10000
+	sim := NewSimulator(params)
+	if err := sim.UpdatePrice(newSimPrice("EUR_USD", "2022-01-04T10:00:00Z", "1.1000", "1.1002")); err != nil {
+		t.Fatalf("Error occurred.\n%+v", err)
+	}
+	connection := &Connection{Environemnt: OandaPractice, Timeout: 10 * time.Second, Strict: true, Transport: sim}
+	return sim, connection.Accounts().AccountID("101-001-0000000-001")
+}
+
+func updateSimPrice(t *testing.T, sim *Simulator, price *PriceDefinition) {
+	if err := sim.UpdatePrice(price); err != nil {
+		t.Fatalf("Error occurred.\n%+v", err)
+	}
+}
+
+func postSimOrder(t *testing.T, account *ReceiverAccountID, order OrderRequestDefinition) *PostOrdersSchema {
+	resp, err := account.Orders().Post(context.Background(), &PostOrdersParams{Body: PostOrdersBodyParams{Order: order}})
+	if err != nil {
+		t.Fatalf("Error occurred.\n%+v", err)
+	}
+	return resp
+}
+
+func getSimSummary(t *testing.T, account *ReceiverAccountID) *AccountSummaryDefinition {
+	resp, err := account.Summary().Get(context.Background())
+	if err != nil {
+		t.Fatalf("Error occurred.\n%+v", err)
+	}
+	return resp.Account
+}
+
+func Test_Simulator(t *testing.T) {
+	t.Run("MarketOrder", func(t *testing.T) {
+		sim, account := newTestSimulator(t, &SimulatorParams{})
+
+		chs, err := account.Transactions().Stream().Get(context.Background(), &GetTransactionsStreamParams{BufferSize: 10})
+		if err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+		defer chs.Close()
+
+		resp := postSimOrder(t, account, &MarketOrderRequestDefinition{
+			Type:             "MARKET",
+			Instrument:       "EUR_USD",
+			Units:            "1000",
+			TakeProfitOnFill: &TakeProfitDetailsDefinition{Price: "1.1050"},
+			StopLossOnFill:   &StopLossDetailsDefinition{Distance: "0.0050"},
+		})
+		if resp.OrderFillTransaction == nil || resp.OrderFillTransaction.TradeOpened == nil {
+			t.Fatalf("Got no fill.\n%+v", resp)
+		}
+		if price := resp.OrderFillTransaction.TradeOpened.Price; price != "1.1002" {
+			t.Fatalf("Got unexpected fill price %s.", price)
+		}
+
+		summary := getSimSummary(t, account)
+		if summary.MarginUsed != "22.0020" || summary.UnrealizedPL != "-0.2000" || *summary.OpenTradeCount != 1 {
+			t.Fatalf("Got unexpected summary.\n%+v", summary)
+		}
+
+		// The take profit closes the trade and cancels the stop loss.
+		updateSimPrice(t, sim, newSimPrice("EUR_USD", "2022-01-04T10:01:00Z", "1.1050", "1.1052"))
+
+		summary = getSimSummary(t, account)
+		if summary.Balance != "10004.8000" || summary.PL != "4.8000" || *summary.OpenTradeCount != 0 || *summary.PendingOrderCount != 0 {
+			t.Fatalf("Got unexpected summary.\n%+v", summary)
+		}
+
+		expect := []string{
+			"MARKET_ORDER CLIENT_ORDER",
+			"ORDER_FILL MARKET_ORDER",
+			"TAKE_PROFIT_ORDER ON_FILL",
+			"STOP_LOSS_ORDER ON_FILL",
+			"ORDER_FILL TAKE_PROFIT_ORDER",
+			"ORDER_CANCEL LINKED_TRADE_CLOSED",
+		}
+		for _, e := range expect {
+			select {
+			case tx, ok := <-chs.TransactionCh:
+				if !ok {
+					t.Fatalf("Error occurred.\n%+v", chs.Err())
+				}
+				if actual := tx.Type + " " + string(tx.Reason); actual != e {
+					t.Fatalf("Got unexpected transaction.\nExpect: %s\nActual: %s", e, actual)
+				}
+			case <-time.After(time.Second):
+				t.Fatalf("Got no transaction %s.", e)
+			}
+		}
+	})
+
+	t.Run("PendingOrders", func(t *testing.T) {
+		sim, account := newTestSimulator(t, &SimulatorParams{})
+
+		postSimOrder(t, account, &LimitOrderRequestDefinition{Type: "LIMIT", Instrument: "EUR_USD", Units: "1000", Price: "1.0950"})
+		postSimOrder(t, account, &MarketIfTouchedOrderRequestDefinition{Type: "MARKET_IF_TOUCHED", Instrument: "EUR_USD", Units: "1000", Price: "1.1050"})
+		stop := postSimOrder(t, account, &StopOrderRequestDefinition{Type: "STOP", Instrument: "EUR_USD", Units: "-1000", Price: "1.0900"})
+
+		updateSimPrice(t, sim, newSimPrice("EUR_USD", "2022-01-04T10:01:00Z", "1.0948", "1.0950"))
+		updateSimPrice(t, sim, newSimPrice("EUR_USD", "2022-01-04T10:02:00Z", "1.1050", "1.1052"))
+
+		trades, err := account.OpenTrades().Get(context.Background())
+		if err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+		if len(trades.Trades) != 2 || trades.Trades[0].Price != "1.1052" || trades.Trades[1].Price != "1.095" {
+			t.Fatalf("Got unexpected trades.\n%+v", trades.Trades)
+		}
+
+		pending, err := account.PendingOrders().Get(context.Background())
+		if err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+		if len(pending.Orders) != 1 || pending.Orders[0].ID != stop.OrderCreateTransaction.ID {
+			t.Fatalf("Got unexpected pending orders.\n%+v", pending.Orders)
+		}
+		if _, err := account.Orders().OrderSpecifier(stop.OrderCreateTransaction.ID).Cancel().Put(context.Background()); err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+		if summary := getSimSummary(t, account); *summary.PendingOrderCount != 0 {
+			t.Fatalf("Got unexpected summary.\n%+v", summary)
+		}
+	})
+
+	t.Run("TrailingStopLoss", func(t *testing.T) {
+		sim, account := newTestSimulator(t, &SimulatorParams{})
+
+		postSimOrder(t, account, &MarketOrderRequestDefinition{
+			Type:                   "MARKET",
+			Instrument:             "EUR_USD",
+			Units:                  "1000",
+			TrailingStopLossOnFill: &TrailingStopLossDetailsDefinition{Distance: "0.0020"},
+		})
+		updateSimPrice(t, sim, newSimPrice("EUR_USD", "2022-01-04T10:01:00Z", "1.1030", "1.1032"))
+		updateSimPrice(t, sim, newSimPrice("EUR_USD", "2022-01-04T10:02:00Z", "1.1020", "1.1022"))
+		if summary := getSimSummary(t, account); *summary.OpenTradeCount != 1 {
+			t.Fatalf("Got unexpected summary.\n%+v", summary)
+		}
+		updateSimPrice(t, sim, newSimPrice("EUR_USD", "2022-01-04T10:03:00Z", "1.1010", "1.1012"))
+
+		if summary := getSimSummary(t, account); *summary.OpenTradeCount != 0 || summary.PL != "0.8000" {
+			t.Fatalf("Got unexpected summary.\n%+v", summary)
+		}
+	})
+
+	t.Run("ReplaceStopLoss", func(t *testing.T) {
+		_, account := newTestSimulator(t, &SimulatorParams{})
+
+		resp := postSimOrder(t, account, &MarketOrderRequestDefinition{
+			Type:           "MARKET",
+			Instrument:     "EUR_USD",
+			Units:          "1000",
+			StopLossOnFill: &StopLossDetailsDefinition{Price: "1.0950"},
+		})
+		tradeID := resp.OrderFillTransaction.TradeOpened.TradeID
+		pending, err := account.PendingOrders().Get(context.Background())
+		if err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+		if len(pending.Orders) != 1 {
+			t.Fatalf("Got unexpected pending orders.\n%+v", pending.Orders)
+		}
+		replaced := pending.Orders[0].ID
+
+		// The replaced stop loss is cancelled before the new one is created.
+		resp = postSimOrder(t, account, &StopLossOrderRequestDefinition{Type: "STOP_LOSS", TradeID: tradeID, Price: "1.0970"})
+		create := resp.OrderCreateTransaction
+		if create == nil || create.Type != "STOP_LOSS_ORDER" || create.Reason != "REPLACEMENT" || create.Price != "1.0970" {
+			t.Fatalf("Got unexpected order create.\n%+v", create)
+		}
+		if resp.OrderCancelTransaction != nil || resp.OrderFillTransaction != nil {
+			t.Fatalf("Got unexpected transactions.\n%+v", resp)
+		}
+		if len(resp.RelatedTransactionIDs) != 2 || resp.RelatedTransactionIDs[1] != create.ID {
+			t.Fatalf("Got unexpected related transactions %v.", resp.RelatedTransactionIDs)
+		}
+
+		order, err := account.Orders().OrderSpecifier(replaced).Get(context.Background())
+		if err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+		if order.Order.State != "CANCELLED" || order.Order.CancellingTransactionID != resp.RelatedTransactionIDs[0] {
+			t.Fatalf("Got unexpected replaced order.\n%+v", order.Order)
+		}
+	})
+
+	t.Run("TradeOrders", func(t *testing.T) {
+		sim, account := newTestSimulator(t, &SimulatorParams{})
+
+		resp := postSimOrder(t, account, &MarketOrderRequestDefinition{
+			Type:             "MARKET",
+			Instrument:       "EUR_USD",
+			Units:            "1000",
+			TakeProfitOnFill: &TakeProfitDetailsDefinition{Price: "1.1050"},
+		})
+		trade := account.Trades().TradeSpecifier(resp.OrderFillTransaction.TradeOpened.TradeID)
+
+		// An invalid order rejects the request without replacing the others.
+		_, err := trade.Orders().Put(context.Background(), &PutTradeSpecifierOrdersParams{Body: &PutTradeSpecifierOrdersBodyParams{
+			TakeProfit: &TakeProfitDetailsDefinition{Price: "1.1060"},
+			StopLoss:   &StopLossDetailsDefinition{},
+		}})
+		var badRequest *PutTradeSpecifierOrdersBadRequestError
+		if !errors.As(err, &badRequest) || badRequest.StopLossOrderRejectTransaction == nil || badRequest.TakeProfitOrderRejectTransaction != nil {
+			t.Fatalf("Got unexpected error.\n%+v", err)
+		}
+
+		orders, err := trade.Orders().Put(context.Background(), &PutTradeSpecifierOrdersParams{Body: &PutTradeSpecifierOrdersBodyParams{
+			TakeProfit: &TakeProfitDetailsDefinition{Price: "1.1060"},
+			StopLoss:   &StopLossDetailsDefinition{Price: "1.0950"},
+		}})
+		if err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+		if orders.TakeProfitOrderCancelTransaction == nil || orders.TakeProfitOrderTransaction.Reason != "REPLACEMENT" || orders.TakeProfitOrderTransaction.Price != "1.1060" {
+			t.Fatalf("Got unexpected take profit.\n%+v", orders)
+		}
+		if orders.StopLossOrderCancelTransaction != nil || orders.StopLossOrderTransaction.Reason != "CLIENT_ORDER" || orders.StopLossOrderTransaction.Price != "1.0950" {
+			t.Fatalf("Got unexpected stop loss.\n%+v", orders)
+		}
+
+		// An order set to null is cancelled.
+		req, err := http.NewRequest("PUT", "https://api-fxpractice.oanda.com/v3/accounts/101-001-0000000-001/trades/"+resp.OrderFillTransaction.TradeOpened.TradeID+"/orders", strings.NewReader(`{"takeProfit":null}`))
+		if err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+		res, err := sim.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+		res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			t.Fatalf("Got unexpected status %d.", res.StatusCode)
+		}
+
+		got, err := trade.Get(context.Background())
+		if err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+		if got.Trade.TakeProfitOrder != nil || got.Trade.StopLossOrder == nil || got.Trade.StopLossOrder.ID != orders.StopLossOrderTransaction.ID {
+			t.Fatalf("Got unexpected trade.\n%+v", got.Trade)
+		}
+		if summary := getSimSummary(t, account); *summary.PendingOrderCount != 1 {
+			t.Fatalf("Got unexpected summary.\n%+v", summary)
+		}
+	})
+
+	t.Run("ReplaceOrder", func(t *testing.T) {
+		_, account := newTestSimulator(t, &SimulatorParams{})
+
+		limit := postSimOrder(t, account, &LimitOrderRequestDefinition{Type: "LIMIT", Instrument: "EUR_USD", Units: "1000", Price: "1.0950"})
+		id := limit.OrderCreateTransaction.ID
+
+		// An invalid replacement keeps the order.
+		_, err := account.Orders().OrderSpecifier(id).Put(context.Background(), &PutOrderSpecifierParams{Body: PutOrderSpecifierBodyParams{
+			Order: &LimitOrderRequestDefinition{Type: "LIMIT", Instrument: "EUR_USD", Units: "1000"},
+		}})
+		var badRequest *PutOrderSpecifierBadRequestError
+		if !errors.As(err, &badRequest) || badRequest.OrderRejectTransaction.IntendedReplacesOrderID != id {
+			t.Fatalf("Got unexpected error.\n%+v", err)
+		}
+
+		// A replacement at a crossed price fills at once.
+		resp, err := account.Orders().OrderSpecifier(id).Put(context.Background(), &PutOrderSpecifierParams{Body: PutOrderSpecifierBodyParams{
+			Order: &LimitOrderRequestDefinition{Type: "LIMIT", Instrument: "EUR_USD", Units: "1000", Price: "1.1010"},
+		}})
+		if err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+		create := resp.OrderCreateTransaction
+		if resp.OrderCancelTransaction == nil || resp.OrderCancelTransaction.OrderID != id || create.Reason != "REPLACEMENT" || create.ReplacesOrderID != id {
+			t.Fatalf("Got unexpected replacement.\n%+v", resp)
+		}
+		if resp.OrderFillTransaction == nil || resp.OrderFillTransaction.Price != "1.1002" {
+			t.Fatalf("Got no fill.\n%+v", resp)
+		}
+
+		replaced, err := account.Orders().OrderSpecifier(id).Get(context.Background())
+		if err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+		if replaced.Order.State != "CANCELLED" || replaced.Order.ReplacedByOrderID != create.ID {
+			t.Fatalf("Got unexpected replaced order.\n%+v", replaced.Order)
+		}
+		if _, err := account.Orders().OrderSpecifier(id).Put(context.Background(), &PutOrderSpecifierParams{Body: PutOrderSpecifierBodyParams{
+			Order: &LimitOrderRequestDefinition{Type: "LIMIT", Instrument: "EUR_USD", Units: "1000", Price: "1.0900"},
+		}}); err == nil {
+			t.Fatalf("Error did not occur.")
+		}
+	})
+
+	t.Run("TradeClientExtensions", func(t *testing.T) {
+		_, account := newTestSimulator(t, &SimulatorParams{})
+
+		resp := postSimOrder(t, account, &MarketOrderRequestDefinition{Type: "MARKET", Instrument: "EUR_USD", Units: "1000"})
+		extensions, err := account.Trades().TradeSpecifier(resp.OrderFillTransaction.TradeOpened.TradeID).ClientExtensions().Put(context.Background(), &PutTradeSpecifierClientExtensionsParams{
+			Body: &PutTradeSpecifierClientExtensionsBodyParams{ClientExtensions: &ClientExtensionsDefinition{ID: "breakout", Tag: "strategy"}},
+		})
+		if err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+		if tx := extensions.TradeClientExtensionsModifyTransaction; tx == nil || tx.TradeClientExtensionsModify.ID != "breakout" {
+			t.Fatalf("Got unexpected transaction.\n%+v", extensions)
+		}
+
+		// The trade is found by its client ID.
+		trade, err := account.Trades().TradeSpecifier("@breakout").Get(context.Background())
+		if err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+		if trade.Trade.ClientExtensions.Tag != "strategy" {
+			t.Fatalf("Got unexpected trade.\n%+v", trade.Trade)
+		}
+	})
+
+	t.Run("Changes", func(t *testing.T) {
+		// An AccountMirror follows the simulated account.
+		sim, account := newTestSimulator(t, &SimulatorParams{})
+		mirror, err := account.Mirror(context.Background(), &GetAccountMirrorParams{Interval: time.Hour})
+		if err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+		defer mirror.Close()
+
+		resp := postSimOrder(t, account, &MarketOrderRequestDefinition{
+			Type:             "MARKET",
+			Instrument:       "EUR_USD",
+			Units:            "1000",
+			TakeProfitOnFill: &TakeProfitDetailsDefinition{Price: "1.1050"},
+		})
+		postSimOrder(t, account, &MarketOrderRequestDefinition{Type: "MARKET", Instrument: "EUR_USD", Units: "-400"})
+		updateSimPrice(t, sim, newSimPrice("EUR_USD", "2022-01-04T10:01:00Z", "1.1020", "1.1022"))
+		if err := mirror.Poll(context.Background(), account.Changes()); err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+
+		snapshot, err := mirror.Snapshot()
+		if err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+		summary := getSimSummary(t, account)
+		for _, v := range []struct {
+			name           string
+			expect, actual string
+		}{
+			{"balance", summary.Balance, snapshot.Balance},
+			{"P/L", summary.PL, snapshot.PL},
+			{"NAV", summary.NAV, snapshot.NAV},
+			{"margin used", summary.MarginUsed, snapshot.MarginUsed},
+			{"last transaction ID", summary.LastTransactionID, snapshot.LastTransactionID},
+		} {
+			if v.expect != v.actual {
+				t.Fatalf("Got unexpected %s.\nExpect: %s\nActual: %s", v.name, v.expect, v.actual)
+			}
+		}
+		if len(snapshot.Trades) != 1 || snapshot.Trades[0].CurrentUnits != "600" || len(snapshot.Orders) != 1 || snapshot.Orders[0].TradeID != resp.OrderFillTransaction.TradeOpened.TradeID {
+			t.Fatalf("Got unexpected account.\n%+v", snapshot)
+		}
+
+		// The take profit closes the trade and cancels nothing else.
+		updateSimPrice(t, sim, newSimPrice("EUR_USD", "2022-01-04T10:02:00Z", "1.1050", "1.1052"))
+		if err := mirror.Poll(context.Background(), account.Changes()); err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+		if snapshot, err = mirror.Snapshot(); err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+		summary = getSimSummary(t, account)
+		if snapshot.Balance != summary.Balance || snapshot.PL != summary.PL || len(snapshot.Trades) != 0 || len(snapshot.Orders) != 0 || *snapshot.OpenPositionCount != 0 {
+			t.Fatalf("Got unexpected account.\n%+v", snapshot)
+		}
+	})
+
+	t.Run("Instruments", func(t *testing.T) {
+		sim, account := newTestSimulator(t, &SimulatorParams{})
+		updateSimPrice(t, sim, newSimPrice("USD_JPY", "2022-01-04T10:00:00Z", "115.00", "115.02"))
+
+		resp, err := account.Instruments().Get(context.Background(), &GetAccountInstrumentsParams{})
+		if err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+		if len(resp.Instruments) != 2 || resp.Instruments[0].Name != "EUR_USD" || *resp.Instruments[0].PipLocation != -4 || *resp.Instruments[1].PipLocation != -2 || resp.Instruments[1].MarginRate != "0.02" {
+			t.Fatalf("Got unexpected instruments.\n%+v", resp.Instruments)
+		}
+		if resp, err = account.Instruments().Get(context.Background(), &GetAccountInstrumentsParams{Instruments: []string{"USD_JPY"}}); err != nil || len(resp.Instruments) != 1 {
+			t.Fatalf("Got unexpected instruments.\n%+v %v", resp, err)
+		}
+	})
+
+	t.Run("ReduceAndClose", func(t *testing.T) {
+		sim, account := newTestSimulator(t, &SimulatorParams{})
+		updateSimPrice(t, sim, newSimPrice("USD_JPY", "2022-01-04T10:00:00Z", "115.00", "115.02"))
+
+		postSimOrder(t, account, &MarketOrderRequestDefinition{Type: "MARKET", Instrument: "EUR_USD", Units: "1000"})
+		resp := postSimOrder(t, account, &MarketOrderRequestDefinition{Type: "MARKET", Instrument: "EUR_USD", Units: "-1500"})
+		fill := resp.OrderFillTransaction
+		if len(fill.TradesClosed) != 1 || fill.TradeOpened == nil || fill.TradeOpened.Units != "-500" || fill.PL != "-0.2000" {
+			t.Fatalf("Got unexpected fill.\n%+v", fill)
+		}
+
+		// P/L in JPY is converted to USD.
+		resp = postSimOrder(t, account, &MarketOrderRequestDefinition{Type: "MARKET", Instrument: "USD_JPY", Units: "1000"})
+		updateSimPrice(t, sim, newSimPrice("USD_JPY", "2022-01-04T10:01:00Z", "116.02", "116.04"))
+		closed, err := account.Trades().TradeSpecifier(resp.OrderFillTransaction.TradeOpened.TradeID).Close().Put(context.Background(), &PutTradeSpecifierCloseParams{Body: &PutTradeSpecifierCloseBodyParams{Units: "ALL"}})
+		if err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+		if pl := closed.OrderFillTransaction.PL; pl != "8.6185" {
+			t.Fatalf("Got unexpected P/L %s.", pl)
+		}
+
+		position, err := account.Positions().Instrument("EUR_USD").Close().Put(context.Background(), &PutPositionsInstrumentCloseParams{Body: &PutPositionsInstrumentCloseBodyParams{ShortUnits: "ALL"}})
+		if err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+		if position.ShortOrderFillTransaction == nil || position.ShortOrderFillTransaction.Units != "500" {
+			t.Fatalf("Got unexpected position closeout.\n%+v", position)
+		}
+		if summary := getSimSummary(t, account); *summary.OpenTradeCount != 0 || summary.Balance != "10008.3185" {
+			t.Fatalf("Got unexpected summary.\n%+v", summary)
+		}
+	})
+
+	t.Run("ClosePosition", func(t *testing.T) {
+		sim, account := newTestSimulator(t, &SimulatorParams{})
+		resp := postSimOrder(t, account, &MarketOrderRequestDefinition{Type: "MARKET", Instrument: "EUR_USD", Units: "1000"})
+		lastID := resp.LastTransactionID
+
+		// Invalid requests close nothing.
+		_, err := account.Positions().Instrument("EUR_USD").Close().Put(context.Background(), &PutPositionsInstrumentCloseParams{Body: &PutPositionsInstrumentCloseBodyParams{LongUnits: "ALL", ShortUnits: "100"}})
+		var badRequest *PutPositionsInstrumentCloseBadRequestError
+		if !errors.As(err, &badRequest) {
+			t.Fatalf("Got unexpected error.\n%+v", err)
+		}
+		for _, route := range []string{"positions/EUR_USD/close", "trades/" + resp.OrderFillTransaction.TradeOpened.TradeID + "/close"} {
+			req, err := http.NewRequest("PUT", "https://api-fxpractice.oanda.com/v3/accounts/101-001-0000000-001/"+route, strings.NewReader(`{"longUnits":`))
+			if err != nil {
+				t.Fatalf("Error occurred.\n%+v", err)
+			}
+			res, err := sim.RoundTrip(req)
+			if err != nil {
+				t.Fatalf("Error occurred.\n%+v", err)
+			}
+			res.Body.Close()
+			if res.StatusCode != http.StatusBadRequest {
+				t.Fatalf("Got unexpected status %d of %s.", res.StatusCode, route)
+			}
+		}
+		if summary := getSimSummary(t, account); *summary.OpenTradeCount != 1 || summary.Balance != "10000.0000" || summary.LastTransactionID != lastID {
+			t.Fatalf("Got unexpected summary.\n%+v", summary)
+		}
+
+		// ALL skips the side without trades.
+		position, err := account.Positions().Instrument("EUR_USD").Close().Put(context.Background(), &PutPositionsInstrumentCloseParams{Body: &PutPositionsInstrumentCloseBodyParams{LongUnits: "ALL", ShortUnits: "ALL"}})
+		if err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+		if position.LongOrderFillTransaction == nil || position.ShortOrderCreateTransaction != nil {
+			t.Fatalf("Got unexpected position closeout.\n%+v", position)
+		}
+		txs, err := account.Transactions().SinceID().Get(context.Background(), &GetTransactionsSinceIDParams{ID: lastID})
+		if err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+		if len(txs.Transactions) != 2 || txs.Transactions[1].ID != position.LastTransactionID {
+			t.Fatalf("Got unexpected transactions.\n%+v", txs.Transactions)
+		}
+	})
+
+	t.Run("InsufficientMargin", func(t *testing.T) {
+		_, account := newTestSimulator(t, &SimulatorParams{})
+
+		resp := postSimOrder(t, account, &MarketOrderRequestDefinition{Type: "MARKET", Instrument: "EUR_USD", Units: "1000000"})
+		if resp.OrderFillTransaction != nil || resp.OrderCancelTransaction == nil || resp.OrderCancelTransaction.Reason != "INSUFFICIENT_MARGIN" {
+			t.Fatalf("Got unexpected response.\n%+v", resp)
+		}
+	})
+
+	t.Run("Rejected", func(t *testing.T) {
+		_, account := newTestSimulator(t, &SimulatorParams{})
+
+		_, err := account.Orders().Post(context.Background(), &PostOrdersParams{Body: PostOrdersBodyParams{Order: &MarketOrderRequestDefinition{Type: "MARKET", Instrument: "EUR_USD", Units: "0"}}})
+		var rejected *PostOrdersBadRequestError
+		if !errors.As(err, &rejected) || rejected.OrderRejectTransaction.RejectReason != "UNITS_INVALID" {
+			t.Fatalf("Got unexpected error.\n%+v", err)
+		}
+	})
+
+	t.Run("Financing", func(t *testing.T) {
+		sim, account := newTestSimulator(t, &SimulatorParams{
+			Financing: map[InstrumentNameDefinition]*SimulatorFinancing{
+				"EUR_USD": {LongRate: -0.05, ShortRate: 0.01},
+			},
+		})
+
+		postSimOrder(t, account, &MarketOrderRequestDefinition{Type: "MARKET", Instrument: "EUR_USD", Units: "10000"})
+		updateSimPrice(t, sim, newSimPrice("EUR_USD", "2022-01-04T20:59:00Z", "1.1000", "1.1002"))
+		updateSimPrice(t, sim, newSimPrice("EUR_USD", "2022-01-04T21:01:00Z", "1.1000", "1.1002"))
+
+		summary := getSimSummary(t, account)
+		if summary.Financing != "-1.5070" || summary.Balance != "9998.4930" {
+			t.Fatalf("Got unexpected summary.\n%+v", summary)
+		}
+	})
+
+	t.Run("PricingStream", func(t *testing.T) {
+		sim, account := newTestSimulator(t, &SimulatorParams{})
+
+		chs, err := account.Pricing().Stream().Get(context.Background(), &GetPricingStreamParams{Instruments: []string{"EUR_USD"}})
+		if err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+		defer chs.Close()
+
+		// The stream starts with the current price.
+		if price := <-chs.PriceCh; price.Bids[0].Price != "1.1000" {
+			t.Fatalf("Got unexpected price.\n%+v", price)
+		}
+		updateSimPrice(t, sim, newSimPrice("EUR_USD", "2022-01-04T10:01:00Z", "1.1010", "1.1012"))
+		if price := <-chs.PriceCh; price.Bids[0].Price != "1.1010" {
+			t.Fatalf("Got unexpected price.\n%+v", price)
+		}
+
+		prices, err := account.Pricing().Get(context.Background(), &GetPricingParams{Instruments: []string{"EUR_USD"}})
+		if err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+		if len(prices.Prices) != 1 || prices.Prices[0].Asks[0].Price != "1.1012" {
+			t.Fatalf("Got unexpected prices.\n%+v", prices.Prices)
+		}
+	})
+}
@@ -0,0 +1,143 @@
+package oanda
+
+import (
+	"math"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+/* Params */
+
+type PositionSizeParams struct {
+	Summary    *AccountSummaryDefinition
+	Instrument *InstrumentDefinition
+	// Converter converts amounts in the quote currency of the instrument
+	// into the home currency. It may be nil when they are the same.
+	Converter *HomeConverter
+	// Risk is the fraction of the balance lost when the stop is hit, e.g.
+	// 0.01 for 1%.
+	Risk float64
+	// RiskNAV takes the risk of the NAV instead of the balance.
+	RiskNAV bool
+	// Entry and Stop are the prices the trade is opened and stopped out at.
+	// The trade buys when Stop is below Entry and sells otherwise.
+	Entry float64
+	Stop  float64
+}
+
+/* Sizing */
+
+type PositionSize struct {
+	// Units to trade, negative to sell.
+	Units float64
+	// Risk is the loss in the home currency when the stop is hit.
+	Risk float64
+	// StopPips is the distance between the entry and the stop in pips.
+	StopPips float64
+	// PipValue is the value of a pip of the units in the home currency.
+	PipValue float64
+	// Margin required by the units in the home currency.
+	Margin float64
+	// MarginLimited tells whether the units were reduced to the margin
+	// available.
+	MarginLimited bool
+
+	precision int
+}
+
+// CalculatePositionSize works out the units of a trade risking a fraction of
+// the account on its stop. The units are rounded down to the trade units
+// precision of the instrument and reduced to the margin available, which is
+// required at the greater margin rate of the account and the instrument.
+func CalculatePositionSize(params *PositionSizeParams) (*PositionSize, error) {
+	summary, instrument := params.Summary, params.Instrument
+	if params.Entry <= 0 || params.Stop <= 0 || params.Entry == params.Stop {
+		return nil, errors.Errorf("Entry %v and stop %v are invalid", params.Entry, params.Stop)
+	}
+	if params.Risk <= 0 {
+		return nil, errors.Errorf("Risk %v is invalid", params.Risk)
+	}
+
+	capital, err := parseDecimal(summary.Balance)
+	if params.RiskNAV {
+		capital, err = parseDecimal(summary.NAV)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "Parse capital failed")
+	}
+	marginAvailable, err := parseDecimal(summary.MarginAvailable)
+	if err != nil {
+		return nil, errors.Wrap(err, "Parse margin available failed")
+	}
+	marginRate, err := parseDecimal(summary.MarginRate)
+	if err != nil {
+		return nil, errors.Wrap(err, "Parse account margin rate failed")
+	}
+	if instrument.MarginRate != "" {
+		rate, err := parseDecimal(instrument.MarginRate)
+		if err != nil {
+			return nil, errors.Wrap(err, "Parse instrument margin rate failed")
+		}
+		marginRate = math.Max(marginRate, rate)
+	}
+	var minimum float64
+	if instrument.MinimumTradeSize != "" {
+		if minimum, err = parseDecimal(instrument.MinimumTradeSize); err != nil {
+			return nil, errors.Wrap(err, "Parse minimum trade size failed")
+		}
+	}
+	if instrument.PipLocation == nil {
+		return nil, errors.Errorf("Pip location of %s is unknown", instrument.Name)
+	}
+	pip := math.Pow10(*instrument.PipLocation)
+
+	converter := params.Converter
+	if converter == nil {
+		converter = NewHomeConverter(summary.Currency)
+	}
+	quote := QuoteCurrency(instrument.Name)
+	distance := math.Abs(params.Entry - params.Stop)
+	loss, err := converter.ConvertPL(quote, -distance)
+	if err != nil {
+		return nil, errors.Wrap(err, "Convert risk failed")
+	}
+	value, err := converter.ConvertPositionValue(quote, params.Entry)
+	if err != nil {
+		return nil, errors.Wrap(err, "Convert position value failed")
+	}
+
+	size := &PositionSize{StopPips: distance / pip}
+	if instrument.TradeUnitsPrecision != nil {
+		size.precision = *instrument.TradeUnitsPrecision
+	}
+	units := size.round(capital * params.Risk / -loss)
+	if margin := units * value * marginRate; margin > marginAvailable {
+		units = size.round(marginAvailable / (value * marginRate))
+		size.MarginLimited = true
+	}
+	if units < minimum || units == 0 {
+		return nil, errors.Errorf("Units %v are below the minimum trade size %v of %s", units, minimum, instrument.Name)
+	}
+
+	size.Units = units
+	if params.Stop > params.Entry {
+		size.Units = -units
+	}
+	size.Risk = units * -loss
+	size.PipValue = units * pip * -loss / distance
+	size.Margin = units * value * marginRate
+	return size, nil
+}
+
+// OrderUnits formats the units for an order request.
+func (s *PositionSize) OrderUnits() DecimalNumberDefinition {
+	return strconv.FormatFloat(s.Units, 'f', s.precision, 64)
+}
+
+// round rounds units down to the precision.
+func (s *PositionSize) round(units float64) float64 {
+	scale := math.Pow10(s.precision)
+	// The epsilon keeps e.g. 0.29999999999999999 from being rounded down.
+	return math.Floor(units*scale+1e-9) / scale
+}
@@ -0,0 +1,125 @@
+package oanda
+
+import (
+	"math"
+	"testing"
+)
+
+func Test_CalculatePositionSize(t *testing.T) {
+	summary := &AccountSummaryDefinition{
+		Currency:        "USD",
+		Balance:         "10000.0000",
+		NAV:             "9000.0000",
+		MarginAvailable: "10000.0000",
+		MarginRate:      "0.02",
+	}
+	eurUSD := &InstrumentDefinition{
+		Name:                "EUR_USD",
+		PipLocation:         Int(-4),
+		TradeUnitsPrecision: Int(0),
+		MinimumTradeSize:    "1",
+		MarginRate:          "0.0333",
+	}
+	eurJPY := &InstrumentDefinition{
+		Name:                "EUR_JPY",
+		PipLocation:         Int(-2),
+		TradeUnitsPrecision: Int(0),
+		MinimumTradeSize:    "1",
+		MarginRate:          "0.02",
+	}
+	converter := NewHomeConverter("USD")
+	if err := converter.Update([]*HomeConversionsDefinition{
+		{Currency: "JPY", AccountGain: "0.0087", AccountLoss: "0.0088", PositionValue: "0.00875"},
+	}); err != nil {
+		t.Fatalf("Error occurred.\n%+v", err)
+	}
+
+	for _, c := range []struct {
+		name   string
+		params *PositionSizeParams
+		expect *PositionSize
+		units  DecimalNumberDefinition
+	}{
+		{
+			name:   "Long",
+			params: &PositionSizeParams{Summary: summary, Instrument: eurUSD, Risk: 0.01, Entry: 1.1000, Stop: 1.0950},
+			expect: &PositionSize{Units: 20000, Risk: 100, StopPips: 50, PipValue: 2, Margin: 732.6},
+			units:  "20000",
+		},
+		{
+			name:   "Short",
+			params: &PositionSizeParams{Summary: summary, Instrument: eurUSD, Risk: 0.01, Entry: 1.1000, Stop: 1.1050},
+			expect: &PositionSize{Units: -20000, Risk: 100, StopPips: 50, PipValue: 2, Margin: 732.6},
+			units:  "-20000",
+		},
+		{
+			name:   "NAV",
+			params: &PositionSizeParams{Summary: summary, Instrument: eurUSD, Risk: 0.01, RiskNAV: true, Entry: 1.1000, Stop: 1.0950},
+			expect: &PositionSize{Units: 18000, Risk: 90, StopPips: 50, PipValue: 1.8, Margin: 659.34},
+			units:  "18000",
+		},
+		{
+			// Losses in JPY are converted with the loss factor.
+			name:   "Conversion",
+			params: &PositionSizeParams{Summary: summary, Instrument: eurJPY, Converter: converter, Risk: 0.01, Entry: 130.00, Stop: 129.50},
+			expect: &PositionSize{Units: 22727, Risk: 99.9988, StopPips: 50, PipValue: 1.999976, Margin: 22727 * 130 * 0.00875 * 0.02},
+			units:  "22727",
+		},
+		{
+			name:   "MarginLimited",
+			params: &PositionSizeParams{Summary: &AccountSummaryDefinition{Currency: "USD", Balance: "10000", MarginAvailable: "500", MarginRate: "0.02"}, Instrument: eurUSD, Risk: 0.01, Entry: 1.1000, Stop: 1.0950},
+			expect: &PositionSize{Units: 13650, Risk: 68.25, StopPips: 50, PipValue: 1.365, Margin: 499.9995, MarginLimited: true},
+			units:  "13650",
+		},
+		{
+			name: "Precision",
+			params: &PositionSizeParams{Summary: summary, Instrument: &InstrumentDefinition{
+				Name: "EUR_USD", PipLocation: Int(-4), TradeUnitsPrecision: Int(1), MarginRate: "0.0333",
+			}, Risk: 0.01, Entry: 1.1000, Stop: 1.0970},
+			expect: &PositionSize{Units: 33333.3, Risk: 99.9999, StopPips: 30, PipValue: 3.33333, Margin: 33333.3 * 1.1 * 0.0333},
+			units:  "33333.3",
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			actual, err := CalculatePositionSize(c.params)
+			if err != nil {
+				t.Fatalf("Error occurred.\n%+v", err)
+			}
+			for _, v := range []struct {
+				name           string
+				expect, actual float64
+			}{
+				{"units", c.expect.Units, actual.Units},
+				{"risk", c.expect.Risk, actual.Risk},
+				{"stop pips", c.expect.StopPips, actual.StopPips},
+				{"pip value", c.expect.PipValue, actual.PipValue},
+				{"margin", c.expect.Margin, actual.Margin},
+			} {
+				if math.Abs(v.expect-v.actual) > 1e-6 {
+					t.Fatalf("Got unexpected %s.\nExpect: %v\nActual: %v", v.name, v.expect, v.actual)
+				}
+			}
+			if actual.MarginLimited != c.expect.MarginLimited {
+				t.Fatalf("Got unexpected margin limit %v.", actual.MarginLimited)
+			}
+			if units := actual.OrderUnits(); units != c.units {
+				t.Fatalf("Got unexpected order units %s.", units)
+			}
+		})
+	}
+
+	t.Run("Errors", func(t *testing.T) {
+		for _, params := range []*PositionSizeParams{
+			// The stop equals the entry.
+			{Summary: summary, Instrument: eurUSD, Risk: 0.01, Entry: 1.1000, Stop: 1.1000},
+			// The units are below the minimum trade size.
+			{Summary: summary, Instrument: eurUSD, Risk: 0.0000001, Entry: 1.1000, Stop: 1.0950},
+			// The conversion of JPY is unknown.
+			{Summary: summary, Instrument: eurJPY, Risk: 0.01, Entry: 130.00, Stop: 129.50},
+		} {
+			if _, err := CalculatePositionSize(params); err == nil {
+				t.Fatalf("Error did not occur.\n%+v", params)
+			}
+		}
+	})
+}
@@ -0,0 +1,199 @@
+// Package tracing creates OpenTelemetry spans for the calls of an
+// oanda.Connection.
+package tracing
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"sync"
+
+	oanda "github.com/denkhaus/oanda-client"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/denkhaus/oanda-client/tracing"
+
+type config struct {
+	provider trace.TracerProvider
+	key      []byte
+}
+
+type Option func(*config)
+
+// WithTracerProvider sets the provider of the tracer, defaults to the global
+// provider.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(c *config) {
+		c.provider = provider
+	}
+}
+
+// WithAccountIDKey records the account of a call as the HMAC-SHA256 of its ID
+// under key. Without a key the account is not recorded. Keep the key secret,
+// the IDs follow a known pattern and can be recovered from an unkeyed hash.
+func WithAccountIDKey(key []byte) Option {
+	return func(c *config) {
+		c.key = key
+	}
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{provider: otel.GetTracerProvider()}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (c *config) tracer() trace.Tracer {
+	return c.provider.Tracer(instrumentationName)
+}
+
+/* Middleware */
+
+// Middleware creates a client span for every REST call, as a child of the
+// span in the context of the call:
+//
+//	connection.Use(tracing.Middleware())
+func Middleware(opts ...Option) oanda.Middleware {
+	c := newConfig(opts)
+	tracer := c.tracer()
+
+	return func(next oanda.Handler) oanda.Handler {
+		return func(ctx context.Context, req *oanda.Request) (*oanda.Response, error) {
+			ctx, span := tracer.Start(ctx, spanName(req),
+				trace.WithSpanKind(trace.SpanKindClient),
+				trace.WithAttributes(c.requestAttributes(req)...),
+			)
+			defer span.End()
+
+			resp, err := next(ctx, req)
+			if resp != nil {
+				span.SetAttributes(responseAttributes(resp)...)
+			}
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			}
+			return resp, err
+		}
+	}
+}
+
+/* Stream hook */
+
+// StreamHook creates a span for every stream, lasting from its request until
+// it is closed:
+//
+//	connection.StreamHooks = append(connection.StreamHooks, tracing.StreamHook())
+func StreamHook(opts ...Option) *oanda.StreamHook {
+	c := newConfig(opts)
+	tracer := c.tracer()
+
+	var mu sync.Mutex
+	spans := make(map[*oanda.Request]trace.Span)
+
+	return &oanda.StreamHook{
+		Open: func(ctx context.Context, req *oanda.Request, resp *oanda.Response, err error) {
+			_, span := tracer.Start(ctx, spanName(req),
+				trace.WithSpanKind(trace.SpanKindClient),
+				trace.WithAttributes(c.requestAttributes(req)...),
+			)
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+				span.End()
+				return
+			}
+			span.SetAttributes(responseAttributes(resp)...)
+
+			mu.Lock()
+			spans[req] = span
+			mu.Unlock()
+		},
+		Close: func(req *oanda.Request, err error) {
+			mu.Lock()
+			span, ok := spans[req]
+			delete(spans, req)
+			mu.Unlock()
+			if !ok {
+				return
+			}
+
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			}
+			span.End()
+		},
+	}
+}
+
+/* Utils */
+
+func spanName(req *oanda.Request) string {
+	return "oanda " + req.Method + " " + req.Route
+}
+
+func (c *config) requestAttributes(req *oanda.Request) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{
+		attribute.String("http.method", req.Method),
+		attribute.String("oanda.endpoint", req.Route),
+	}
+	if id := req.Params["accountID"]; id != "" && len(c.key) > 0 {
+		attrs = append(attrs, attribute.String("oanda.account_id_hash", hashAccountID(c.key, id)))
+	}
+	if instrument := req.Params["instrument"]; instrument != "" {
+		attrs = append(attrs, attribute.String("oanda.instrument", instrument))
+	} else if instrument := orderInstrument(req.Body); instrument != "" {
+		attrs = append(attrs, attribute.String("oanda.instrument", instrument))
+	} else if instruments := req.Query.Get("instruments"); instruments != "" {
+		attrs = append(attrs, attribute.StringSlice("oanda.instruments", strings.Split(instruments, ",")))
+	}
+	return attrs
+}
+
+func responseAttributes(resp *oanda.Response) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{
+		attribute.Int("http.status_code", resp.StatusCode),
+	}
+	if id := resp.Header.Get("RequestID"); id != "" {
+		attrs = append(attrs, attribute.String("oanda.request_id", id))
+	}
+	return attrs
+}
+
+// hashAccountID identifies an account in traces without revealing its ID.
+func hashAccountID(key []byte, id string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(id))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// orderInstrument returns the instrument of the order in the body of an order
+// request, which carries it in the body instead of the path.
+func orderInstrument(body interface{}) string {
+	if body == nil {
+		return ""
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return ""
+	}
+	var fields struct {
+		Order struct {
+			Instrument oanda.InstrumentNameDefinition `json:"instrument"`
+		} `json:"order"`
+	}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return ""
+	}
+	return fields.Order.Instrument
+}
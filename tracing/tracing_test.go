@@ -0,0 +1,106 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	oanda "github.com/denkhaus/oanda-client"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func Test_Tracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	key := []byte("secret")
+
+	t.Run("Middleware", func(t *testing.T) {
+		connection := &oanda.Connection{Environemnt: oanda.OandaPractice, DryRun: true}
+		connection.Use(Middleware(WithTracerProvider(provider), WithAccountIDKey(key)))
+
+		ctx, parent := provider.Tracer("test").Start(context.Background(), "order flow")
+		_, err := connection.Accounts().AccountID("101-001-1234567-001").Positions().Instrument("EUR_USD").Close().Put(ctx, &oanda.PutPositionsInstrumentCloseParams{
+			Body: &oanda.PutPositionsInstrumentCloseBodyParams{LongUnits: "ALL"},
+		})
+		parent.End()
+		if err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+
+		spans := recorder.Ended()
+		span := spans[0]
+		if span.Name() != "oanda PUT /v3/accounts/{accountID}/positions/{instrument}/close" {
+			t.Fatalf("Got unexpected span name: %s", span.Name())
+		}
+		if span.Parent().SpanID() != parent.SpanContext().SpanID() {
+			t.Fatalf("Span is not a child of the span of the context.")
+		}
+
+		attrs := make(map[attribute.Key]attribute.Value)
+		for _, kv := range span.Attributes() {
+			attrs[kv.Key] = kv.Value
+		}
+		for key, expect := range map[attribute.Key]string{
+			"oanda.account_id_hash": hashAccountID(key, "101-001-1234567-001"),
+			"oanda.instrument":      "EUR_USD",
+			"oanda.request_id":      "dry-run",
+		} {
+			if actual := attrs[key].AsString(); actual != expect {
+				t.Fatalf("Got unexpected %s.\nExpect: %s\nActual: %s", key, expect, actual)
+			}
+		}
+		if status := attrs["http.status_code"].AsInt64(); status != 200 {
+			t.Fatalf("Got unexpected status code: %d", status)
+		}
+	})
+	t.Run("Order", func(t *testing.T) {
+		connection := &oanda.Connection{Environemnt: oanda.OandaPractice, DryRun: true}
+		connection.Use(Middleware(WithTracerProvider(provider)))
+
+		_, err := connection.Accounts().AccountID("101-001-1234567-001").Orders().Post(context.Background(), &oanda.PostOrdersParams{
+			Body: oanda.PostOrdersBodyParams{Order: &oanda.MarketOrderRequestDefinition{Type: "MARKET", Instrument: "EUR_USD", Units: "100"}},
+		})
+		if err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+
+		spans := recorder.Ended()
+		attrs := make(map[attribute.Key]attribute.Value)
+		for _, kv := range spans[len(spans)-1].Attributes() {
+			attrs[kv.Key] = kv.Value
+		}
+		// The instrument is taken from the order, the account is not recorded
+		// without a key.
+		if instrument := attrs["oanda.instrument"].AsString(); instrument != "EUR_USD" {
+			t.Fatalf("Got unexpected instrument: %s", instrument)
+		}
+		if _, ok := attrs["oanda.account_id_hash"]; ok {
+			t.Fatalf("Account is recorded without a key.")
+		}
+	})
+	t.Run("HashAccountID", func(t *testing.T) {
+		hash := hashAccountID(key, "101-001-1234567-001")
+		if hash == hashAccountID([]byte("other"), "101-001-1234567-001") || hash == hashAccountID(key, "101-001-1234567-002") {
+			t.Fatalf("Got colliding hashes: %s", hash)
+		}
+	})
+	t.Run("StreamHook", func(t *testing.T) {
+		hook := StreamHook(WithTracerProvider(provider))
+		req := &oanda.Request{Method: "GET", Route: "/v3/accounts/{accountID}/pricing/stream", Stream: true}
+
+		before := len(recorder.Ended())
+		hook.Open(context.Background(), req, &oanda.Response{StatusCode: 200, Header: http.Header{}}, nil)
+		if len(recorder.Ended()) != before {
+			t.Fatalf("Span ended before the stream was closed.")
+		}
+		hook.Close(req, nil)
+
+		spans := recorder.Ended()
+		if len(spans) != before+1 || spans[before].Name() != "oanda GET /v3/accounts/{accountID}/pricing/stream" {
+			t.Fatalf("Got unexpected spans: %v", spans)
+		}
+	})
+}
@@ -2,6 +2,7 @@ package oanda
 
 import (
 	"context"
+	"net/http"
 	"strconv"
 	"strings"
 
@@ -153,24 +154,114 @@ func (p *TradeResult) IsCanceledWithInsufficientMargin() bool {
 	return p.IsCanceled() && p.OrderCancelTransaction.Reason.IsInsufficientMargin()
 }
 
+/* Headers */
+
+type GetTradesHeaders struct {
+	RequestID string
+}
+
+func (s *GetTradesSchema) setHeaders(resp *http.Response) error {
+	s.Headers = new(GetTradesHeaders)
+	if h, err := copyHeader(resp, "Requestid"); err == nil {
+		s.Headers.RequestID = h[0]
+	} else {
+		return errors.Errorf("Parse headers failed: %v", err)
+	}
+	return nil
+}
+
+type GetOpenTradesHeaders struct {
+	RequestID string
+}
+
+func (s *GetOpenTradesSchema) setHeaders(resp *http.Response) error {
+	s.Headers = new(GetOpenTradesHeaders)
+	if h, err := copyHeader(resp, "Requestid"); err == nil {
+		s.Headers.RequestID = h[0]
+	} else {
+		return errors.Errorf("Parse headers failed: %v", err)
+	}
+	return nil
+}
+
+type GetTradeSpecifierHeaders struct {
+	RequestID string
+}
+
+func (s *GetTradeSpecifierSchema) setHeaders(resp *http.Response) error {
+	s.Headers = new(GetTradeSpecifierHeaders)
+	if h, err := copyHeader(resp, "Requestid"); err == nil {
+		s.Headers.RequestID = h[0]
+	} else {
+		return errors.Errorf("Parse headers failed: %v", err)
+	}
+	return nil
+}
+
+type PutTradeSpecifierCloseHeaders struct {
+	RequestID string
+}
+
+func (s *PutTradeSpecifierCloseSchema) setHeaders(resp *http.Response) error {
+	s.Headers = new(PutTradeSpecifierCloseHeaders)
+	if h, err := copyHeader(resp, "Requestid"); err == nil {
+		s.Headers.RequestID = h[0]
+	} else {
+		return errors.Errorf("Parse headers failed: %v", err)
+	}
+	return nil
+}
+
+type PutTradeSpecifierClientExtensionsHeaders struct {
+	RequestID string
+}
+
+func (s *PutTradeSpecifierClientExtensionsSchema) setHeaders(resp *http.Response) error {
+	s.Headers = new(PutTradeSpecifierClientExtensionsHeaders)
+	if h, err := copyHeader(resp, "Requestid"); err == nil {
+		s.Headers.RequestID = h[0]
+	} else {
+		return errors.Errorf("Parse headers failed: %v", err)
+	}
+	return nil
+}
+
+type PutTradeSpecifierOrdersHeaders struct {
+	RequestID string
+}
+
+func (s *PutTradeSpecifierOrdersSchema) setHeaders(resp *http.Response) error {
+	s.Headers = new(PutTradeSpecifierOrdersHeaders)
+	if h, err := copyHeader(resp, "Requestid"); err == nil {
+		s.Headers.RequestID = h[0]
+	} else {
+		return errors.Errorf("Parse headers failed: %v", err)
+	}
+	return nil
+}
+
 /* Schemas */
 
 type GetTradesSchema struct {
+	Headers           *GetTradesHeaders
 	Trades            []*TradeDefinition      `json:"trades,omitempty"`
 	LastTransactionID TransactionIDDefinition `json:"lastTransactionID,omitempty"`
 }
 
 type GetOpenTradesSchema struct {
+	Headers           *GetOpenTradesHeaders
 	Trades            []*TradeDefinition      `json:"trades,omitempty"`
 	LastTransactionID TransactionIDDefinition `json:"lastTransactionID,omitempty"`
 }
 
 type GetTradeSpecifierSchema struct {
+	Headers           *GetTradeSpecifierHeaders
 	Trade             *TradeDefinition        `json:"trade,omitempty"`
 	LastTransactionID TransactionIDDefinition `json:"lastTransactionID,omitempty"`
 }
 
 type PutTradeSpecifierCloseSchema struct {
+	Headers                *PutTradeSpecifierCloseHeaders
 	OrderCreateTransaction *TransactionDefinition    `json:"orderCreateTransaction,omitempty"`
 	OrderFillTransaction   *TransactionDefinition    `json:"orderFillTransaction,omitempty"`
 	OrderCancelTransaction *TransactionDefinition    `json:"orderCancelTransaction,omitempty"`
@@ -186,12 +277,14 @@ func (p *PutTradeSpecifierCloseSchema) ToTradeResult() *TradeResult {
 }
 
 type PutTradeSpecifierClientExtensionsSchema struct {
+	Headers                                *PutTradeSpecifierClientExtensionsHeaders
 	TradeClientExtensionsModifyTransaction *TransactionDefinition    `json:"tradeClientExtensionsModifyTransaction,omitempty"`
 	RelatedTransactionIDs                  []TransactionIDDefinition `json:"relatedTransactionIDs,omitempty"`
 	LastTransactionID                      TransactionIDDefinition   `json:"lastTransactionID,omitempty"`
 }
 
 type PutTradeSpecifierOrdersSchema struct {
+	Headers                                 *PutTradeSpecifierOrdersHeaders
 	TakeProfitOrderCancelTransaction        *TransactionDefinition    `json:"takeProfitOrderCancelTransaction,omitempty"`
 	TakeProfitOrderTransaction              *TransactionDefinition    `json:"takeProfitOrderTransaction,omitempty"`
 	TakeProfitOrderFillTransaction          *TransactionDefinition    `json:"takeProfitOrderFillTransaction,omitempty"`
@@ -209,6 +302,8 @@ type PutTradeSpecifierOrdersSchema struct {
 /* Errors */
 
 type PutTradeSpecifierCloseBadRequestError struct {
+	errorHeaders
+
 	OrderRejectTransaction *TransactionDefinition `json:"orderRejectTransaction,omitempty"`
 	ErrorCode              string                 `json:"errorCode,omitempty"`
 	ErrorMessage           string                 `json:"errorMessage,omitempty"`
@@ -220,6 +315,8 @@ func (r *PutTradeSpecifierCloseBadRequestError) Error() string {
 }
 
 type PutTradeSpecifierCloseNotFoundError struct {
+	errorHeaders
+
 	OrderRejectTransaction *TransactionDefinition    `json:"orderRejectTransaction,omitempty"`
 	LastTransactionID      TransactionIDDefinition   `json:"lastTransactionID,omitempty"`
 	RelatedTransactionIDs  []TransactionIDDefinition `json:"relatedTransactionIDs,omitempty"`
@@ -232,6 +329,8 @@ func (r *PutTradeSpecifierCloseNotFoundError) Error() string {
 }
 
 type PutTradeSpecifierClientExtensionsBadRequestError struct {
+	errorHeaders
+
 	TradeClientExtensionsModifyRejectTransaction *TransactionDefinition    `json:"tradeClientExtensionsModifyRejectTransaction,omitempty"`
 	LastTransactionID                            TransactionIDDefinition   `json:"lastTransactionID,omitempty"`
 	RelatedTransactionIDs                        []TransactionIDDefinition `json:"relatedTransactionIDs,omitempty"`
@@ -245,6 +344,8 @@ func (r *PutTradeSpecifierClientExtensionsBadRequestError) Error() string {
 }
 
 type PutTradeSpecifierClientExtensionsNotFoundError struct {
+	errorHeaders
+
 	TradeClientExtensionsModifyRejectTransaction *TransactionDefinition    `json:"tradeClientExtensionsModifyRejectTransaction,omitempty"`
 	LastTransactionID                            TransactionIDDefinition   `json:"lastTransactionID,omitempty"`
 	RelatedTransactionIDs                        []TransactionIDDefinition `json:"relatedTransactionIDs,omitempty"`
@@ -258,6 +359,8 @@ func (r *PutTradeSpecifierClientExtensionsNotFoundError) Error() string {
 }
 
 type PutTradeSpecifierOrdersBadRequestError struct {
+	errorHeaders
+
 	TakeProfitOrderCancelRejectTransaction       *TransactionDefinition    `json:"takeProfitOrderCancelRejectTransaction,omitempty"`
 	TakeProfitOrderRejectTransaction             *TransactionDefinition    `json:"takeProfitOrderRejectTransaction,omitempty"`
 	StopLossOrderCancelRejectTransaction         *TransactionDefinition    `json:"stopLossOrderCancelRejectTransaction,omitempty"`
@@ -282,7 +385,7 @@ func (r *ReceiverTrades) Get(ctx context.Context, params *GetTradesParams) (*Get
 	childCtx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	resp, err := r.Connection.request(
+	data, err := r.Connection.request(
 		childCtx,
 		&requestParams{
 			method:   "GET",
@@ -315,22 +418,13 @@ func (r *ReceiverTrades) Get(ctx context.Context, params *GetTradesParams) (*Get
 
 				return q
 			}(),
+			results: map[int]interface{}{
+				200: new(GetTradesSchema),
+			},
 		},
 	)
 	if err != nil {
-		return nil, errors.Errorf("Get trades canceled: %v", err)
-	}
-	defer resp.Body.Close()
-
-	var data interface{}
-	switch resp.StatusCode {
-	case 200:
-		data = new(GetTradesSchema)
-	}
-
-	data, err = parseResponse(resp, data, r.Connection.Strict)
-	if err != nil {
-		return nil, errors.Errorf("Get trades failed: %v", err)
+		return nil, errors.Wrap(err, "Get trades failed")
 	}
 	return data.(*GetTradesSchema), nil
 }
@@ -340,7 +434,7 @@ func (r *ReceiverOpenTrades) Get(ctx context.Context) (*GetOpenTradesSchema, err
 	childCtx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	resp, err := r.Connection.request(
+	data, err := r.Connection.request(
 		childCtx,
 		&requestParams{
 			method:   "GET",
@@ -348,22 +442,13 @@ func (r *ReceiverOpenTrades) Get(ctx context.Context) (*GetOpenTradesSchema, err
 			headers: []header{
 				{key: "Accept-Datetime-Format", value: "RFC3339"},
 			},
+			results: map[int]interface{}{
+				200: new(GetOpenTradesSchema),
+			},
 		},
 	)
 	if err != nil {
-		return nil, errors.Errorf("Get open trades canceled: %v", err)
-	}
-	defer resp.Body.Close()
-
-	var data interface{}
-	switch resp.StatusCode {
-	case 200:
-		data = new(GetOpenTradesSchema)
-	}
-
-	data, err = parseResponse(resp, data, r.Connection.Strict)
-	if err != nil {
-		return nil, errors.Errorf("Get open trades failed: %v", err)
+		return nil, errors.Wrap(err, "Get open trades failed")
 	}
 	return data.(*GetOpenTradesSchema), nil
 }
@@ -373,7 +458,7 @@ func (r *ReceiverTradeSpecifier) Get(ctx context.Context) (*GetTradeSpecifierSch
 	childCtx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	resp, err := r.Connection.request(
+	data, err := r.Connection.request(
 		childCtx,
 		&requestParams{
 			method:   "GET",
@@ -381,22 +466,13 @@ func (r *ReceiverTradeSpecifier) Get(ctx context.Context) (*GetTradeSpecifierSch
 			headers: []header{
 				{key: "Accept-Datetime-Format", value: "RFC3339"},
 			},
+			results: map[int]interface{}{
+				200: new(GetTradeSpecifierSchema),
+			},
 		},
 	)
 	if err != nil {
-		return nil, errors.Errorf("Get trade specifier canceled: %v", err)
-	}
-	defer resp.Body.Close()
-
-	var data interface{}
-	switch resp.StatusCode {
-	case 200:
-		data = new(GetTradeSpecifierSchema)
-	}
-
-	data, err = parseResponse(resp, data, r.Connection.Strict)
-	if err != nil {
-		return nil, errors.Errorf("Get trade specifier failed: %v", err)
+		return nil, errors.Wrap(err, "Get trade specifier failed")
 	}
 	return data.(*GetTradeSpecifierSchema), nil
 }
@@ -406,7 +482,7 @@ func (r *ReceiverTradeSpecifierClose) Put(ctx context.Context, params *PutTradeS
 	childCtx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	resp, err := r.Connection.request(
+	data, err := r.Connection.request(
 		childCtx,
 		&requestParams{
 			method:   "PUT",
@@ -415,26 +491,24 @@ func (r *ReceiverTradeSpecifierClose) Put(ctx context.Context, params *PutTradeS
 				{key: "Accept-Datetime-Format", value: "RFC3339"},
 			},
 			body: params.Body,
+			simulate: func() (int, interface{}) {
+				units := "ALL"
+				if params.Body != nil && params.Body.Units != "" {
+					units = params.Body.Units
+				}
+				tx := dryRunTransaction(r.AccountID, MarketOrderTransaction, "TRADE_CLOSE")
+				tx.TradeClose = &MarketOrderTradeCloseDefinition{TradeID: r.TradeSpecifier, Units: units}
+				return 200, &PutTradeSpecifierCloseSchema{OrderCreateTransaction: tx}
+			},
+			results: map[int]interface{}{
+				200: new(PutTradeSpecifierCloseSchema),
+				400: new(PutTradeSpecifierCloseBadRequestError),
+				404: new(PutTradeSpecifierCloseNotFoundError),
+			},
 		},
 	)
 	if err != nil {
-		return nil, errors.Errorf("Put trade specifier close canceled: %v", err)
-	}
-	defer resp.Body.Close()
-
-	var data interface{}
-	switch resp.StatusCode {
-	case 200:
-		data = new(PutTradeSpecifierCloseSchema)
-	case 400:
-		data = new(PutTradeSpecifierCloseBadRequestError)
-	case 404:
-		data = new(PutTradeSpecifierCloseNotFoundError)
-	}
-
-	data, err = parseResponse(resp, data, r.Connection.Strict)
-	if err != nil {
-		return nil, errors.Errorf("Put trade specifier close failed: %v", err)
+		return nil, errors.Wrap(err, "Put trade specifier close failed")
 	}
 	return data.(*PutTradeSpecifierCloseSchema), nil
 }
@@ -444,7 +518,7 @@ func (r *ReceiverTradeSpecifierClientExtensions) Put(ctx context.Context, params
 	childCtx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	resp, err := r.Connection.request(
+	data, err := r.Connection.request(
 		childCtx,
 		&requestParams{
 			method:   "PUT",
@@ -453,26 +527,23 @@ func (r *ReceiverTradeSpecifierClientExtensions) Put(ctx context.Context, params
 				{key: "Accept-Datetime-Format", value: "RFC3339"},
 			},
 			body: params.Body,
+			simulate: func() (int, interface{}) {
+				tx := dryRunTransaction(r.AccountID, TradeClientExtensionsModifyTransaction, "")
+				tx.TradeID = r.TradeSpecifier
+				if params.Body != nil {
+					tx.TradeClientExtensionsModify = params.Body.ClientExtensions
+				}
+				return 200, &PutTradeSpecifierClientExtensionsSchema{TradeClientExtensionsModifyTransaction: tx}
+			},
+			results: map[int]interface{}{
+				200: new(PutTradeSpecifierClientExtensionsSchema),
+				400: new(PutTradeSpecifierClientExtensionsBadRequestError),
+				404: new(PutTradeSpecifierClientExtensionsNotFoundError),
+			},
 		},
 	)
 	if err != nil {
-		return nil, errors.Errorf("Put trade specifier client extensions canceled: %v", err)
-	}
-	defer resp.Body.Close()
-
-	var data interface{}
-	switch resp.StatusCode {
-	case 200:
-		data = new(PutTradeSpecifierClientExtensionsSchema)
-	case 400:
-		data = new(PutTradeSpecifierClientExtensionsBadRequestError)
-	case 404:
-		data = new(PutTradeSpecifierClientExtensionsNotFoundError)
-	}
-
-	data, err = parseResponse(resp, data, r.Connection.Strict)
-	if err != nil {
-		return nil, errors.Errorf("Put trade specifier client extensions failed: %v", err)
+		return nil, errors.Wrap(err, "Put trade specifier client extensions failed")
 	}
 	return data.(*PutTradeSpecifierClientExtensionsSchema), nil
 }
@@ -482,7 +553,7 @@ func (r *ReceiverTradeSpecifierOrders) Put(ctx context.Context, params *PutTrade
 	childCtx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	resp, err := r.Connection.request(
+	data, err := r.Connection.request(
 		childCtx,
 		&requestParams{
 			method:   "PUT",
@@ -491,24 +562,35 @@ func (r *ReceiverTradeSpecifierOrders) Put(ctx context.Context, params *PutTrade
 				{key: "Accept-Datetime-Format", value: "RFC3339"},
 			},
 			body: params.Body,
+			simulate: func() (int, interface{}) {
+				data := new(PutTradeSpecifierOrdersSchema)
+				if params.Body == nil {
+					return 200, data
+				}
+				order := func(typ TransactionTypeDefinition) *TransactionDefinition {
+					tx := dryRunTransaction(r.AccountID, typ, "REPLACEMENT")
+					tx.TradeID = r.TradeSpecifier
+					return tx
+				}
+				if params.Body.TakeProfit != nil {
+					data.TakeProfitOrderTransaction = order(TakeProfitOrderTransaction)
+				}
+				if params.Body.StopLoss != nil {
+					data.StopLossOrderTransaction = order(StopLossOrderTransaction)
+				}
+				if params.Body.TrailingStopLoss != nil {
+					data.TrailingStopLossOrderTransaction = order(TrailingStopLossOrderTransaction)
+				}
+				return 200, data
+			},
+			results: map[int]interface{}{
+				200: new(PutTradeSpecifierOrdersSchema),
+				400: new(PutTradeSpecifierOrdersBadRequestError),
+			},
 		},
 	)
 	if err != nil {
-		return nil, errors.Errorf("Put trade specifier orders canceled: %v", err)
-	}
-	defer resp.Body.Close()
-
-	var data interface{}
-	switch resp.StatusCode {
-	case 200:
-		data = new(PutTradeSpecifierOrdersSchema)
-	case 400:
-		data = new(PutTradeSpecifierOrdersBadRequestError)
-	}
-
-	data, err = parseResponse(resp, data, r.Connection.Strict)
-	if err != nil {
-		return nil, errors.Errorf("Put trade specifier orders failed: %v", err)
+		return nil, errors.Wrap(err, "Put trade specifier orders failed")
 	}
 	return data.(*PutTradeSpecifierOrdersSchema), nil
 }
@@ -1,9 +1,9 @@
 package oanda
 
 import (
-	"bufio"
 	"context"
 	"encoding/json"
+	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
@@ -100,9 +100,68 @@ type GetTransactionsStreamParams struct {
 	BufferSize int
 }
 
+/* Headers */
+
+type GetTransactionsHeaders struct {
+	RequestID string
+}
+
+func (s *GetTransactionsSchema) setHeaders(resp *http.Response) error {
+	s.Headers = new(GetTransactionsHeaders)
+	if h, err := copyHeader(resp, "Requestid"); err == nil {
+		s.Headers.RequestID = h[0]
+	} else {
+		return errors.Errorf("Parse headers failed: %v", err)
+	}
+	return nil
+}
+
+type GetTransactionsIdrangeHeaders struct {
+	RequestID string
+}
+
+func (s *GetTransactionsIdrangeSchema) setHeaders(resp *http.Response) error {
+	s.Headers = new(GetTransactionsIdrangeHeaders)
+	if h, err := copyHeader(resp, "Requestid"); err == nil {
+		s.Headers.RequestID = h[0]
+	} else {
+		return errors.Errorf("Parse headers failed: %v", err)
+	}
+	return nil
+}
+
+type GetTransactionIDHeaders struct {
+	RequestID string
+}
+
+func (s *GetTransactionIDSchema) setHeaders(resp *http.Response) error {
+	s.Headers = new(GetTransactionIDHeaders)
+	if h, err := copyHeader(resp, "Requestid"); err == nil {
+		s.Headers.RequestID = h[0]
+	} else {
+		return errors.Errorf("Parse headers failed: %v", err)
+	}
+	return nil
+}
+
+type GetTransactionsSinceIDHeaders struct {
+	RequestID string
+}
+
+func (s *GetTransactionsSinceIDSchema) setHeaders(resp *http.Response) error {
+	s.Headers = new(GetTransactionsSinceIDHeaders)
+	if h, err := copyHeader(resp, "Requestid"); err == nil {
+		s.Headers.RequestID = h[0]
+	} else {
+		return errors.Errorf("Parse headers failed: %v", err)
+	}
+	return nil
+}
+
 /* Schemas */
 
 type GetTransactionsSchema struct {
+	Headers           *GetTransactionsHeaders
 	From              DateTimeDefinition            `json:"from,omitempty"`
 	To                DateTimeDefinition            `json:"to,omitempty"`
 	PageSize          int                           `json:"pageSize,omitempty"`
@@ -113,16 +172,19 @@ type GetTransactionsSchema struct {
 }
 
 type GetTransactionsIdrangeSchema struct {
+	Headers           *GetTransactionsIdrangeHeaders
 	Transactions      []*TransactionDefinition `json:"transactions,omitempty"`
 	LastTransactionID TransactionIDDefinition  `json:"lastTransactionID,omitempty"`
 }
 
 type GetTransactionIDSchema struct {
+	Headers           *GetTransactionIDHeaders
 	Transaction       *TransactionDefinition  `json:"transaction,omitempty"`
 	LastTransactionID TransactionIDDefinition `json:"lastTransactionID,omitempty"`
 }
 
 type GetTransactionsSinceIDSchema struct {
+	Headers           *GetTransactionsSinceIDHeaders
 	Transactions      []*TransactionDefinition `json:"transactions,omitempty"`
 	LastTransactionID TransactionIDDefinition  `json:"lastTransactionID,omitempty"`
 }
@@ -146,7 +208,7 @@ func (r *ReceiverTransactions) Get(ctx context.Context, params *GetTransactionsP
 	childCtx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	resp, err := r.Connection.request(
+	data, err := r.Connection.request(
 		childCtx,
 		&requestParams{
 			method:   "GET",
@@ -183,22 +245,13 @@ func (r *ReceiverTransactions) Get(ctx context.Context, params *GetTransactionsP
 
 				return q
 			}(),
+			results: map[int]interface{}{
+				200: new(GetTransactionsSchema),
+			},
 		},
 	)
 	if err != nil {
-		return nil, errors.Errorf("Get transactions canceled: %v", err)
-	}
-	defer resp.Body.Close()
-
-	var data interface{}
-	switch resp.StatusCode {
-	case 200:
-		data = new(GetTransactionsSchema)
-	}
-
-	data, err = parseResponse(resp, data, r.Connection.Strict)
-	if err != nil {
-		return nil, errors.Errorf("Get transactions failed: %v", err)
+		return nil, errors.Wrap(err, "Get transactions failed")
 	}
 	return data.(*GetTransactionsSchema), nil
 }
@@ -208,7 +261,7 @@ func (r *ReceiverTransactionID) Get(ctx context.Context) (*GetTransactionIDSchem
 	childCtx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	resp, err := r.Connection.request(
+	data, err := r.Connection.request(
 		childCtx,
 		&requestParams{
 			method:   "GET",
@@ -216,22 +269,13 @@ func (r *ReceiverTransactionID) Get(ctx context.Context) (*GetTransactionIDSchem
 			headers: []header{
 				{key: "Accept-Datetime-Format", value: "RFC3339"},
 			},
+			results: map[int]interface{}{
+				200: new(GetTransactionIDSchema),
+			},
 		},
 	)
 	if err != nil {
-		return nil, errors.Errorf("Get transactions id canceled: %v", err)
-	}
-	defer resp.Body.Close()
-
-	var data interface{}
-	switch resp.StatusCode {
-	case 200:
-		data = new(GetTransactionIDSchema)
-	}
-
-	data, err = parseResponse(resp, data, r.Connection.Strict)
-	if err != nil {
-		return nil, errors.Errorf("Get transactions id failed: %v", err)
+		return nil, errors.Wrap(err, "Get transactions id failed")
 	}
 	return data.(*GetTransactionIDSchema), nil
 }
@@ -243,7 +287,7 @@ func (r *ReceiverTransactionsIdrange) Get(ctx context.Context, params *GetTransa
 	childCtx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	resp, err := r.Connection.request(
+	data, err := r.Connection.request(
 		childCtx,
 		&requestParams{
 			method:   "GET",
@@ -271,21 +315,13 @@ func (r *ReceiverTransactionsIdrange) Get(ctx context.Context, params *GetTransa
 
 				return q
 			}(),
+			results: map[int]interface{}{
+				200: new(GetTransactionsIdrangeSchema),
+			},
 		},
 	)
 	if err != nil {
-		return nil, errors.Errorf("Get transactions idrange canceled: %v", err)
-	}
-	defer resp.Body.Close()
-
-	var data interface{}
-	switch resp.StatusCode {
-	case 200:
-		data = new(GetTransactionsIdrangeSchema)
-	}
-	data, err = parseResponse(resp, data, r.Connection.Strict)
-	if err != nil {
-		return nil, errors.Errorf("Get transactions idrange failed: %v", err)
+		return nil, errors.Wrap(err, "Get transactions idrange failed")
 	}
 
 	return data.(*GetTransactionsIdrangeSchema), nil
@@ -296,7 +332,7 @@ func (r *ReceiverTransactionsSinceID) Get(ctx context.Context, params *GetTransa
 	childCtx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	resp, err := r.Connection.request(
+	data, err := r.Connection.request(
 		childCtx,
 		&requestParams{
 			method:   "GET",
@@ -314,22 +350,13 @@ func (r *ReceiverTransactionsSinceID) Get(ctx context.Context, params *GetTransa
 
 				return q
 			}(),
+			results: map[int]interface{}{
+				200: new(GetTransactionsSinceIDSchema),
+			},
 		},
 	)
 	if err != nil {
-		return nil, errors.Errorf("Get transactions sinceid canceled: %v", err)
-	}
-	defer resp.Body.Close()
-
-	var data interface{}
-	switch resp.StatusCode {
-	case 200:
-		data = new(GetTransactionsSinceIDSchema)
-	}
-
-	data, err = parseResponse(resp, data, r.Connection.Strict)
-	if err != nil {
-		return nil, errors.Errorf("Get transactions sinceid failed: %v", err)
+		return nil, errors.Wrap(err, "Get transactions sinceid failed")
 	}
 	return data.(*GetTransactionsSinceIDSchema), nil
 }
@@ -340,7 +367,7 @@ func (r *ReceiverTransactionsSinceID) Get(ctx context.Context, params *GetTransa
 func (r *ReceiverTransactionsStream) Get(ctx context.Context, params *GetTransactionsStreamParams) (*TransactionsChannels, error) {
 	childCtx, cancel := context.WithCancel(ctx)
 
-	resp, err := r.Connection.stream(
+	resp, observer, err := r.Connection.stream(
 		childCtx,
 		&requestParams{
 			method:   "GET",
@@ -361,12 +388,13 @@ func (r *ReceiverTransactionsStream) Get(ctx context.Context, params *GetTransac
 			resp.Body.Close()
 			cancel()
 		}()
-		var err interface{}
-		_, err = parseResponse(resp, err, r.Connection.Strict)
-		return nil, errors.Errorf("Get transactions stream failed: %v", err)
+		var err error
+		_, err = parseResponse(resp, nil, r.Connection.compare(childCtx, observer.req, resp))
+		return nil, errors.Wrap(err, "Get transactions stream failed")
 	}
 
 	closeWait := new(sync.WaitGroup)
+	closeWait.Add(3)
 
 	transactionCh := make(chan *TransactionDefinition, params.BufferSize)
 	errorCh := make(chan error, 3)
@@ -380,32 +408,36 @@ func (r *ReceiverTransactionsStream) Get(ctx context.Context, params *GetTransac
 			cancel()
 			closeWait.Done()
 		}()
-		closeWait.Add(1)
 		<-childCtx.Done()
 	}()
 
 	// 受信したデータ(JSON)を構造体にしてreaderCh channelに送信するgoroutine
-	readerCh := make(chan []byte, params.BufferSize)
+	readerCh := make(chan *TransactionDefinition, params.BufferSize)
 	go func() {
 		defer func() {
 			close(readerCh)
 			cancel()
 			closeWait.Done()
 		}()
-		closeWait.Add(1)
 
-		reader := bufio.NewReader(resp.Body)
+		decoder := json.NewDecoder(resp.Body)
 		for {
-			line, err := reader.ReadBytes('\n')
-			if err != nil {
+			data := new(TransactionDefinition)
+			if err := decoder.Decode(data); err != nil {
 				select {
 				case <-childCtx.Done():
 				default:
-					errorCh <- errors.Errorf("Read response stream failed: %v", err)
+					errorCh <- observer.fail(decodeFailed(err, "response stream"))
 				}
 				return
 			}
-			readerCh <- line
+			observer.message(data)
+
+			select {
+			case readerCh <- data:
+			case <-childCtx.Done():
+				return
+			}
 		}
 	}()
 
@@ -415,9 +447,9 @@ func (r *ReceiverTransactionsStream) Get(ctx context.Context, params *GetTransac
 		defer func() {
 			close(transactionCh)
 			cancel()
+			observer.close()
 			closeWait.Done()
 		}()
-		closeWait.Add(1)
 
 		timeout := time.NewTimer(0)
 		received := true
@@ -426,21 +458,24 @@ func (r *ReceiverTransactionsStream) Get(ctx context.Context, params *GetTransac
 			select {
 			case <-childCtx.Done():
 				return
-			case line := <-readerCh:
+			case data, ok := <-readerCh:
+				if !ok {
+					return
+				}
 				received = true
 
-				data := new(TransactionDefinition)
-				if err := json.Unmarshal(line, data); err != nil {
-					errorCh <- errors.Errorf("Unmarshal response stream failed: %v", err)
+				r.Connection.setStreamBuffer(StreamTransactions, len(transactionCh), cap(transactionCh))
+
+				select {
+				case transactionCh <- data:
+				case <-childCtx.Done():
 					return
 				}
-
-				transactionCh <- data
 			case <-timeout.C:
 				timeout.Reset(r.Connection.Timeout)
 				if !received {
 					var err error = &StreamHeartbeatBroken{ErrorMessage: "Heartbeat was broken"}
-					errorCh <- errors.Errorf("Get pricing stream heartbeat was broken: %v", err)
+					errorCh <- observer.fail(errors.Errorf("Get pricing stream heartbeat was broken: %v", err))
 					return
 				}
 				received = false
@@ -480,7 +515,7 @@ func (s *GetTransactionsSchema) IdrangeParams() ([]*GetTransactionsIdrangeParams
 	for _, page := range s.Pages {
 		u, err := url.Parse(page)
 		if err != nil {
-			return nil, errors.Errorf("Parse transactions idrange params failed: %v", err)
+			return nil, errors.Wrap(err, "Parse transactions idrange params failed")
 		}
 		query := u.Query()
 
@@ -488,13 +523,13 @@ func (s *GetTransactionsSchema) IdrangeParams() ([]*GetTransactionsIdrangeParams
 		if v, ok := query["from"]; ok {
 			param.From, err = strconv.Atoi(v[0])
 			if err != nil {
-				return nil, errors.Errorf("Parse transactions idrange from query failed: %v", err)
+				return nil, errors.Wrap(err, "Parse transactions idrange from query failed")
 			}
 		}
 		if v, ok := query["to"]; ok {
 			param.To, err = strconv.Atoi(v[0])
 			if err != nil {
-				return nil, errors.Errorf("Parse transactions idrange to query failed: %v", err)
+				return nil, errors.Wrap(err, "Parse transactions idrange to query failed")
 			}
 		}
 		if v, ok := query["type"]; ok {
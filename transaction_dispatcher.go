@@ -0,0 +1,194 @@
+package oanda
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+/* Dispatcher */
+
+type TransactionHandler func(ctx context.Context, tx *TransactionDefinition) error
+
+// TransactionDispatcher delivers transactions to the handlers registered for
+// their type. Transactions are delivered one after another and handlers are
+// called in the order of registration. A failing or panicking handler is
+// reported to the error handler and doesn't stop the others.
+type TransactionDispatcher struct {
+	mu       sync.RWMutex
+	handlers []*transactionHandlerEntry
+	onError  func(tx *TransactionDefinition, err error)
+}
+
+type transactionHandlerEntry struct {
+	match   func(tx *TransactionDefinition) bool
+	handler TransactionHandler
+}
+
+func NewTransactionDispatcher() *TransactionDispatcher {
+	return &TransactionDispatcher{}
+}
+
+/* Registration */
+
+// On registers a handler for transactions of the given type.
+func (d *TransactionDispatcher) On(typ TransactionTypeDefinition, handler TransactionHandler) {
+	d.handle(func(tx *TransactionDefinition) bool { return tx.Type == typ }, handler)
+}
+
+// OnAny registers a handler for every transaction except heartbeats.
+func (d *TransactionDispatcher) OnAny(handler TransactionHandler) {
+	d.handle(func(tx *TransactionDefinition) bool { return tx.Type != "HEARTBEAT" }, handler)
+}
+
+// OnError registers the handler that receives the errors and panics of the
+// transaction handlers.
+func (d *TransactionDispatcher) OnError(handler func(tx *TransactionDefinition, err error)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.onError = handler
+}
+
+func (d *TransactionDispatcher) OnOrderFill(handler TransactionHandler) {
+	d.On(OrderFillTransaction, handler)
+}
+
+func (d *TransactionDispatcher) OnOrderCancel(handler TransactionHandler) {
+	d.On(OrderCancelTransaction, handler)
+}
+
+// OnOrderCreate registers a handler for the creation of orders of any type.
+func (d *TransactionDispatcher) OnOrderCreate(handler TransactionHandler) {
+	d.handle(func(tx *TransactionDefinition) bool {
+		switch tx.Type {
+		case MarketOrderTransaction, LimitOrderTransaction, StopOrderTransaction,
+			MarketIfTouchedOrderTransaction, TakeProfitOrderTransaction,
+			StopLossOrderTransaction, TrailingStopLossOrderTransaction:
+			return true
+		}
+		return false
+	}, handler)
+}
+
+// OnOrderReject registers a handler for the rejection of orders of any type.
+func (d *TransactionDispatcher) OnOrderReject(handler TransactionHandler) {
+	d.handle(func(tx *TransactionDefinition) bool {
+		switch tx.Type {
+		case MarketOrderRejectTransaction, LimitOrderRejectTransaction, StopOrderRejectTransaction,
+			MarketIfTouchedOrderRejectTransaction, TakeProfitOrderRejectTransaction,
+			StopLossOrderRejectTransaction, TrailingStopLossOrderRejectTransaction:
+			return true
+		}
+		return false
+	}, handler)
+}
+
+// OnTakeProfitTriggered registers a handler for fills of take profit orders.
+func (d *TransactionDispatcher) OnTakeProfitTriggered(handler TransactionHandler) {
+	d.onFill("TAKE_PROFIT_ORDER", handler)
+}
+
+// OnStopLossTriggered registers a handler for fills of stop loss orders.
+func (d *TransactionDispatcher) OnStopLossTriggered(handler TransactionHandler) {
+	d.onFill("STOP_LOSS_ORDER", handler)
+}
+
+// OnTrailingStopLossTriggered registers a handler for fills of trailing stop
+// loss orders.
+func (d *TransactionDispatcher) OnTrailingStopLossTriggered(handler TransactionHandler) {
+	d.onFill("TRAILING_STOP_LOSS_ORDER", handler)
+}
+
+// OnMarginCloseout registers a handler for fills of margin closeout orders.
+func (d *TransactionDispatcher) OnMarginCloseout(handler TransactionHandler) {
+	d.onFill("MARKET_ORDER_MARGIN_CLOSEOUT", handler)
+}
+
+func (d *TransactionDispatcher) OnMarginCallEnter(handler TransactionHandler) {
+	d.On(MarginCallEnterTransaction, handler)
+}
+
+func (d *TransactionDispatcher) OnMarginCallExtend(handler TransactionHandler) {
+	d.On(MarginCallExtendTransaction, handler)
+}
+
+func (d *TransactionDispatcher) OnMarginCallExit(handler TransactionHandler) {
+	d.On(MarginCallExitTransaction, handler)
+}
+
+func (d *TransactionDispatcher) OnDailyFinancing(handler TransactionHandler) {
+	d.On(DailyFinancingTransaction, handler)
+}
+
+func (d *TransactionDispatcher) OnTransferFunds(handler TransactionHandler) {
+	d.On(TransferFundsTransaction, handler)
+}
+
+func (d *TransactionDispatcher) OnClientConfigure(handler TransactionHandler) {
+	d.On(ClientConfigureTransaction, handler)
+}
+
+func (d *TransactionDispatcher) onFill(reason Reason, handler TransactionHandler) {
+	d.handle(func(tx *TransactionDefinition) bool {
+		return tx.Type == OrderFillTransaction && tx.Reason == reason
+	}, handler)
+}
+
+func (d *TransactionDispatcher) handle(match func(tx *TransactionDefinition) bool, handler TransactionHandler) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.handlers = append(d.handlers, &transactionHandlerEntry{match: match, handler: handler})
+}
+
+/* Delivery */
+
+// Dispatch delivers a single transaction to the matching handlers.
+func (d *TransactionDispatcher) Dispatch(ctx context.Context, tx *TransactionDefinition) {
+	d.mu.RLock()
+	handlers := d.handlers
+	onError := d.onError
+	d.mu.RUnlock()
+
+	for _, entry := range handlers {
+		if !entry.match(tx) {
+			continue
+		}
+		if err := callTransactionHandler(ctx, entry.handler, tx); err != nil && onError != nil {
+			onError(tx, err)
+		}
+	}
+}
+
+// Run dispatches the transactions of a stream until it is closed or ctx is
+// done, and returns the error of the stream.
+func (d *TransactionDispatcher) Run(ctx context.Context, chs *TransactionsChannels) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case tx, ok := <-chs.TransactionCh:
+			if !ok {
+				return chs.Err()
+			}
+			d.Dispatch(ctx, tx)
+		}
+	}
+}
+
+func callTransactionHandler(ctx context.Context, handler TransactionHandler, tx *TransactionDefinition) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = &TransactionHandlerPanic{
+				ErrorMessage: fmt.Sprintf("Transaction handler panicked on %s transaction %s: %v", tx.Type, tx.ID, v),
+				Value:        v,
+			}
+		}
+	}()
+
+	if err := handler(ctx, tx); err != nil {
+		return errors.Errorf("Transaction handler failed on %s transaction %s: %v", tx.Type, tx.ID, err)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package oanda
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func Test_TransactionDispatcher(t *testing.T) {
+	d := NewTransactionDispatcher()
+
+	var calls []string
+	record := func(name string) TransactionHandler {
+		return func(ctx context.Context, tx *TransactionDefinition) error {
+			calls = append(calls, name+":"+tx.ID)
+			return nil
+		}
+	}
+
+	var failures []error
+	d.OnError(func(tx *TransactionDefinition, err error) {
+		failures = append(failures, err)
+	})
+
+	d.OnOrderFill(record("fill"))
+	d.OnOrderFill(func(ctx context.Context, tx *TransactionDefinition) error {
+		panic("boom")
+	})
+	d.OnStopLossTriggered(record("stopLoss"))
+	d.OnOrderFill(func(ctx context.Context, tx *TransactionDefinition) error {
+		return errors.New("ledger unavailable")
+	})
+	d.OnMarginCallEnter(record("marginCall"))
+	d.OnDailyFinancing(record("financing"))
+	d.OnAny(record("any"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	transactionCh := make(chan *TransactionDefinition, 10)
+	chs := &TransactionsChannels{
+		TransactionCh: transactionCh,
+		errorCh:       make(chan error, 1),
+		close:         cancel,
+		closeWait:     new(sync.WaitGroup),
+	}
+
+	transactionCh <- &TransactionDefinition{ID: "1", Type: "HEARTBEAT"}
+	transactionCh <- &TransactionDefinition{ID: "2", Type: "ORDER_FILL", Reason: "MARKET_ORDER"}
+	transactionCh <- &TransactionDefinition{ID: "3", Type: "ORDER_FILL", Reason: "STOP_LOSS_ORDER"}
+	transactionCh <- &TransactionDefinition{ID: "4", Type: "MARGIN_CALL_ENTER"}
+	transactionCh <- &TransactionDefinition{ID: "5", Type: "DAILY_FINANCING"}
+	close(transactionCh)
+
+	if err := d.Run(ctx, chs); err != nil {
+		t.Fatalf("Error occurred.\n%+v", err)
+	}
+
+	expect := []string{
+		"fill:2", "any:2",
+		"fill:3", "stopLoss:3", "any:3",
+		"marginCall:4", "any:4",
+		"financing:5", "any:5",
+	}
+	if !reflect.DeepEqual(calls, expect) {
+		t.Fatalf("Got unexpected calls.\nExpect: %v\nActual: %v", expect, calls)
+	}
+
+	if len(failures) != 4 {
+		t.Fatalf("Got unexpected failures.\n%v", failures)
+	}
+	for n, failure := range failures {
+		_, panicked := failure.(*TransactionHandlerPanic)
+		if expect := n%2 == 0; panicked != expect {
+			t.Fatalf("Got unexpected failure.\n%v", failure)
+		}
+	}
+}
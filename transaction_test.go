@@ -109,6 +109,27 @@ func Test_TransactionsSinceID(t *testing.T) {
 }
 
 func Test_TransactionsStream(t *testing.T) {
+	// Close waits for the goroutines of the stream even when it is called
+	// right after opening.
+	t.Run("Close", func(t *testing.T) {
+		connection := newIdleStreamConnection()
+		for i := 0; i < 50; i++ {
+			chs, err := connection.Accounts().AccountID(testReplayAccountID).Transactions().Stream().Get(context.Background(), &GetTransactionsStreamParams{})
+			if err != nil {
+				t.Fatalf("Error occurred.\n%+v", err)
+			}
+			chs.Close()
+			select {
+			case _, ok := <-chs.TransactionCh:
+				if ok {
+					t.Fatalf("Got data after close.")
+				}
+			default:
+				t.Fatalf("Stream is still running after close.")
+			}
+		}
+	})
+
 	t.Run("Success", func(t *testing.T) {
 		connection := newConnection(t, OandaPractice)
 		accountID := Getenv("ACCOUNT_ID")
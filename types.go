@@ -58,6 +58,9 @@ type AccountDefinition struct {
 	Trades                           []*TradeSummaryDefinition                `json:"trades,omitempty"`
 	Positions                        []*PositionDefinition                    `json:"positions,omitempty"`
 	Orders                           []*OrderDefinition                       `json:"orders,omitempty"`
+
+	// Extra holds the fields of the API this library doesn't know yet.
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 type AccountChangesStateDefinition struct {
@@ -403,6 +406,9 @@ type OrderDefinition struct {
 	Units                      DecimalNumberDefinition                 `json:"units,omitempty"`
 
 	PartialFill Undefined `json:"partialFill"`
+
+	// Extra holds the fields of the API this library doesn't know yet.
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 type TakeProfitOrderDefinition = OrderDefinition
@@ -928,6 +934,9 @@ type TransactionDefinition struct {
 	PartialFill             Undefined `json:"partialFill,omitempty"`
 	TradeCloseTransactionID Undefined `json:"tradeCloseTransactionID,omitempty"`
 	ClosedTradeID           Undefined `json:"closedTradeID,omitempty"`
+
+	// Extra holds the fields of the API this library doesn't know yet.
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 // Transaction-related Definitions
@@ -1533,6 +1542,9 @@ type InstrumentDefinition struct {
 
 	// Financing data for this instrument.
 	Financing InstrumentFinancingDefinition `json:"financing,omitempty"`
+
+	// Extra holds the fields of the API this library doesn't know yet.
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 type DateTimeDefinition = string
@@ -31,6 +31,10 @@ type requestParams struct {
 	headers  []header
 	queries  []query
 	body     interface{}
+	// Results to decode the response into by status code.
+	results map[int]interface{}
+	// Response returned in dry-run mode instead of sending the request.
+	simulate func() (int, interface{})
 }
 
 type baseURLs struct {
@@ -87,13 +91,9 @@ func copyHeader(resp *http.Response, header string) ([]string, error) {
 	return dst, nil
 }
 
-func parseResponse(resp *http.Response, data interface{}, strict bool) (interface{}, error) {
-	body, err := ioutil.ReadAll(resp.Body)
-
-	if err != nil {
-		return nil, errors.Errorf("Read response body failed: %v", err)
-	}
-
+// parseResponse decodes the response into data. compare is called with the
+// body unless it is nil.
+func parseResponse(resp *http.Response, data interface{}, compare func(data interface{}, body []byte) error) (interface{}, error) {
 	var errMessage string
 	switch resp.StatusCode {
 	case 200, 201:
@@ -127,36 +127,70 @@ func parseResponse(resp *http.Response, data interface{}, strict bool) (interfac
 		return nil, errors.Errorf("Unexpected status code(%d)", resp.StatusCode)
 	}
 
-	if err := json.Unmarshal(body, data); err != nil {
-		spew.Dump(body)
-		return nil, errors.Errorf("Unmarshal response body failed: %v", err)
-	}
-
-	if strict {
-		if err := compareJson(data, body); err != nil {
-			return nil, errors.Errorf("Response body JSON is different from unmarshalled it: %v", err)
+	// The body is only kept to be compared with the decoded data.
+	if compare != nil {
+		body, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return nil, errors.Errorf("Read response body failed: %v", err)
+		}
+		if err := json.Unmarshal(body, data); err != nil {
+			return nil, errors.Errorf("Unmarshal response body failed: %v", err)
+		}
+		if err := compare(data, body); err != nil {
+			return nil, err
 		}
+	} else if err := json.NewDecoder(resp.Body).Decode(data); err != nil {
+		return nil, decodeFailed(err, "response body")
 	}
 
-	if resp.StatusCode/100 != 2 {
-		return nil, errors.Errorf("%s: %v", errMessage, data)
+	sm, ok := data.(schemas)
+	if !ok {
+		return nil, errors.Errorf("%T has no headers", data)
+	}
+	if err := sm.setHeaders(resp); err != nil {
+		return nil, errors.Errorf("Set headers failed: %v", err)
 	}
 
-	{
-		sm, ok := data.(schemas)
-		if !ok {
-			// TODO: ヘッダ未実装時の回避、全て実装したら消す
-			return data, nil
-		}
-		if err := sm.setHeaders(resp); err != nil {
-			return nil, errors.Errorf("Set headers failed: %v", err)
+	if resp.StatusCode/100 != 2 {
+		if err, ok := data.(error); ok {
+			return data, errors.Wrap(err, errMessage)
 		}
+		return data, errors.Errorf("%s: %v", errMessage, data)
 	}
 
 	return data, nil
 }
 
+// decodeFailed tells malformed JSON from read errors of a json.Decoder.
+func decodeFailed(err error, subject string) error {
+	switch err.(type) {
+	case *json.SyntaxError, *json.UnmarshalTypeError:
+		return errors.Errorf("Unmarshal %s failed: %v", subject, err)
+	}
+	return errors.Errorf("Read %s failed: %v", subject, err)
+}
+
 func compareJson(jsonObj interface{}, jsonStr []byte) error {
+	var first *jsonDiff
+	err := diffJson(jsonObj, jsonStr, func(d *jsonDiff) {
+		if first == nil {
+			first = d
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	if first != nil {
+		bytes, _ := json.Marshal(jsonObj)
+		return errors.Errorf("Unmarshalled JSON is lacking:\nExpect: %s\nActual: %s\n: %v", string(jsonStr), string(bytes), first.err())
+	}
+	return nil
+}
+
+// diffJson calls diff with every value of jsonStr that is lost when it is
+// unmarshalled into jsonObj and marshalled again.
+func diffJson(jsonObj interface{}, jsonStr []byte, diff func(*jsonDiff)) error {
 	bytes, err := json.Marshal(jsonObj)
 	if err != nil {
 		return errors.Errorf("Marshal JSON object failed: %v", err)
@@ -172,91 +206,117 @@ func compareJson(jsonObj interface{}, jsonStr []byte) error {
 		return errors.Errorf("Unmarshal JSON string failed: %v", err)
 	}
 
-	if err := deepEqual(expect, actual, []string{reflect.TypeOf(jsonObj).String()}); err != nil {
-		return errors.Errorf("Unmarshalled JSON is lacking:\nExpect: %s\nActual: %s\n: %v", string(jsonStr), string(bytes), err)
-	}
-
+	walkJson(*expect, *actual, []string{reflect.TypeOf(jsonObj).String()}, diff)
 	return nil
 }
 
+// jsonDiff is a value of the expected JSON that differs from the actual one.
+type jsonDiff struct {
+	Breadcrumbs []string
+	Expect      interface{}
+	// Actual is nil when the value is missing.
+	Actual interface{}
+	// Whether the type of Expect is unexpected in JSON.
+	unexpectedType bool
+}
+
+func (d *jsonDiff) err() error {
+	if d.unexpectedType {
+		return errors.Errorf("Unexpected type was given\nBreadcrumbs: %s\nType: %T\nExpect: %sActual: %s", strings.Join(d.Breadcrumbs, " > "), d.Expect, spew.Sdump(d.Expect), spew.Sdump(d.Actual))
+	}
+	return errors.Errorf("Actual value is not equal to expect\nBreadcrumbs: %s\nExpect: %sActual: %s", strings.Join(d.Breadcrumbs, " > "), spew.Sdump(d.Expect), spew.Sdump(d.Actual))
+}
+
 func deepEqual(expect, actual interface{}, breadcrumbs []string) error {
-	if reflect.TypeOf(expect).String() == "*interface {}" {
-		expect = reflect.Indirect(reflect.ValueOf(expect)).Interface()
+	if v, ok := expect.(*interface{}); ok && v != nil {
+		expect = *v
+	}
+	if v, ok := actual.(*interface{}); ok && v != nil {
+		actual = *v
 	}
 
-	if actual == nil {
-		return errors.Errorf("Actual value is not equal to expect\nBreadcrumbs: %s\nExpect: %sActual: %s", strings.Join(breadcrumbs, " > "), spew.Sdump(expect), spew.Sdump(actual))
+	var first *jsonDiff
+	walkJson(expect, actual, breadcrumbs, func(d *jsonDiff) {
+		if first == nil {
+			first = d
+		}
+	})
+	if first != nil {
+		return first.err()
 	}
-	if reflect.TypeOf(actual).String() == "*interface {}" {
-		actual = reflect.Indirect(reflect.ValueOf(actual)).Interface()
+	return nil
+}
+
+func walkJson(expect, actual interface{}, breadcrumbs []string, diff func(*jsonDiff)) {
+	report := func() {
+		diff(&jsonDiff{
+			Breadcrumbs: append([]string(nil), breadcrumbs...),
+			Expect:      expect,
+			Actual:      actual,
+		})
 	}
 
 	switch expectValue := expect.(type) {
+	case nil:
+		// A null carries nothing that could be lost.
 	case map[string]interface{}:
 		actualValue, ok := actual.(map[string]interface{})
 		if !ok {
-			return errors.Errorf("Actual value is not equal to expect\nBreadcrumbs: %s\nExpect: %+v\nActual: %+v", strings.Join(breadcrumbs, " > "), expect, actual)
+			report()
+			return
 		}
 
 		for k := range expectValue {
 			if v, ok := expectValue[k].([]interface{}); ok && len(v) == 0 {
 				if _, ok := actualValue[k]; !ok {
-					return nil
+					continue
 				}
 			}
-
-			if err := deepEqual(expectValue[k], actualValue[k], append(breadcrumbs, k)); err != nil {
-				return err
-			}
+			walkJson(expectValue[k], actualValue[k], append(breadcrumbs, k), diff)
 		}
 	case []interface{}:
 		actualValue, ok := actual.([]interface{})
 		if !ok {
-			return errors.Errorf("Actual value is not equal to expect\nBreadcrumbs: %s\nExpect: %+v\nActual: %+v", strings.Join(breadcrumbs, " > "), expect, actual)
+			report()
+			return
 		}
 
 		for n := range expectValue {
-			if err := deepEqual(expectValue[n], actualValue[n], append(breadcrumbs, "["+strconv.Itoa(n)+"]")); err != nil {
-				return err
+			var v interface{}
+			if n < len(actualValue) {
+				v = actualValue[n]
 			}
+			walkJson(expectValue[n], v, append(breadcrumbs, "["+strconv.Itoa(n)+"]"), diff)
 		}
 	case string:
 		switch actualValue := actual.(type) {
 		case string:
 			if expectValue != actualValue {
-				return errors.Errorf("Actual value is not equal to expect\nBreadcrumbs: %s\nExpect: %sActual: %s", strings.Join(breadcrumbs, " > "), spew.Sdump(expect), spew.Sdump(actual))
+				report()
 			}
 		case float64:
-			ev, _ := strconv.ParseFloat(expectValue, 64)
-			if ev != actualValue {
-				return errors.Errorf("Actual value is not equal to expect\nBreadcrumbs: %s\nExpect: %sActual: %s", strings.Join(breadcrumbs, " > "), spew.Sdump(expect), spew.Sdump(actual))
+			if ev, _ := strconv.ParseFloat(expectValue, 64); ev != actualValue {
+				report()
 			}
 		default:
-			return errors.Errorf("Actual value is not equal to expect\nBreadcrumbs: %s\nExpect: %sActual: %s", strings.Join(breadcrumbs, " > "), spew.Sdump(expect), spew.Sdump(actual))
+			report()
 		}
 	case float64:
-		switch actualValue := actual.(type) {
-		case float64:
-			if expectValue != actualValue {
-				return errors.Errorf("Actual value is not equal to expect\nBreadcrumbs: %s\nExpect: %sActual: %s", strings.Join(breadcrumbs, " > "), spew.Sdump(expect), spew.Sdump(actual))
-			}
-		default:
-			return errors.Errorf("Actual value is not equal to expect\nBreadcrumbs: %s\nExpect: %sActual: %s", strings.Join(breadcrumbs, " > "), spew.Sdump(expect), spew.Sdump(actual))
+		if actualValue, ok := actual.(float64); !ok || expectValue != actualValue {
+			report()
 		}
 	case bool:
-		switch actualValue := actual.(type) {
-		case bool:
-			if expectValue != actualValue {
-				return errors.Errorf("Actual value is not equal to expect\nBreadcrumbs: %s\nExpect: %sActual: %s", strings.Join(breadcrumbs, " > "), spew.Sdump(expect), spew.Sdump(actual))
-			}
-		default:
-			return errors.Errorf("Actual value is not equal to expect\nBreadcrumbs: %s\nExpect: %sActual: %s", strings.Join(breadcrumbs, " > "), spew.Sdump(expect), spew.Sdump(actual))
+		if actualValue, ok := actual.(bool); !ok || expectValue != actualValue {
+			report()
 		}
 	default:
-		return errors.Errorf("Unexpected type was given\nBreadcrumbs: %s\nType: %s\nExpect: %sActual: %s", strings.Join(breadcrumbs, " > "), reflect.TypeOf(expect).String(), spew.Sdump(expect), spew.Sdump(actual))
+		diff(&jsonDiff{
+			Breadcrumbs:    append([]string(nil), breadcrumbs...),
+			Expect:         expect,
+			Actual:         actual,
+			unexpectedType: true,
+		})
 	}
-
-	return nil
 }
 
 type CandleDataRangeDefinition struct {
@@ -436,3 +496,29 @@ func Duration2Granularity(dur time.Duration) CandlestickGranularityDefinition {
 		panic(fmt.Sprintf("granularity %s not handled", dur))
 	}
 }
+
+func parseDecimal(v DecimalNumberDefinition) (float64, error) {
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return 0, errors.Errorf("Parse decimal number %#v failed: %v", v, err)
+	}
+	return f, nil
+}
+
+// QuoteCurrency returns the quote currency of an instrument, e.g. "USD" for
+// "EUR_USD".
+func QuoteCurrency(instrument InstrumentNameDefinition) CurrencyDefinition {
+	if n := strings.LastIndex(instrument, "_"); n >= 0 {
+		return instrument[n+1:]
+	}
+	return ""
+}
+
+// BaseCurrency returns the base currency of an instrument, e.g. "EUR" for
+// "EUR_USD".
+func BaseCurrency(instrument InstrumentNameDefinition) CurrencyDefinition {
+	if n := strings.Index(instrument, "_"); n >= 0 {
+		return instrument[:n]
+	}
+	return instrument
+}
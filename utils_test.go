@@ -2,7 +2,10 @@ package oanda
 
 import (
 	"context"
+	"io"
+	"net/http"
 	"net/url"
+	"strings"
 	"testing"
 	"time"
 
@@ -16,7 +19,8 @@ func Test_request(t *testing.T) {
 		connection.Timeout = time.Nanosecond // 即タイムアウトさせるため最小の待ち時間にする
 		_, err := connection.Accounts().Get(context.Background())
 
-		if _, ok := errors.Unwrap(err).(*url.Error); !ok {
+		var urlErr *url.Error
+		if !errors.As(err, &urlErr) {
 			t.Fatalf("Connection was not refused.\n%+v", err)
 		}
 	})
@@ -27,12 +31,95 @@ func Test_request(t *testing.T) {
 		connection.Token = "hogehoge" // 不正なトークンに書き換え
 		_, err := connection.Accounts().Get(context.Background())
 
-		if _, ok := errors.Unwrap(err).(*UnauthorizedError); !ok {
+		var unauthorized *UnauthorizedError
+		if !errors.As(err, &unauthorized) || unauthorized.ResponseHeaders().RequestID == "" {
 			t.Fatalf("Request was authorized.\n%+v", err)
 		}
 	})
 }
 
+func Test_parseResponse(t *testing.T) {
+	newResponse := func(status int, body string, header http.Header) *http.Response {
+		return &http.Response{
+			StatusCode: status,
+			Header:     header,
+			Body:       io.NopCloser(strings.NewReader(body)),
+		}
+	}
+
+	t.Run("Location", func(t *testing.T) {
+		header := http.Header{}
+		header.Set("RequestID", "42")
+		header.Set("Location", "https://api-fxpractice.oanda.com/v3/accounts/101/orders/7")
+
+		data, err := parseResponse(newResponse(201, `{"lastTransactionID":"7"}`, header), new(PostOrdersSchema), compareJson)
+		if err != nil {
+			t.Fatalf("Error occurred.\n%+v", err)
+		}
+		headers := data.(*PostOrdersSchema).Headers
+		if headers.RequestID != "42" || headers.Location != header.Get("Location") {
+			t.Fatalf("Got unexpected headers.\n%#v", headers)
+		}
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		header := http.Header{}
+		header.Set("RequestID", "43")
+
+		_, err := parseResponse(newResponse(400, `{"errorCode":"MARKET_HALTED","errorMessage":"halted"}`, header), new(PostOrdersBadRequestError), compareJson)
+		err = errors.Wrap(err, "Post orders failed")
+
+		var badRequest *PostOrdersBadRequestError
+		if !errors.As(err, &badRequest) || badRequest.ErrorCode != "MARKET_HALTED" {
+			t.Fatalf("Got unexpected error.\n%+v", err)
+		}
+		var respErr ResponseError
+		if !errors.As(err, &respErr) || respErr.ResponseHeaders().RequestID != "43" {
+			t.Fatalf("Got unexpected error.\n%+v", err)
+		}
+		if expect := "Post orders failed: 400 bad request: halted"; err.Error() != expect {
+			t.Fatalf("Got unexpected message.\nExpect: %s\nActual: %s", expect, err.Error())
+		}
+	})
+
+	t.Run("ErrorTypes", func(t *testing.T) {
+		for _, data := range []interface{}{
+			new(PatchAccountConfigurationBadRequestError), new(PatchAccountConfigurationForbiddenError),
+			new(PostOrdersBadRequestError), new(PostOrdersNotFoundError),
+			new(PutOrderSpecifierBadRequestError), new(PutOrderSpecifierNotFoundError),
+			new(PutOrderSpecifierCancelNotFoundError),
+			new(PutOrderSpecifierClientExtensionsBadRequestError), new(PutOrderSpecifierClientExtensionsNotFoundError),
+			new(PutTradeSpecifierCloseBadRequestError), new(PutTradeSpecifierCloseNotFoundError),
+			new(PutTradeSpecifierClientExtensionsBadRequestError), new(PutTradeSpecifierClientExtensionsNotFoundError),
+			new(PutTradeSpecifierOrdersBadRequestError),
+			new(PutPositionsInstrumentCloseBadRequestError), new(PutPositionsInstrumentCloseNotFoundError),
+			new(BadRequestError), new(UnauthorizedError), new(ForbiddenError), new(NotFoundError),
+		} {
+			header := http.Header{}
+			header.Set("RequestID", "45")
+			_, err := parseResponse(newResponse(400, `{"errorMessage":"failed"}`, header), data, nil)
+
+			var respErr ResponseError
+			if !errors.As(err, &respErr) || respErr.ResponseHeaders().RequestID != "45" {
+				t.Fatalf("Got unexpected error of %T.\n%+v", data, err)
+			}
+		}
+	})
+
+	t.Run("Schemas", func(t *testing.T) {
+		for _, data := range []interface{}{
+			new(GetOrdersSchema), new(PutTradeSpecifierCloseSchema), new(GetPositionsSchema),
+			new(GetPricingSchema), new(GetTransactionIDSchema),
+		} {
+			header := http.Header{}
+			header.Set("RequestID", "44")
+			if _, err := parseResponse(newResponse(200, `{}`, header), data, nil); err != nil {
+				t.Fatalf("Error occurred.\n%+v", err)
+			}
+		}
+	})
+}
+
 func TestIsGranularityValid(t *testing.T) {
 	granularities := []CandlestickGranularityDefinition{S5, S10, S15, S30, M1, M2, M4, M5, M10, M15, M30, H1, H2, H3, H4, H6, H8, H12, D, W}
 
@@ -43,3 +130,12 @@ func TestIsGranularityValid(t *testing.T) {
 	}
 
 }
+
+func TestInstrumentCurrencies(t *testing.T) {
+	if actual := BaseCurrency("EUR_USD"); actual != "EUR" {
+		t.Fatalf("Got unexpected base currency %s", actual)
+	}
+	if actual := QuoteCurrency("EUR_USD"); actual != "USD" {
+		t.Fatalf("Got unexpected quote currency %s", actual)
+	}
+}